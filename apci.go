@@ -2,6 +2,7 @@ package iec104
 
 import (
 	"errors"
+	"fmt"
 )
 
 const startByte = 0x68
@@ -40,11 +41,11 @@ func (apci *APCI) Parse(data []byte) (Frame, error) {
 	apci.Cf4 = data[3]
 
 	switch {
-	case apci.Cf1&0x1 == FrameTypeI:
+	case FrameType(apci.Cf1&0x1) == FrameTypeI:
 		return apci.parseIFrame(), nil
-	case apci.Cf1&0x3 == FrameTypeS:
+	case FrameType(apci.Cf1&0x3) == FrameTypeS:
 		return apci.parseSFrame(), nil
-	case apci.Cf1&0x3 == FrameTypeU:
+	case FrameType(apci.Cf1&0x3) == FrameTypeU:
 		return apci.parseUFrame(), nil
 	default:
 		return nil, errors.New("unknown frame type")
@@ -55,8 +56,8 @@ func (apci *APCI) Parse(data []byte) (Frame, error) {
 parseIFrame is responsible for parsing IFrame from the control fields.
 */
 func (apci *APCI) parseIFrame() *IFrame {
-	send := uint16(apci.Cf1>>1 | apci.Cf2<<7)
-	recv := uint16(apci.Cf3>>1 | apci.Cf4<<7)
+	send := uint16(apci.Cf1>>1) | uint16(apci.Cf2)<<7
+	recv := uint16(apci.Cf3>>1) | uint16(apci.Cf4)<<7
 	return &IFrame{
 		SendSN: send,
 		RecvSN: recv,
@@ -67,7 +68,7 @@ func (apci *APCI) parseIFrame() *IFrame {
 parseSFrame is responsible for parsing SFrame from the control fields.
 */
 func (apci *APCI) parseSFrame() *SFrame {
-	recv := uint16(apci.Cf3>>1 | apci.Cf4<<7)
+	recv := uint16(apci.Cf3>>1) | uint16(apci.Cf4)<<7
 	return &SFrame{
 		RecvSN: recv,
 	}
@@ -88,14 +89,26 @@ FrameType is the transmission frame format.
 
 The frame format is determined by the two last bits of the first control field (CF1).
 */
-type FrameType = byte // transmission frame format
+type FrameType byte // transmission frame format
 
 const (
-	FrameTypeI FrameType = iota
-	FrameTypeS
-	FrameTypeU FrameType = iota + 1
+	FrameTypeI FrameType = 0x00 // CF1: x x x x x x x | 0
+	FrameTypeS FrameType = 0x01 // CF1: x x x x x x | 0 1
+	FrameTypeU FrameType = 0x03 // CF1: x x x x x x | 1 1
 )
 
+func (t FrameType) String() string {
+	switch t {
+	case FrameTypeI:
+		return "I"
+	case FrameTypeS:
+		return "S"
+	case FrameTypeU:
+		return "U"
+	}
+	return fmt.Sprintf("FrameType(%d)", byte(t))
+}
+
 type UFrameFunction []byte
 
 var (
@@ -107,6 +120,25 @@ var (
 	UFrameFunctionTestFC   UFrameFunction = []byte{0x83, 0x00, 0x00, 0x00} // Test Frame Confirmation          CF1: 1 0 0 0 0 0 | 1 1
 )
 
+// uFrameName returns the name of the function of U-format frame by CF1, e.g. "StartDTA".
+func uFrameName(cf1 byte) string {
+	switch cf1 {
+	case UFrameFunctionStartDTA[0]:
+		return "StartDTA"
+	case UFrameFunctionStartDTC[0]:
+		return "StartDTC"
+	case UFrameFunctionStopDTA[0]:
+		return "StopDTA"
+	case UFrameFunctionStopDTC[0]:
+		return "StopDTC"
+	case UFrameFunctionTestFA[0]:
+		return "TestFA"
+	case UFrameFunctionTestFC[0]:
+		return "TestFC"
+	}
+	return ""
+}
+
 type Frame interface {
 	Type() FrameType
 	Data() []byte
@@ -157,6 +189,30 @@ func (i *IFrame) Data() []byte {
 	return []byte{sBytes[0], sBytes[1], rBytes[0], rBytes[1]}
 }
 
+// seqModulus is the modulus of the 15-bit sequence numbers N(S) and N(R), which wrap to 0 after 32767.
+const seqModulus = 1 << 15
+
+// seqNext returns the sequence number following n.
+func seqNext(n uint16) uint16 {
+	return (n + 1) % seqModulus
+}
+
+// seqDistance returns the number of steps from a forward to b modulo 2^15.
+func seqDistance(a, b uint16) uint16 {
+	return (b - a) % seqModulus
+}
+
+// seqLess reports whether a precedes b, i.e. b is ahead of a by less than half of the sequence space.
+func seqLess(a, b uint16) bool {
+	d := seqDistance(a, b)
+	return d != 0 && d < seqModulus/2
+}
+
+// seqInWindow reports whether n is in the window from lo to hi, both inclusive, which may wrap around.
+func seqInWindow(n, lo, hi uint16) bool {
+	return seqDistance(lo, n) <= seqDistance(lo, hi)
+}
+
 /*
 SFrame (Numbered Supervisory functions), last two bit of CF1 is (01)B.
 
@@ -182,7 +238,8 @@ func (s *SFrame) Type() FrameType {
 }
 
 func (s *SFrame) Data() []byte {
-	return []byte{byte(0b1), byte(0b0), byte(s.RecvSN & 0b01111111), byte(s.RecvSN >> 7)}
+	rBytes := serializeLittleEndianUint16(s.RecvSN << 1)
+	return []byte{byte(0b1), byte(0b0), rBytes[0], rBytes[1]}
 }
 
 /*
@@ -204,7 +261,7 @@ Control fields of U-format frame:
     In this state, the controlled station does not send any data via this connection, except unnumbered control functions
     and confirmations. The controlling station must activate the user data transfer by sending a STARTDT act (activate).
     The controlled station responds with a STARTDT con (confirm). If the STARTDT is not confirmed, the connection is
-    closed by the controlling station. TODO 给 STARTDT ACTIVATE 设置确认超时！！！
+    closed by the controlling station after t1 (see ClientOption.SetStartDTTimeout).
   - Only the controlling station sends the STARTDT. The expected mode of operation is that the STARTDT is sent only
     once after the initial establishment of the connection. The connection then operates with both controlled and
     controlling station permitted to send any messages at any time until the controlling station decides to close
@@ -214,7 +271,8 @@ Control fields of U-format frame:
   - Open connections may be periodically tested in both directions by sending test APDUs (TESTFR=act) which are confirmed
     by the receiving station sending TESTFR=con.
   - Both stations may initiate the test procedure after a specific period of time in which no data transfer occur (timeout).
-    TODO 主站向子站发送 TESTFR=act 等待子站响应 TESTFR=con。
+    The controlling station sends TESTFR=act after t3 of idle, and closes the connection if TESTFR=con isn't received
+    in t1 (see ClientOption.SetTestFrameInterval).
 */
 type UFrame struct {
 	APCI
@@ -1 +1,185 @@
 package iec104
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAPCI_ParseSequenceNumbers(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame Frame
+		data  []byte
+	}{
+		{"i frame", &IFrame{SendSN: 3, RecvSN: 1}, []byte{0x06, 0x00, 0x02, 0x00}},
+		{"i frame over 127", &IFrame{SendSN: 300, RecvSN: 1<<15 - 1}, []byte{0x58, 0x02, 0xfe, 0xff}},
+		{"s frame", &SFrame{RecvSN: 1}, []byte{0x01, 0x00, 0x02, 0x00}},
+		{"s frame over 127", &SFrame{RecvSN: 300}, []byte{0x01, 0x00, 0x58, 0x02}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.frame.Data(); !bytes.Equal(got, tt.data) {
+				t.Errorf("Data() = [% X], want [% X]", got, tt.data)
+			}
+			frame, err := new(APCI).Parse(tt.data)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			switch want := tt.frame.(type) {
+			case *IFrame:
+				got, ok := frame.(*IFrame)
+				if !ok || got.SendSN != want.SendSN || got.RecvSN != want.RecvSN {
+					t.Errorf("Parse() = %+v, want %+v", frame, want)
+				}
+			case *SFrame:
+				got, ok := frame.(*SFrame)
+				if !ok || got.RecvSN != want.RecvSN {
+					t.Errorf("Parse() = %+v, want %+v", frame, want)
+				}
+			}
+		})
+	}
+}
+
+// TestAPCI_SequenceNumberRoundTrip is the regression of the wire format of the sequence numbers, N(S) and N(R) are
+// 15 bits across two control fields, so the high bits of CF2 mustn't be lost by shifting in a byte and the N(R) of
+// S-format frame is shifted like the N(R) of I-format frame.
+func TestAPCI_SequenceNumberRoundTrip(t *testing.T) {
+	for sn := uint16(0); sn < 1<<15; sn++ {
+		frame, err := new(APCI).Parse((&IFrame{SendSN: sn, RecvSN: sn}).Data())
+		if err != nil {
+			t.Fatalf("Parse(I N(S)=%d) error = %v", sn, err)
+		}
+		if got := frame.(*IFrame); got.SendSN != sn || got.RecvSN != sn {
+			t.Fatalf("Parse(I N(S)=%d N(R)=%d) = %+v", sn, sn, got)
+		}
+
+		frame, err = new(APCI).Parse((&SFrame{RecvSN: sn}).Data())
+		if err != nil {
+			t.Fatalf("Parse(S N(R)=%d) error = %v", sn, err)
+		}
+		if got := frame.(*SFrame); got.RecvSN != sn {
+			t.Fatalf("Parse(S N(R)=%d) = %+v", sn, got)
+		}
+	}
+
+	// the most significant bits of N(S) and N(R) are in CF2 and CF4
+	data := []byte{0xfe, 0xff, 0xfe, 0xff}
+	frame, err := new(APCI).Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := frame.(*IFrame); got.SendSN != 1<<15-1 || got.RecvSN != 1<<15-1 {
+		t.Errorf("Parse([% X]) = %+v, want N(S)=N(R)=%d", data, got, 1<<15-1)
+	}
+}
+
+func TestAPCI_ParseFrameType(t *testing.T) {
+	for cf1 := 0; cf1 < 1<<8; cf1++ {
+		want := FrameTypeI
+		switch cf1 & 0x3 {
+		case 0b01:
+			want = FrameTypeS
+		case 0b11:
+			want = FrameTypeU
+		}
+		frame, err := new(APCI).Parse([]byte{byte(cf1), 0x00, 0x00, 0x00})
+		if err != nil {
+			t.Fatalf("Parse(CF1 %08b) error = %v", cf1, err)
+		}
+		if got := frame.Type(); got != want {
+			t.Errorf("Parse(CF1 %08b) = %s frame, want %s frame", cf1, got, want)
+		}
+	}
+}
+
+func TestFrameType_String(t *testing.T) {
+	tests := []struct {
+		frameType FrameType
+		want      string
+	}{
+		{FrameTypeI, "I"},
+		{FrameTypeS, "S"},
+		{FrameTypeU, "U"},
+		{FrameType(0x02), "FrameType(2)"},
+	}
+	for _, tt := range tests {
+		if got := tt.frameType.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func Test_seqArithmetic(t *testing.T) {
+	const last = seqModulus - 1
+	tests := []struct {
+		name     string
+		a, b     uint16
+		distance uint16
+		less     bool
+	}{
+		{"equal", 5, 5, 0, false},
+		{"ahead by 1", 5, 6, 1, true},
+		{"behind by 1", 6, 5, last, false},
+		{"wrap from the last to 0", last, 0, 1, true},
+		{"0 behind the last", 0, last, last, false},
+		{"wrap ahead by 10", last - 4, 5, 10, true},
+		{"ahead by less than half", 0, seqModulus/2 - 1, seqModulus/2 - 1, true},
+		{"ahead by half", 0, seqModulus / 2, seqModulus / 2, false},
+		{"wrap ahead by less than half", seqModulus / 2, last, seqModulus/2 - 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seqDistance(tt.a, tt.b); got != tt.distance {
+				t.Errorf("seqDistance(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.distance)
+			}
+			if got := seqLess(tt.a, tt.b); got != tt.less {
+				t.Errorf("seqLess(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.less)
+			}
+		})
+	}
+}
+
+func Test_seqNext(t *testing.T) {
+	tests := []struct {
+		n, want uint16
+	}{
+		{0, 1},
+		{seqModulus - 2, seqModulus - 1},
+		{seqModulus - 1, 0},
+	}
+	for _, tt := range tests {
+		if got := seqNext(tt.n); got != tt.want {
+			t.Errorf("seqNext(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func Test_seqInWindow(t *testing.T) {
+	const last = seqModulus - 1
+	tests := []struct {
+		name      string
+		n, lo, hi uint16
+		want      bool
+	}{
+		{"lower bound", 10, 10, 20, true},
+		{"upper bound", 20, 10, 20, true},
+		{"inside", 15, 10, 20, true},
+		{"below", 9, 10, 20, false},
+		{"above", 21, 10, 20, false},
+		{"empty window", 10, 10, 10, true},
+		{"outside empty window", 11, 10, 10, false},
+		{"wrapped lower part", last, last - 2, 3, true},
+		{"wrapped upper part", 2, last - 2, 3, true},
+		{"wrapped upper bound", 3, last - 2, 3, true},
+		{"above wrapped window", 4, last - 2, 3, false},
+		{"below wrapped window", last - 3, last - 2, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seqInWindow(tt.n, tt.lo, tt.hi); got != tt.want {
+				t.Errorf("seqInWindow(%d, %d, %d) = %v, want %v", tt.n, tt.lo, tt.hi, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,17 @@ package iec104
 
 import (
 	"fmt"
+	"time"
 )
 
 const (
 	ApduHeaderLen = 4 // non-include startByte and apduLen
+	// Deprecated: AsduHeaderLen is only the length of the data unit identifier with the standard 2-byte COT and 2-byte
+	// COA, it's 2 bytes (TypeID and VSQ) plus the lengths of COT and COA set by SetCOTLength and SetCOALength.
 	AsduHeaderLen = 6
+	CotLen        = 2   // standard length of COT including ORG, the profiles without ORG use 1-byte COT
+	CoaLen        = 2   // standard length of COA, some deployments bridged from IEC 101 use 1-byte COA
+	MaxApduLen    = 253 // the maximum length of APDU (non-include startByte and apduLen)
 )
 
 /*
@@ -14,38 +20,150 @@ APDU (Application Protocol Data Unit).
 
 APDU contains an APCI or an APCI with ASDU.
 
-  | <-   8 bits    -> |  -----    -----
-  | Start Byte (Ox68) |    |        |
-  | Length of APDU    |    |        |
-  | Control Field 1   |   APCI     APDU
-  | Control Field 2   |    |        |
-  | Control Field 3   |    |        |
-  | Control Field 4   |    |        |
-  | <-   8 bits    -> |  -----    -----
-  <-      APDU with fixed length     ->
-
-
-  | <-   8 bits    -> |  -----    -----
-  | Start Byte (Ox68) |    |        |
-  | Length of APDU    |    |        |
-  | Control Field 1   |   APCI     APDU
-  | Control Field 2   |    |        |
-  | Control Field 3   |    |        |
-  | Control Field 4   |    |        |
-  | ASDU              |   ASDU      |
-  | <-   8 bits    -> |  -----    -----
-  <-    APDU with variable length    ->
+	| <-   8 bits    -> |  -----    -----
+	| Start Byte (Ox68) |    |        |
+	| Length of APDU    |    |        |
+	| Control Field 1   |   APCI     APDU
+	| Control Field 2   |    |        |
+	| Control Field 3   |    |        |
+	| Control Field 4   |    |        |
+	| <-   8 bits    -> |  -----    -----
+	<-      APDU with fixed length     ->
 
+
+	| <-   8 bits    -> |  -----    -----
+	| Start Byte (Ox68) |    |        |
+	| Length of APDU    |    |        |
+	| Control Field 1   |   APCI     APDU
+	| Control Field 2   |    |        |
+	| Control Field 3   |    |        |
+	| Control Field 4   |    |        |
+	| ASDU              |   ASDU      |
+	| <-   8 bits    -> |  -----    -----
+	<-    APDU with variable length    ->
 */
 type APDU struct {
 	*APCI
 	*ASDU
 
-	frame       Frame
+	frame   Frame
+	rawASDU []byte
+	opt     *parseOption
+}
+
+// RawASDU returns the ASDU bytes exactly as received, even if the TypeID isn't supported to decode. It returns nil
+// for S-format and U-format frames which don't have ASDU.
+func (apdu *APDU) RawASDU() []byte {
+	return apdu.rawASDU
+}
+
+// Frame returns the parsed APCI frame, i.e., *IFrame, *SFrame or *UFrame, by which the sequence numbers of I-format
+// and S-format frames are read without reparsing.
+func (apdu *APDU) Frame() Frame {
+	return apdu.frame
+}
+
+// String renders the frame type with the sequence numbers or the function of U-format frame, followed by the ASDU of
+// I-format frame in the lines rendered by ASDU.String, e.g.
+//
+//	I N(S)=3 N(R)=1
+//	M_SP_NA_1 SQ=0 NOO=1 COT=spont(3) ORG=0 COA=1
+//	  IOA=1 value=1 quality=OK
+func (apdu *APDU) String() string {
+	switch frame := apdu.frame.(type) {
+	case *IFrame:
+		s := fmt.Sprintf("I N(S)=%d N(R)=%d", frame.SendSN, frame.RecvSN)
+		if apdu.ASDU != nil {
+			s += "\n" + apdu.ASDU.String()
+		}
+		return s
+	case *SFrame:
+		return fmt.Sprintf("S N(R)=%d", frame.RecvSN)
+	case *UFrame:
+		if len(frame.Cmd) > 0 && uFrameName(frame.Cmd[0]) != "" {
+			return "U " + uFrameName(frame.Cmd[0])
+		}
+		return fmt.Sprintf("U [% X]", frame.Cmd)
+	}
+	return "APDU <nil>"
+}
+
+// RequiresAck reports whether the frame must be acknowledged by the receiver. Only I-format frames consume the receive
+// sequence number N(R) and are acknowledged, S-format and U-format frames aren't.
+func (apdu *APDU) RequiresAck() bool {
+	return apdu.frame != nil && apdu.frame.Type() == FrameTypeI
+}
+
+// parseOption configures how to parse APDUs, nil means the default behaviors.
+type parseOption struct {
+	cp24Clock    func() time.Time // reference clock to complete the date and hour of CP24Time2a
+	headerOnly   bool             // skip decoding the information objects until ASDU.DecodeElements is called
+	lg           Logger           // logger of the client parsing, the logger of the package if it's nil
+	cotLen       int              // length of COT in bytes, 1 or 2, 0 means CotLen
+	coaLen       int              // length of COA in bytes, 1 or 2, 0 means CoaLen
+	loc          *time.Location   // location of the time tags, time.Local if it's nil
+	cp56YearBase int              // first year of the window of the 2-digit year of CP56Time2a, 0 means the default
+
+	interrogationConfirmations bool // handle the confirmations and terminations of interrogations
+}
+
+// cotLength returns the length of COT to parse.
+func (o *parseOption) cotLength() int {
+	if o == nil || o.cotLen == 0 {
+		return CotLen
+	}
+	return o.cotLen
+}
+
+// coaLength returns the length of COA to parse.
+func (o *parseOption) coaLength() int {
+	if o == nil || o.coaLen == 0 {
+		return CoaLen
+	}
+	return o.coaLen
+}
+
+// validFieldLength reports whether n is the supported length of COT or COA, i.e. 1 or 2 bytes.
+func validFieldLength(n int) bool {
+	return n == 1 || n == 2
+}
+
+// location returns the location to decode the time tags in.
+func (o *parseOption) location() *time.Location {
+	if o == nil || o.loc == nil {
+		return time.Local
+	}
+	return o.loc
+}
+
+// handlesInterrogationConfirmations reports whether the confirmations and terminations of interrogations are handled.
+func (o *parseOption) handlesInterrogationConfirmations() bool {
+	return o != nil && o.interrogationConfirmations
+}
+
+// referenceTime returns the reference time to complete CP24Time2a.
+func (o *parseOption) referenceTime() time.Time {
+	if o == nil || o.cp24Clock == nil {
+		return time.Now()
+	}
+	return o.cp24Clock()
+}
+
+// ParseHeaderOnly parses the APCI and the data unit identifier of ASDU (TypeID, SQ, NOO, COT, ORG and COA) only, the
+// information objects are decoded on demand by DecodeElements. It saves the decoding for pass-through scenarios which
+// route frames by TypeID or COA.
+func (apdu *APDU) ParseHeaderOnly(data []byte) error {
+	opt := parseOption{}
+	if apdu.opt != nil {
+		opt = *apdu.opt
+	}
+	opt.headerOnly = true
+	apdu.opt = &opt
+	return apdu.Parse(data)
 }
 
 func (apdu *APDU) Parse(data []byte) error {
-	if len(data) < ApduHeaderLen {
+	if len(data) < ApduHeaderLen || len(data) > MaxApduLen {
 		return fmt.Errorf("invalid apdu body: % X", data)
 	}
 
@@ -59,16 +177,38 @@ func (apdu *APDU) Parse(data []byte) error {
 	apdu.frame = frame
 
 	switch frame.Type() {
-	case FrameTypeS, FrameTypeU: // S-format or U-format frame doesn't have ASDU.
+	case FrameTypeS, FrameTypeU: // S-format or U-format frame doesn't have ASDU, so it has fixed length.
+		if len(data) != ApduHeaderLen {
+			return fmt.Errorf("invalid length of s/u frame: %d, expected: %d", len(data), ApduHeaderLen)
+		}
 		return nil
 	}
 
 	// Parse ASDU.
-	asdu := new(ASDU)
-	if err = asdu.Parse(data[ApduHeaderLen:]); err != nil {
+	apdu.rawASDU = append([]byte(nil), data[ApduHeaderLen:]...)
+	asdu := &ASDU{opt: apdu.opt}
+	if err = asdu.Parse(apdu.rawASDU); err != nil {
 		return err
 	}
 	apdu.ASDU = asdu
 
 	return nil
 }
+
+// checkApduLen checks the length of APDU declared in the second byte of frame.
+func checkApduLen(apduLen uint8) error {
+	if apduLen < ApduHeaderLen || apduLen > MaxApduLen {
+		return fmt.Errorf("invalid length of apdu: %d", apduLen)
+	}
+	return nil
+}
+
+// buildFrame prepends startByte and the length of APDU to the data (control fields and ASDU).
+func buildFrame(data []byte) []byte {
+	frame := make([]byte, 0, 0)
+	iBytes := serializeBigEndianUint16(uint16(len(data)))
+	frame = append(frame, startByte)
+	frame = append(frame, iBytes[1])
+	frame = append(frame, data...)
+	return frame
+}
@@ -1 +1,236 @@
 package iec104
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAPDU_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    FrameType
+		wantErr bool
+	}{
+		{"s frame", []byte{0x01, 0x00, 0x02, 0x00}, FrameTypeS, false},
+		{"u frame", []byte{0x07, 0x00, 0x00, 0x00}, FrameTypeU, false},
+		{"i frame", []byte{0x00, 0x00, 0x00, 0x00, 0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}, FrameTypeI, false},
+		{"short s frame", []byte{0x01, 0x00, 0x02}, 0, true},
+		{"long s frame", []byte{0x01, 0x00, 0x02, 0x00, 0x00}, 0, true},
+		{"short u frame", []byte{0x07, 0x00}, 0, true},
+		{"long u frame", []byte{0x43, 0x00, 0x00, 0x00, 0x64, 0x01, 0x07, 0x00, 0x01, 0x00}, 0, true},
+		{"too long frame", make([]byte, MaxApduLen+1), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu := new(APDU)
+			err := apdu.Parse(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && apdu.frame.Type() != tt.want {
+				t.Errorf("Parse() frame type = %v, want %v", apdu.frame.Type(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkApduLen(t *testing.T) {
+	tests := []struct {
+		name    string
+		apduLen uint8
+		wantErr bool
+	}{
+		{"empty", 0, true},
+		{"shorter than control fields", 3, true},
+		{"s/u frame", 4, false},
+		{"maximum", MaxApduLen, false},
+		{"longer than maximum", MaxApduLen + 1, true},
+		{"255", 255, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkApduLen(tt.apduLen); (err != nil) != tt.wantErr {
+				t.Errorf("checkApduLen() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPDU_RawASDU(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{
+			"unsupported type",
+			[]byte{0x02, 0x00, 0x00, 0x00, 0x7f, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0xaa, 0xbb, 0xcc},
+			[]byte{0x7f, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0xaa, 0xbb, 0xcc},
+		},
+		{
+			"single point information",
+			[]byte{0x02, 0x00, 0x00, 0x00, 0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01},
+			[]byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01},
+		},
+		{
+			"s frame",
+			[]byte{0x01, 0x00, 0x02, 0x00},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu := new(APDU)
+			if err := apdu.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := apdu.RawASDU(); !bytes.Equal(got, tt.want) {
+				t.Errorf("RawASDU() = [% X], want [% X]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPDU_Frame(t *testing.T) {
+	apdu := new(APDU)
+	// N(S)=5, N(R)=300
+	if err := apdu.Parse([]byte{0x0a, 0x00, 0x58, 0x02, 0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := apdu.Frame().Type(); got != FrameTypeI {
+		t.Fatalf("Frame().Type() = %s, want %s", got, FrameTypeI)
+	}
+	iFrame, ok := apdu.Frame().(*IFrame)
+	if !ok {
+		t.Fatalf("Frame() = %T, want *IFrame", apdu.Frame())
+	}
+	if iFrame.SendSN != 5 || iFrame.RecvSN != 300 {
+		t.Errorf("N(S), N(R) = %d, %d, want 5, 300", iFrame.SendSN, iFrame.RecvSN)
+	}
+}
+
+func TestAPDU_RequiresAck(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"i frame", []byte{0x00, 0x00, 0x00, 0x00, 0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}, true},
+		{"s frame", []byte{0x01, 0x00, 0x02, 0x00}, false},
+		{"u frame", []byte{0x07, 0x00, 0x00, 0x00}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu := new(APDU)
+			if err := apdu.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := apdu.RequiresAck(); got != tt.want {
+				t.Errorf("RequiresAck() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// shortFloatFrame builds an I-format frame of MMeNc1 with n objects, SQ=0, CotSpont and COA=1.
+func shortFloatFrame(n int) []byte {
+	data := []byte{0x00, 0x00, 0x00, 0x00, byte(MMeNc1), byte(n), byte(CotSpont), 0x00, 0x01, 0x00}
+	for i := 0; i < n; i++ {
+		data = append(data, byte(i+1), 0x40, 0x00)        // IOA
+		data = append(data, 0x00, 0x80, 0x66, 0x43, 0x00) // 230.5 without quality
+	}
+	return data
+}
+
+func TestAPDU_ParseHeaderOnly(t *testing.T) {
+	data := shortFloatFrame(3)
+
+	apdu := new(APDU)
+	if err := apdu.ParseHeaderOnly(data); err != nil {
+		t.Fatalf("ParseHeaderOnly() error = %v", err)
+	}
+	if apdu.typeID != MMeNc1 || apdu.nObjs != 3 || apdu.cot != CotSpont || apdu.coa != 1 {
+		t.Errorf("ParseHeaderOnly() header = {%X, %d, %d, %d}, want {%X, 3, %d, 1}",
+			apdu.typeID, apdu.nObjs, apdu.cot, apdu.coa, MMeNc1, CotSpont)
+	}
+	if len(apdu.Signals) != 0 {
+		t.Errorf("ParseHeaderOnly() decodes %d signals, want 0", len(apdu.Signals))
+	}
+
+	if err := apdu.DecodeElements(); err != nil {
+		t.Fatalf("DecodeElements() error = %v", err)
+	}
+	if err := apdu.DecodeElements(); err != nil { // decoded only once
+		t.Fatalf("DecodeElements() error = %v", err)
+	}
+	want := new(APDU)
+	if err := want.Parse(data); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(apdu.Signals) != len(want.Signals) {
+		t.Fatalf("DecodeElements() decodes %d signals, want %d", len(apdu.Signals), len(want.Signals))
+	}
+	for i, signal := range apdu.Signals {
+		if signal.Address != want.Signals[i].Address || signal.Value != want.Signals[i].Value {
+			t.Errorf("Signals[%d] = {%d, %f}, want {%d, %f}", i,
+				signal.Address, signal.Value, want.Signals[i].Address, want.Signals[i].Value)
+		}
+	}
+}
+
+func BenchmarkAPDU_Parse(b *testing.B) {
+	data := shortFloatFrame(30)
+	for i := 0; i < b.N; i++ {
+		if err := new(APDU).Parse(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAPDU_ParseHeaderOnly(b *testing.B) {
+	data := shortFloatFrame(30)
+	for i := 0; i < b.N; i++ {
+		if err := new(APDU).ParseHeaderOnly(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAPDU_String(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"s frame", []byte{0x01, 0x00, 0x0a, 0x00}, "S N(R)=5"},
+		{"u frame", []byte{0x43, 0x00, 0x00, 0x00}, "U TestFA"},
+		{
+			"interrogation confirmation",
+			[]byte{0x06, 0x00, 0x02, 0x00, 0x64, 0x01, 0x47, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x15},
+			"I N(S)=3 N(R)=1\nC_IC_NA_1 SQ=0 NOO=1 COT=actcon(7) negative ORG=0 COA=1\n  IOA=0 value=21",
+		},
+		{
+			"single points (SQ=1)",
+			[]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x82, 0x14, 0x02, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x01, 0x80},
+			"I N(S)=0 N(R)=0\nM_SP_NA_1 SQ=1 NOO=2 COT=inrogen(20) ORG=2 COA=1\n" +
+				"  IOA=10 value=1 quality=OK\n  IOA=11 value=0 quality=IV",
+		},
+		{
+			"unsupported type",
+			[]byte{0x00, 0x00, 0x00, 0x00, 0x7f, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0xaa},
+			"I N(S)=0 N(R)=0\nTypeID(127) SQ=0 NOO=1 COT=spont(3) ORG=0 COA=1\n  IOA=1 raw=[AA]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu := new(APDU)
+			if err := apdu.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := apdu.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package iec104
 import (
 	"encoding/binary"
 	"fmt"
+	"strings"
+	"time"
 )
 
 /*
@@ -36,15 +38,15 @@ The format of ASDU:
 
 */
 type ASDU struct {
-	// Data Uint Identifier(with the fixed length of 6 bytes)
+	// Data Uint Identifier(with the fixed length of 6 bytes, or 4-5 bytes with 1-byte COT or COA)
 	typeID TypeID // 8  bits
 	sq     SQ     // 1  bit
 	nObjs  NOO    // 7  bits
 	t      T      // 1  bit
 	pn     PN     // 1  bit
 	cot    COT    // 6  bits
-	org    ORG    // 8  bits
-	coa    COA    // 16 bits
+	org    ORG    // 8  bits, absent if cotLen is 1
+	coa    COA    // 16 bits, or 8 bits if coaLen is 1
 
 	toBeHandled bool
 	sendSFrame  bool
@@ -52,11 +54,22 @@ type ASDU struct {
 
 	ios     []*InformationObject
 	Signals []*InformationElement
+
+	cotLen   int // length of COT in bytes, 1 or 2, 0 means CotLen
+	coaLen   int // length of COA in bytes, 1 or 2, 0 means CoaLen
+	opt      *parseOption
+	ref      time.Time      // reference time to complete CP24Time2a
+	loc      *time.Location // location of the time tags, time.Local if it's nil
+	yearBase int            // first year of the window of the 2-digit year of CP56Time2a, 0 means DefaultCP56YearBase
+	body     []byte         // information objects not decoded yet, see APDU.ParseHeaderOnly
 }
 
 func (asdu *ASDU) Parse(data []byte) error {
+	asdu.cotLen = asdu.opt.cotLength()
+	asdu.coaLen = asdu.opt.coaLength()
+	headerLen := asdu.headerLen()
 	// I-format frame have ASDU.
-	if len(data) < AsduHeaderLen {
+	if len(data) < headerLen {
 		return fmt.Errorf("invalid asdu header: % X", data)
 	}
 
@@ -69,13 +82,67 @@ func (asdu *ASDU) Parse(data []byte) error {
 	asdu.parseT(data[2])
 	asdu.parsePN(data[2])
 	asdu.parseCOT(data[2])
-	// the 4th byte
-	asdu.parseORG(data[3])
-	// the 5th and 6th bytes
-	asdu.parseCOA(data[4:AsduHeaderLen])
+	// the 4th byte if COT is 2 bytes
+	coaStart := 2 + asdu.cotLength()
+	if asdu.cotLength() == 2 {
+		asdu.parseORG(data[3])
+	} else {
+		asdu.org = 0
+	}
+	// the 2 bytes, or 1 byte if COA is 1 byte, following COT
+	asdu.parseCOA(data[coaStart:headerLen])
 
-	asdu.parseInformationObjects(data[AsduHeaderLen:])
-	return nil
+	asdu.ref = asdu.opt.referenceTime()
+	asdu.loc = asdu.opt.location()
+	if asdu.opt != nil {
+		asdu.yearBase = asdu.opt.cp56YearBase
+	}
+	if asdu.opt != nil && asdu.opt.headerOnly {
+		asdu.body = data[headerLen:]
+		return nil
+	}
+	return asdu.parseInformationObjects(data[headerLen:])
+}
+
+// headerLen returns the length of the data unit identifier, which is 6 bytes for the standard 2-byte COT and 2-byte
+// COA, and 4 bytes for 1-byte COT and COA.
+func (asdu *ASDU) headerLen() int {
+	return 2 + asdu.cotLength() + asdu.coaLength()
+}
+
+// cotLength returns the length of COT in bytes.
+func (asdu *ASDU) cotLength() int {
+	if asdu.cotLen == 0 {
+		return CotLen
+	}
+	return asdu.cotLen
+}
+
+// coaLength returns the length of COA in bytes.
+func (asdu *ASDU) coaLength() int {
+	if asdu.coaLen == 0 {
+		return CoaLen
+	}
+	return asdu.coaLen
+}
+
+// logger returns the logger of the client parsing the ASDU, or the logger of the package.
+func (asdu *ASDU) logger() Logger {
+	if asdu.opt != nil && asdu.opt.lg != nil {
+		return asdu.opt.lg
+	}
+	return pkgLogger()
+}
+
+// DecodeElements decodes the information objects skipped by APDU.ParseHeaderOnly into Signals, it does nothing if
+// they have been decoded.
+func (asdu *ASDU) DecodeElements() error {
+	if asdu.body == nil {
+		return nil
+	}
+	body := asdu.body
+	asdu.body = nil
+	return asdu.parseInformationObjects(body)
 }
 
 func (asdu *ASDU) Data() []byte {
@@ -85,7 +152,7 @@ func (asdu *ASDU) Data() []byte {
 	// the 2nd byte
 	data = append(data, func() byte {
 		if asdu.sq {
-			return (0b1 << 7) & asdu.nObjs
+			return (0b1 << 7) | asdu.nObjs
 		} else {
 			return asdu.nObjs
 		}
@@ -93,35 +160,110 @@ func (asdu *ASDU) Data() []byte {
 	// the 3rd byte
 	data = append(data, func() byte {
 		if bool(asdu.t) && bool(asdu.pn) {
-			return (0b11 << 6) & byte(asdu.cot)
+			return (0b11 << 6) | byte(asdu.cot)
 		} else if asdu.t {
-			return (0b1 << 7) & byte(asdu.cot)
+			return (0b1 << 7) | byte(asdu.cot)
 		} else if asdu.pn {
-			return (0b1 << 6) & byte(asdu.cot)
+			return (0b1 << 6) | byte(asdu.cot)
 		} else {
 			return byte(asdu.cot)
 		}
 	}())
-	// the 4th byte
-	data = append(data, byte(asdu.org))
-	// the 5th and 6th bytes
+	// the 4th byte if COT is 2 bytes
+	if asdu.cotLength() == 2 {
+		data = append(data, byte(asdu.org))
+	}
+	// the 2 bytes, or 1 byte if COA is 1 byte, following COT
 	data = append(data, func() []byte {
+		if asdu.coaLength() == 1 {
+			return []byte{byte(asdu.coa)}
+		}
 		x := make([]byte, 2, 2)
 		binary.LittleEndian.PutUint16(x, asdu.coa)
 		return x
 	}()...)
 
 	// the remaining bytes (some information objects)
-	data = append(data, func() []byte {
-		x := make([]byte, 0)
-		for _, signal := range asdu.ios {
-			x = append(x, signal.Data()...)
+	data = append(data, asdu.serializeInformationObjects()...)
+	return data
+}
+
+// serializeInformationObjects serializes the information objects by SQ. Each object is serialized with its IOA if
+// SQ=0, otherwise the IOA of the first object is followed by the elements of all objects, whose addresses are
+// continuous by +1 from it.
+func (asdu *ASDU) serializeInformationObjects() []byte {
+	data := make([]byte, 0)
+	if !asdu.sq {
+		for _, io := range asdu.ios {
+			data = append(data, io.Data()...)
 		}
-		return x
-	}()...)
+		return data
+	}
+
+	if len(asdu.ios) == 0 {
+		return data
+	}
+	data = append(data, asdu.ios[0].serializeIOA()...)
+	for _, io := range asdu.ios {
+		for _, ie := range io.ies {
+			data = append(data, ie.encoded()...)
+		}
+	}
 	return data
 }
 
+// TypeID returns the type identification of the ASDU.
+func (asdu *ASDU) TypeID() TypeID {
+	return asdu.typeID
+}
+
+// COT returns the cause of transmission of the ASDU.
+func (asdu *ASDU) COT() COT {
+	return asdu.cot
+}
+
+// OriginatorAddress returns the originator address (ORG) of the ASDU, by which the confirmations are routed to the
+// controlling station sending the command when there are multiple controlling stations. It's 0 without ORG, see
+// ClientOption.SetCOTLength.
+func (asdu *ASDU) OriginatorAddress() ORG {
+	return asdu.org
+}
+
+// CommonAddress returns the common address (COA) of the ASDU, which is the station sending the data in monitor
+// direction, or the station which the command is sent to in control direction.
+func (asdu *ASDU) CommonAddress() COA {
+	return asdu.coa
+}
+
+// String renders the data unit identifier in a line, followed by an indented line per information element, e.g.
+//
+//	M_ME_NC_1 SQ=0 NOO=2 COT=spont(3) ORG=0 COA=1
+//	  IOA=16385 value=1.5 quality=OK
+//	  IOA=16387 value=-2 quality=IV
+func (asdu *ASDU) String() string {
+	var b strings.Builder
+	sq := 0
+	if asdu.sq {
+		sq = 1
+	}
+	fmt.Fprintf(&b, "%s SQ=%d NOO=%d COT=%s(%d)", asdu.typeID, sq, asdu.nObjs, asdu.cot, uint8(asdu.cot))
+	if asdu.t {
+		b.WriteString(" test")
+	}
+	if asdu.pn {
+		b.WriteString(" negative")
+	}
+	fmt.Fprintf(&b, " ORG=%d COA=%d", asdu.org, asdu.coa)
+
+	for _, io := range asdu.ios {
+		for i, ie := range io.ies {
+			// the elements of the sequence (SQ=1) are addressed continuously from the IOA
+			fmt.Fprintf(&b, "\n  IOA=%d %s", io.ioa+IOA(i), ie.describe())
+		}
+	}
+	return b.String()
+}
+
 /*
 TypeID (Type Identification, 1 byte):
 - value range:
@@ -162,6 +304,26 @@ const (
 	// COT: CotSpont
 	// [遥信 - 双点 - 三字节时标]
 	MDpTa1 TypeID = 0x4 // 4
+	// MStNa1 indicates step position information.
+	// InformationElementType: VTI + QDS
+	// COT: 2, 3, 5, 11, 12, 20, 20+G
+	// [步位置信息 - 不带时标]
+	MStNa1 TypeID = 0x5 // 5
+	// MStTa1 indicates step position information with time tag CP24Time2a.
+	// InformationElementType: VTI + QDS + CP24Time2a
+	// COT: 3, 5, 11, 12
+	// [步位置信息 - 三字节时标]
+	MStTa1 TypeID = 0x6 // 6
+	// MBoNa1 indicates bitstring of 32 bits.
+	// InformationElementType: BSI + QDS
+	// COT: 2, 3, 5, 11, 12, 20, 20+G
+	// [32 比特串 - 不带时标]
+	MBoNa1 TypeID = 0x7 // 7
+	// MBoTa1 indicates bitstring of 32 bits with time tag CP24Time2a.
+	// InformationElementType: BSI + QDS + CP24Time2a
+	// COT: 3, 5
+	// [32 比特串 - 三字节时标]
+	MBoTa1 TypeID = 0x8 // 8
 	// MMeNa1 indicates measured value, normalized value.
 	// InformationElementType: NVA + QDS
 	// COT: 2, 3, 5, 11, 12, 20, 20+G
@@ -194,6 +356,26 @@ const (
 	// InformationElementType: BCR + CP24Time2a
 	// COT: 3, CotReqcogen, 37+G
 	MItTa1 TypeID = 0x10 // 16
+	// MEpTa1 indicates event of protection equipment with time tag CP24Time2a.
+	// InformationElementType: SEP + CP16Time2a + CP24Time2a
+	// COT: 3
+	// [继电保护设备事件 - 三字节时标]
+	MEpTa1 TypeID = 0x11 // 17
+	// MEpTb1 indicates packed start events of protection equipment with time tag CP24Time2a.
+	// InformationElementType: SPE + QDP + CP16Time2a + CP24Time2a
+	// COT: 3
+	// [继电保护设备成组启动事件 - 三字节时标]
+	MEpTb1 TypeID = 0x12 // 18
+	// MEpTc1 indicates packed output circuit information of protection equipment with time tag CP24Time2a.
+	// InformationElementType: OCI + QDP + CP16Time2a + CP24Time2a
+	// COT: 3
+	// [继电保护设备成组输出电路信息 - 三字节时标]
+	MEpTc1 TypeID = 0x13 // 19
+	// MPsNa1 indicates packed single point information with status change detection.
+	// InformationElementType: SCD + QDS
+	// COT: 2, 3, 5, 11, 12, 20, 20+G
+	// [带变位检出的成组单点信息]
+	MPsNa1 TypeID = 0x14 // 20
 	// MMeNd1 indicates measured value, normalized value without quality descriptor.
 	// InformationElementType: NVA
 	// COT: 1,2,3,5,11,12,20,20+G
@@ -210,6 +392,14 @@ const (
 	// InformationElementType: DIQ + CP56Time2a
 	// COT: 3,5,11,12
 	MDpTb1 TypeID = 0x1f // 31
+	// MStTb1 indicates step position information with time tag CP56Time2a.
+	// InformationElementType: VTI + QDS + CP56Time2a
+	// COT: 3, 5, 11, 12
+	MStTb1 TypeID = 0x20 // 32
+	// MBoTb1 indicates bitstring of 32 bits with time tag CP56Time2a.
+	// InformationElementType: BSI + QDS + CP56Time2a
+	// COT: 3, 5
+	MBoTb1 TypeID = 0x21 // 33
 	// MMeTd1 indicates measured value, normalized value with time tag CP56Time2a.
 	// InformationElementType: NVA + QDS + CP56Time2a
 	// COT: CotSpont, 5
@@ -226,6 +416,18 @@ const (
 	// InformationElementType: BCR + CP56Time2a
 	// COT: CotSpont, CotReqcogen, 37+G
 	MItTb1 TypeID = 0x25 // 37
+	// MEpTd1 indicates event of protection equipment with time tag CP56Time2a.
+	// InformationElementType: SEP + CP16Time2a + CP56Time2a
+	// COT: 3
+	MEpTd1 TypeID = 0x26 // 38
+	// MEpTe1 indicates packed start events of protection equipment with time tag CP56Time2a.
+	// InformationElementType: SPE + QDP + CP16Time2a + CP56Time2a
+	// COT: 3
+	MEpTe1 TypeID = 0x27 // 39
+	// MEpTf1 indicates packed output circuit information of protection equipment with time tag CP56Time2a.
+	// InformationElementType: OCI + QDP + CP16Time2a + CP56Time2a
+	// COT: 3
+	MEpTf1 TypeID = 0x28 // 40
 
 	// Process information in control direction.
 
@@ -253,6 +455,10 @@ const (
 	// InformationElementType: IEEE754STD + QOS
 	// COT: 6, 7, 8, 9, 10, 44, 45, 46, 47
 	CSeNc1 TypeID = 0x32 // 50
+	// CBoNa1 indicates bitstring of 32 bits command.
+	// InformationElementType: BSI
+	// COT: 6, 7, 8, 9, 10, 44, 45, 46, 47
+	CBoNa1 TypeID = 0x33 // 51
 
 	// Command telegrams with long time tag.
 
@@ -300,13 +506,38 @@ const (
 	CRpNc1 TypeID = 0x69 // 105
 	// CCdNa1 indicates delay acquisition command.
 	// InformationElementType: CP16Time2a
-	// COT: CotAct, CotActCon, 44, 45, 46, 47
+	// COT: CotSpont, CotAct, CotActCon, 44, 45, 46, 47
 	CCdNa1 TypeID = 0x6a // 106
 	// CTsTa1 indicates command with time tag CP56Time2a.
 	// InformationElementType:
 	CTsTa1 TypeID = 0x6b // 107
 )
 
+// typeIDNames are the names of TypeIDs defined by IEC 60870-5-101 and IEC 60870-5-104.
+var typeIDNames = map[TypeID]string{
+	MSpNa1: "M_SP_NA_1", MSpTa1: "M_SP_TA_1", MDpNa1: "M_DP_NA_1", MDpTa1: "M_DP_TA_1",
+	MStNa1: "M_ST_NA_1", MStTa1: "M_ST_TA_1", MBoNa1: "M_BO_NA_1", MBoTa1: "M_BO_TA_1",
+	MMeNa1: "M_ME_NA_1", MMeTa1: "M_ME_TA_1", MMeNb1: "M_ME_NB_1", MMeTb1: "M_ME_TB_1",
+	MMeNc1: "M_ME_NC_1", MMeTc1: "M_ME_TC_1", MItNa1: "M_IT_NA_1", MItTa1: "M_IT_TA_1",
+	MEpTa1: "M_EP_TA_1", MEpTb1: "M_EP_TB_1", MEpTc1: "M_EP_TC_1", MPsNa1: "M_PS_NA_1",
+	MMeNd1: "M_ME_ND_1", MSpTb1: "M_SP_TB_1", MDpTb1: "M_DP_TB_1", MStTb1: "M_ST_TB_1",
+	MBoTb1: "M_BO_TB_1", MMeTd1: "M_ME_TD_1", MMeTe1: "M_ME_TE_1", MMeTf1: "M_ME_TF_1",
+	MItTb1: "M_IT_TB_1", MEpTd1: "M_EP_TD_1", MEpTe1: "M_EP_TE_1", MEpTf1: "M_EP_TF_1",
+	CScNa1: "C_SC_NA_1", CDcNa1: "C_DC_NA_1", CRcNa1: "C_RC_NA_1", CSeNa1: "C_SE_NA_1",
+	CSeNb1: "C_SE_NB_1", CSeNc1: "C_SE_NC_1", CBoNa1: "C_BO_NA_1", CScTa1: "C_SC_TA_1", CDcTa1: "C_DC_TA_1",
+	CSeTa1: "C_SE_TA_1", CSeTb1: "C_SE_TB_1", CSeTc1: "C_SE_TC_1", CIcNa1: "C_IC_NA_1",
+	CCiNa1: "C_CI_NA_1", CRdNa1: "C_RD_NA_1", CCsNa1: "C_CS_NA_1", CTsNb1: "C_TS_NB_1",
+	CRpNc1: "C_RP_NC_1", CCdNa1: "C_CD_NA_1", CTsTa1: "C_TS_TA_1",
+}
+
+// String returns the name of the TypeID, e.g. "M_SP_NA_1", or "TypeID(n)" if it isn't defined.
+func (t TypeID) String() string {
+	if name, ok := typeIDNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TypeID(%d)", uint8(t))
+}
+
 func (asdu *ASDU) parseTypeID(data byte) TypeID {
 	asdu.typeID = TypeID(data)
 	return asdu.typeID
@@ -367,6 +598,9 @@ NOO (Number of Objects/Elements, 7 bits).
 */
 type NOO = uint8
 
+// MaxNOO is the maximum number of information objects or elements of an ASDU.
+const MaxNOO NOO = 127
+
 func (asdu *ASDU) parseNOO(data byte) NOO {
 	asdu.nObjs = data & 0b1111111
 	return asdu.nObjs
@@ -462,6 +696,39 @@ const (
 	// TODO How to support COT for special use?
 )
 
+// cotNames are the abbreviations of the standard definitions of COT.
+var cotNames = map[COT]string{
+	CotPerCyc: "per/cyc", CotBack: "back", CotSpont: "spont", CotInit: "init", CotReq: "req", CotAct: "act",
+	CotActCon: "actcon", CotDeact: "deact", CotDeactCon: "deactcon", CotActTerm: "actterm", CotRetRem: "retrem",
+	CotRetLoc: "retloc", CotFile: "file", CotInrogen: "inrogen", CotReqcogen: "reqcogen",
+	CotUnknownType: "unknown type", CotUnknownCause: "unknown cause", CotUnknownAsduAddress: "unknown ASDU address",
+	CotUnknownObjectAddress: "unknown object address",
+}
+
+// String returns the abbreviation of the COT, e.g. "spont", "inro1" or "reqco1" for the groups, or "COT(n)" if it
+// isn't defined.
+func (cot COT) String() string {
+	if name, ok := cotNames[cot]; ok {
+		return name
+	}
+	switch {
+	case cot >= CotInro1 && cot <= CotInro16:
+		return fmt.Sprintf("inro%d", cot-CotInrogen)
+	case cot >= CotReqco1 && cot <= CotReqco4:
+		return fmt.Sprintf("reqco%d", cot-CotReqcogen)
+	}
+	return fmt.Sprintf("COT(%d)", uint8(cot))
+}
+
+// InterrogationGroup returns the group (1-16) of the general interrogation which is responded by the COT.
+// 0 means the COT isn't a response of group interrogation (including the station interrogation CotInrogen).
+func (cot COT) InterrogationGroup() uint8 {
+	if cot >= CotInro1 && cot <= CotInro16 {
+		return uint8(cot - CotInrogen)
+	}
+	return 0
+}
+
 func (asdu *ASDU) parseCOT(data byte) COT {
 	asdu.cot = COT(data & 0b111111)
 	return asdu.cot
@@ -488,7 +755,8 @@ func (asdu *ASDU) parseORG(data byte) ORG {
 
 /*
 COA (Common Address of ASDU, 2 bytes) is normally interpreted as a station address.
-- COA is either 1 or 2 bytes in length, fixed on pre-system basis. The value range of 2 bytes (the standard):
+- COA is either 1 or 2 bytes in length, fixed on pre-system basis. The value range of 1 byte is 1-254 and 255 is the
+  global address, which is parsed as GlobalCOA. The value range of 2 bytes (the standard):
   - 0 is not used;
   - 1-65534 means a station address;
   - 65535 means global address, and it is broadcast in control direction have to be answered in monitor direction by
@@ -502,7 +770,32 @@ COA (Common Address of ASDU, 2 bytes) is normally interpreted as a station addre
 */
 type COA = uint16
 
+// GlobalCOA is the global address to broadcast the ASDU to all stations.
+const GlobalCOA COA = 0xffff
+
+// IsGlobalAddress reports whether the COA is the global address GlobalCOA, which is broadcast in control direction by
+// CIcNa1, CCiNa1, CCsNa1 and CRpNc1 only, and answered in monitor direction by the specific address of the station.
+func IsGlobalAddress(coa COA) bool {
+	return coa == GlobalCOA
+}
+
+// checkCOA checks whether COA fits in the length of COA, i.e. 1-byte COA is in [1, 254] or GlobalCOA, which is sent
+// as 255.
+func (asdu *ASDU) checkCOA() error {
+	if asdu.coaLength() == 1 && asdu.coa > 0xfe && !IsGlobalAddress(asdu.coa) {
+		return fmt.Errorf("COA %d exceeds the range [1, 254] of 1-byte COA", asdu.coa)
+	}
+	return nil
+}
+
 func (asdu *ASDU) parseCOA(data []byte) COA {
+	if len(data) == 1 {
+		asdu.coa = COA(data[0])
+		if data[0] == 0xff {
+			asdu.coa = GlobalCOA
+		}
+		return asdu.coa
+	}
 	asdu.coa = binary.LittleEndian.Uint16([]byte{data[0], data[1]})
 	return asdu.coa
 }
@@ -0,0 +1,227 @@
+package iec104
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/*
+ASDUBuilder builds the ASDU in monitor direction carrying one or more information objects of the same TypeID, e.g. the
+measured values and status sent by a controlled station. The information elements are encoded by the format table of
+the TypeID, so only the values are given:
+
+	asdu, err := NewASDU(MMeNc1, CotSpont).
+		AddObject(0x4001, 220.5).
+		AddObject(0x4002, 0.3, IV).
+		Build()
+
+The objects are addressed individually (SQ=0) by default, SetSequence packs them after a single IOA (SQ=1), whose
+addresses must be continuous. The first error of the building is returned by Build.
+*/
+type ASDUBuilder struct {
+	asdu *ASDU
+	ies  []*InformationElement
+	err  error
+}
+
+// NewASDU returns the builder of the ASDU of the TypeID and COT, the TypeID must be in monitor direction (1-40).
+func NewASDU(typeID TypeID, cot COT) *ASDUBuilder {
+	b := &ASDUBuilder{asdu: &ASDU{typeID: typeID, cot: cot}}
+	if _, ok := signalElements[typeID]; !ok {
+		b.err = fmt.Errorf("unsupported type to build: TypeID[%X]", uint8(typeID))
+	}
+	return b
+}
+
+// SetSequence sets whether the objects are packed as a sequence of elements after the IOA of the first one (SQ=1).
+func (b *ASDUBuilder) SetSequence(sq bool) *ASDUBuilder {
+	b.asdu.sq = SQ(sq)
+	return b
+}
+
+// SetOriginatorAddress sets the originator address (ORG), it's 0 by default.
+func (b *ASDUBuilder) SetOriginatorAddress(org ORG) *ASDUBuilder {
+	b.asdu.org = org
+	return b
+}
+
+// SetCommonAddress sets the common address (COA) of the station sending the ASDU.
+func (b *ASDUBuilder) SetCommonAddress(coa COA) *ASDUBuilder {
+	b.asdu.coa = coa
+	return b
+}
+
+// AddObject adds the information object of the address with the value and the optional quality descriptor. The value
+// is the state of single and double points, the normalized value in [-1, 1) of NVA, the bits of BSI and the counter
+// reading of BCR. The time tag is the current time, AddElement sets the other fields, e.g. the time tag.
+func (b *ASDUBuilder) AddObject(ioa IOA, value float64, quality ...QualityDescriptor) *ASDUBuilder {
+	ie := &InformationElement{TypeID: b.asdu.typeID, Address: ioa, Value: value, Bitstring: uint32(int64(value))}
+	if len(quality) > 0 {
+		ie.Quality = quality[0]
+	}
+	return b.AddElement(ie)
+}
+
+// AddElement adds the information object of the information element, whose Address, Value, Quality, Ts and the other
+// fields decoded from the elements of the TypeID are encoded. The TypeID of the element must be the TypeID of the ASDU
+// if it's set, and the time tag is the current time if Ts is zero. The element isn't modified.
+func (b *ASDUBuilder) AddElement(ie *InformationElement) *ASDUBuilder {
+	if b.err != nil {
+		return b
+	}
+	if ie.TypeID != 0 && ie.TypeID != b.asdu.typeID {
+		b.err = fmt.Errorf("TypeID[%X] of IOA %d mismatches TypeID[%X] of the ASDU", uint8(ie.TypeID), ie.Address,
+			uint8(b.asdu.typeID))
+		return b
+	}
+	if ie.Address > MaxIOA {
+		b.err = fmt.Errorf("invalid IOA %d, the maximum is %d", ie.Address, MaxIOA)
+		return b
+	}
+
+	x := &InformationElement{}
+	*x = *ie
+	x.TypeID = b.asdu.typeID
+	x.data, x.offset, x.err = nil, 0, nil // the element parsed is encoded by its fields rather than the bytes parsed
+	if err := serializeElement(x); err != nil {
+		b.err = err
+		return b
+	}
+	b.ies = append(b.ies, x)
+	return b
+}
+
+// Build returns the ASDU built, it fails if there isn't any object, there are more than MaxNOO objects, the addresses
+// of the sequence (SQ=1) aren't continuous, or the ASDU doesn't fit in an APDU.
+func (b *ASDUBuilder) Build() (*ASDU, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	n := len(b.ies)
+	if n == 0 || n > int(MaxNOO) {
+		return nil, fmt.Errorf("invalid number of information objects: %d, expected 1-%d", n, MaxNOO)
+	}
+
+	asdu := *b.asdu
+	asdu.nObjs = NOO(n)
+	if asdu.sq {
+		first := b.ies[0].Address
+		for i, ie := range b.ies {
+			if uint64(ie.Address) != uint64(first)+uint64(i) {
+				return nil, fmt.Errorf("invalid sequence of TypeID[%X]: IOA %d of object %d, expected %d",
+					uint8(asdu.typeID), ie.Address, i, uint64(first)+uint64(i))
+			}
+		}
+		asdu.ios = []*InformationObject{{ioa: first, ies: b.ies}}
+	} else {
+		asdu.ios = make([]*InformationObject, 0, n)
+		for _, ie := range b.ies {
+			asdu.ios = append(asdu.ios, &InformationObject{ioa: ie.Address, ies: []*InformationElement{ie}})
+		}
+	}
+	asdu.Signals = b.ies
+
+	if size := len(asdu.Data()); size > MaxApduLen-ApduHeaderLen {
+		return nil, fmt.Errorf("ASDU of %d objects is %d bytes, which exceeds %d bytes of an APDU", n, size,
+			MaxApduLen-ApduHeaderLen)
+	}
+	return &asdu, nil
+}
+
+// serializeElement encodes the information elements of ie by the format table signalElements of its TypeID into Raw,
+// and sets Format.
+func serializeElement(ie *InformationElement) error {
+	elements, ok := signalElements[ie.TypeID]
+	if !ok {
+		return fmt.Errorf("unsupported type to serialize: TypeID[%X]", uint8(ie.TypeID))
+	}
+	raw := make([]byte, 0, elementLen[ie.TypeID])
+	for _, x := range elements {
+		raw = append(raw, ie.putElement(x)...)
+	}
+	ie.Format = append(InformationElementFormat(nil), elements...)
+	ie.Raw = raw
+	return nil
+}
+
+// putElement encodes the element of the type in signalElements, it's the inverse of getElement.
+func (ie *InformationElement) putElement(x InformationElementType) []byte {
+	switch x {
+	case SIQ:
+		return []byte{byte(ie.Quality)&0xf0 | byte(ie.Value)&0b1}
+	case DIQ:
+		return []byte{byte(ie.Quality)&0xf0 | byte(ie.Value)&0b11}
+	case VTI:
+		vti := byte(int8(ie.Value)) & 0x7f
+		if ie.TransientState {
+			vti |= 0x80
+		}
+		return []byte{vti}
+	case BSI:
+		return serializeLittleEndianUint32(ie.Bitstring)
+	case SCD:
+		return append(serializeLittleEndianUint16(ie.StatusChange.Status),
+			serializeLittleEndianUint16(ie.StatusChange.Changed)...)
+	case QDS:
+		return []byte{byte(ie.Quality)}
+	case NVA:
+		return serializeNVA(ie.Value)
+	case SVA:
+		return serializeSVA(int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(ie.Value)))))
+	case IEEE754STD:
+		return serializeIEEESTD754(float32(ie.Value))
+	case BCR:
+		descriptor := byte(ie.Quality)&0x80 | ie.CounterSequence&0x1f
+		if ie.CounterCarry {
+			descriptor |= 0x20
+		}
+		if ie.CounterAdjusted {
+			descriptor |= 0x40
+		}
+		return append(serializeLittleEndianUint32(uint32(int64(ie.Value))), descriptor)
+	case SEP:
+		return []byte{byte(ie.Quality)&0xf8 | byte(ie.Value)&0b11}
+	case SPE:
+		return []byte{ie.Protection & 0x3f}
+	case OCI:
+		return []byte{ie.Protection & 0x0f}
+	case QDP:
+		return []byte{byte(ie.Quality) & 0xf8}
+	case CP16Time2a:
+		return SerializeCP16Time2a(ie.Elapsed)
+	case CP24Time2a:
+		return ie.invalidateTime(serializeCP24Time2a(ie.timeTag(), ie.location()))
+	case CP56Time2a:
+		return ie.invalidateTime(serializeCP56Time2a(ie.timeTag(), ie.location()))
+	}
+	return nil
+}
+
+// invalidateTime sets IV of the time tag, which is in the minute byte of both CP24Time2a and CP56Time2a, if
+// TimeIsInvalid is true.
+func (ie *InformationElement) invalidateTime(data []byte) []byte {
+	if ie.TimeIsInvalid {
+		data[2] |= 0x80
+	}
+	return data
+}
+
+// timeTag returns the time to tag the element, which is the current time if Ts is zero.
+func (ie *InformationElement) timeTag() time.Time {
+	if ie.Ts.IsZero() {
+		return time.Now()
+	}
+	return ie.Ts
+}
+
+// serializeCP24Time2a serializes the minute, second and millisecond of the time in the location loc to the 3 bytes of
+// CP24Time2a, nil loc means time.Local.
+func serializeCP24Time2a(t time.Time, loc *time.Location) []byte {
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+	return append(serializeLittleEndianUint16(uint16(t.Second()*1000+t.Nanosecond()/int(time.Millisecond))),
+		byte(t.Minute()))
+}
@@ -0,0 +1,113 @@
+package iec104
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestASDUBuilder_Build(t *testing.T) {
+	ts := time.Date(2022, 8, 1, 10, 20, 30, 400*int(time.Millisecond), time.Local)
+	tests := []struct {
+		name    string
+		builder *ASDUBuilder
+		want    []byte
+	}{
+		{
+			"short floating points (SQ=0)",
+			NewASDU(MMeNc1, CotSpont).SetCommonAddress(1).
+				AddObject(0x4001, 1.5).
+				AddObject(0x4003, -2, IV),
+			[]byte{0x0d, 0x02, 0x03, 0x00, 0x01, 0x00,
+				0x01, 0x40, 0x00, 0x00, 0x00, 0xc0, 0x3f, 0x00,
+				0x03, 0x40, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x80},
+		},
+		{
+			"single points (SQ=1)",
+			NewASDU(MSpNa1, CotInrogen).SetSequence(true).SetCommonAddress(1).
+				AddObject(0x0001, 1).
+				AddObject(0x0002, 0, NT).
+				AddObject(0x0003, 1),
+			[]byte{0x01, 0x83, 0x14, 0x00, 0x01, 0x00,
+				0x01, 0x00, 0x00, 0x01, 0x40, 0x01},
+		},
+		{
+			"scaled value with time tag CP56Time2a",
+			NewASDU(MMeTe1, CotSpont).SetOriginatorAddress(2).SetCommonAddress(0x1234).
+				AddElement(&InformationElement{Address: 0x0102, Value: -100, Ts: ts}),
+			append([]byte{0x23, 0x01, 0x03, 0x02, 0x34, 0x12,
+				0x02, 0x01, 0x00, 0x9c, 0xff, 0x00}, SerializeCP56Time2a(ts)...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asdu, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got := asdu.Data(); !bytes.Equal(got, tt.want) {
+				t.Errorf("Data() = [% X], want [% X]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestASDUBuilder_RoundTrip(t *testing.T) {
+	ts := time.Date(2022, 8, 1, 10, 20, 30, 0, time.Local)
+	for _, sq := range []bool{false, true} {
+		b := NewASDU(MStTb1, CotSpont).SetSequence(sq).SetCommonAddress(1)
+		for i := 0; i < 5; i++ {
+			b.AddElement(&InformationElement{Address: IOA(0x10 + i), Value: float64(i - 2), TransientState: i%2 == 0,
+				Quality: SB, Ts: ts})
+		}
+		built, err := b.Build()
+		if err != nil {
+			t.Fatalf("Build() with SQ=%v error = %v", sq, err)
+		}
+
+		parsed := &ASDU{}
+		if err := parsed.Parse(built.Data()); err != nil {
+			t.Fatalf("Parse() with SQ=%v error = %v", sq, err)
+		}
+		if len(parsed.Signals) != 5 {
+			t.Fatalf("Parse() with SQ=%v got %d signals, want 5", sq, len(parsed.Signals))
+		}
+		for i, signal := range parsed.Signals {
+			want := built.Signals[i]
+			if signal.Address != want.Address || signal.Value != want.Value || signal.Quality != want.Quality ||
+				signal.TransientState != want.TransientState || !signal.Ts.Equal(ts) {
+				t.Errorf("signal #%d with SQ=%v = %+v, want %+v", i, sq, signal, want)
+			}
+		}
+	}
+}
+
+func TestASDUBuilder_Invalid(t *testing.T) {
+	full := NewASDU(MSpNa1, CotSpont)
+	for i := 0; i <= int(MaxNOO); i++ {
+		full.AddObject(IOA(i+1), 1)
+	}
+	large := NewASDU(MMeTf1, CotSpont)
+	for i := 0; i < 20; i++ {
+		large.AddObject(IOA(i+1), 1)
+	}
+	tests := []struct {
+		name    string
+		builder *ASDUBuilder
+	}{
+		{"unsupported type", NewASDU(CScNa1, CotAct).AddObject(1, 1)},
+		{"no object", NewASDU(MSpNa1, CotSpont)},
+		{"mismatched type", NewASDU(MSpNa1, CotSpont).AddElement(&InformationElement{TypeID: MDpNa1, Address: 1})},
+		{"IOA overflow", NewASDU(MSpNa1, CotSpont).AddObject(MaxIOA+1, 1)},
+		{"discontinuous sequence", NewASDU(MSpNa1, CotSpont).SetSequence(true).AddObject(1, 1).AddObject(3, 1)},
+		{"too many objects", full},
+		{"exceeding an APDU", large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.builder.Build(); err == nil {
+				t.Errorf("Build() error = nil, want error")
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package iec104
 
 import (
+	"fmt"
 	"math"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,20 +19,122 @@ type InformationElement struct {
 	Raw     []byte            `json:"raw"`
 	Quality QualityDescriptor `json:"quality"` // if the value's quality is not zero, it means the value is not valid!
 	Ts      time.Time         `json:"ts"`
+	Group   uint8             `json:"group"` // interrogation group (1-16) of the response, 0 if not a group response
 
+	// RawValue is the integer transmitted in the normalized value (NVA) or the scaled value (SVA), from which Value is
+	// computed by the scaling of the address, see Client.SetScaling.
+	RawValue int64 `json:"raw_value"`
+
+	// Bitstring is decoded from the binary state information (BSI), the raw bits are kept in Raw as well.
+	Bitstring uint32 `json:"bitstring"`
+
+	// Protection is decoded from the start events (SPE) or the output circuit information (OCI) of protection
+	// equipment, and Elapsed is decoded from CP16Time2a, which is the elapsed time of the event, the relay duration
+	// of the start events or the relay operation time of the output circuit.
+	Protection uint8         `json:"protection"`
+	Elapsed    time.Duration `json:"elapsed"`
+	// ProtectionQuality is decoded from the quality descriptor for events of protection equipment (QDP, SEP), the
+	// bits are kept in Quality as well.
+	ProtectionQuality ProtectionQuality `json:"protection_quality"`
+
+	// StatusChange is decoded from the status and change detection (SCD).
+	StatusChange StatusChangeDetection `json:"status_change"`
+
+	// TransientState is decoded from the step position (VTI), it's true if the equipment is in transient state.
+	TransientState bool `json:"transient_state"`
+
+	// CounterRequest and FreezeMode are decoded from the qualifier of counter interrogation command (QCC).
+	CounterRequest CounterRequest `json:"counter_request"`
+	FreezeMode     FreezeMode     `json:"freeze_mode"`
+
+	// TimeIsInvalid is decoded from IV of the time tag (CP24Time2a, CP56Time2a), it's true if the clock of the station
+	// isn't synchronized. SummerTime and Weekday are decoded from SU and the day of week (1-7 means Monday-Sunday, 0
+	// means not used) of CP56Time2a, SU resolves the wall clock repeated when the summer time ends.
+	TimeIsInvalid bool  `json:"time_is_invalid"`
+	SummerTime    bool  `json:"summer_time"`
+	Weekday       uint8 `json:"weekday"`
+
+	// CounterSequence, CounterCarry and CounterAdjusted are decoded from the descriptor of the binary counter reading
+	// (BCR), which are the sequence number (0-31), whether the counter overflowed in the period (CY) and whether it
+	// was adjusted (CA). IV of the descriptor is kept in Quality.
+	CounterSequence uint8 `json:"counter_sequence"`
+	CounterCarry    bool  `json:"counter_carry"`
+	CounterAdjusted bool  `json:"counter_adjusted"`
+
+	// SelectExecute, Qualifier and State are decoded from the command (SCO, DCO, RCO), SelectExecute is true for select.
+	SelectExecute bool  `json:"select_execute"`
+	Qualifier     uint8 `json:"qualifier"`
+	State         uint8 `json:"state"`
+
+	// Format is the types of the elements decoded in order, the time tag (CP24Time2a or CP56Time2a) included, e.g.
+	// SIQ and CP24Time2a for MSpTa1, so it describes the whole encoding of the information element.
 	Format InformationElementFormat
 
-	data   []byte
-	offset int
+	data     []byte
+	offset   int
+	loc      *time.Location // location of the time tags, time.Local if it's nil
+	yearBase int            // first year of the window of the 2-digit year of CP56Time2a, 0 means DefaultCP56YearBase
+	err      error          // the first error of getting the elements
 }
 
 func (ie *InformationElement) IsValid() bool {
 	return ie.Quality == 0
 }
 
+// encoded returns the bytes of the elements, which are the bytes decoded from if the element is parsed, so that the
+// parsed ASDU is serialized to the identical bytes, otherwise Raw built to send.
+func (ie *InformationElement) encoded() []byte {
+	if ie.data != nil {
+		return ie.data
+	}
+	return ie.Raw
+}
+
+// remain reports whether there are n bytes remaining to get the next element. Otherwise, the error is recorded and
+// the element is left zero, so that the truncated information element fails to parse rather than panics.
+func (ie *InformationElement) remain(n int) bool {
+	if ie.err != nil {
+		return false
+	}
+	if len(ie.data)-ie.offset < n {
+		ie.err = fmt.Errorf("invalid information element: %d bytes expected at offset %d, got [% X]", n, ie.offset, ie.data)
+		return false
+	}
+	return true
+}
+
+// location returns the location to decode the time tags in.
+func (ie *InformationElement) location() *time.Location {
+	if ie.loc == nil {
+		return time.Local
+	}
+	return ie.loc
+}
+
+// cp56YearBase returns the first year of the window which the 2-digit year of CP56Time2a is in.
+func (ie *InformationElement) cp56YearBase() int {
+	if ie.yearBase == 0 {
+		return DefaultCP56YearBase
+	}
+	return ie.yearBase
+}
+
+// hasFormat reports whether the information element has the element of the type.
+func (ie *InformationElement) hasFormat(x InformationElementType) bool {
+	for _, f := range ie.Format {
+		if f == x {
+			return true
+		}
+	}
+	return false
+}
+
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1278
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2413
 func (ie *InformationElement) getSIQ() {
+	if !ie.remain(1) {
+		return
+	}
 	ie.Format = append(ie.Format, SIQ)
 	ie.Quality = QualityDescriptor(ie.data[ie.offset] & 0xf0)
 	ie.Value = float64(parseLittleEndianUint16([]byte{ie.data[ie.offset] & 0b1, 0x00})) // 0b1 represents open; 0b0 represents close.
@@ -40,6 +145,9 @@ func (ie *InformationElement) getSIQ() {
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1298
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2437
 func (ie *InformationElement) getDIQ() {
+	if !ie.remain(1) {
+		return
+	}
 	ie.Format = append(ie.Format, DIQ)
 	ie.Quality = QualityDescriptor(ie.data[ie.offset] & 0xf0)
 	ie.Value = float64(parseLittleEndianUint16([]byte{ie.data[ie.offset] & 0b11, 0x00})) // 0b01 represents close; 0b10 represents open.
@@ -47,11 +155,152 @@ func (ie *InformationElement) getDIQ() {
 	ie.offset++
 }
 
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1338
+func (ie *InformationElement) getVTI() {
+	if !ie.remain(1) {
+		return
+	}
+	ie.Format = append(ie.Format, VTI)
+	ie.TransientState = ie.data[ie.offset]&0x80 != 0
+	ie.Value = float64(int8(ie.data[ie.offset]<<1) >> 1) // sign-extend the 7-bit value
+
+	ie.offset++
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1412
+func (ie *InformationElement) getBSI() {
+	if !ie.remain(4) {
+		return
+	}
+	ie.Format = append(ie.Format, BSI)
+	ie.Raw = append([]byte(nil), ie.data[ie.offset:ie.offset+4]...)
+	ie.Bitstring = parseLittleEndianUint32(ie.Raw)
+
+	ie.offset += 4
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1433
+func (ie *InformationElement) getSCD() {
+	if !ie.remain(4) {
+		return
+	}
+	ie.Format = append(ie.Format, SCD)
+	ie.StatusChange = StatusChangeDetection{
+		Status:  parseLittleEndianUint16(ie.data[ie.offset : ie.offset+2]),
+		Changed: parseLittleEndianUint16(ie.data[ie.offset+2 : ie.offset+4]),
+	}
+
+	ie.offset += 4
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1484
+func (ie *InformationElement) getSEP() {
+	if !ie.remain(1) {
+		return
+	}
+	ie.Format = append(ie.Format, SEP)
+	ie.Quality = QualityDescriptor(ie.data[ie.offset] & 0xf8)
+	ie.ProtectionQuality = parseProtectionQuality(ie.data[ie.offset])
+	ie.Value = float64(ie.data[ie.offset] & 0b11) // event state: 0b01 represents off; 0b10 represents on.
+
+	ie.offset++
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1510
+func (ie *InformationElement) getSPE() {
+	if !ie.remain(1) {
+		return
+	}
+	ie.Format = append(ie.Format, SPE)
+	ie.Protection = ie.data[ie.offset] & 0x3f
+
+	ie.offset++
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1536
+func (ie *InformationElement) getOCI() {
+	if !ie.remain(1) {
+		return
+	}
+	ie.Format = append(ie.Format, OCI)
+	ie.Protection = ie.data[ie.offset] & 0x0f
+
+	ie.offset++
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1558
+func (ie *InformationElement) getQDP() {
+	if !ie.remain(1) {
+		return
+	}
+	ie.Format = append(ie.Format, QDP)
+	ie.Quality = QualityDescriptor(ie.data[ie.offset] & 0xf8)
+	ie.ProtectionQuality = parseProtectionQuality(ie.data[ie.offset])
+
+	ie.offset++
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1185
+func (ie *InformationElement) getCP16Time2a() {
+	if !ie.remain(2) {
+		return
+	}
+	ie.Format = append(ie.Format, CP16Time2a)
+	ie.Elapsed = time.Duration(parseLittleEndianUint16(ie.data[ie.offset:ie.offset+2])) * time.Millisecond
+
+	ie.offset += 2
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1733
+func (ie *InformationElement) getQOI() {
+	if !ie.remain(1) {
+		return
+	}
+	ie.Format = append(ie.Format, QOI)
+	ie.Value = float64(ie.data[ie.offset])
+
+	ie.offset++
+}
+
+// https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1749
+func (ie *InformationElement) getQCC() {
+	if !ie.remain(1) {
+		return
+	}
+	ie.Format = append(ie.Format, QCC)
+	ie.Value = float64(ie.data[ie.offset])
+	// | FRZ | RQT |, FRZ is bit 7-8 and RQT is bit 1-6.
+	ie.CounterRequest = CounterRequest(ie.data[ie.offset] & 0x3f)
+	ie.FreezeMode = FreezeMode(ie.data[ie.offset] >> 6)
+
+	ie.offset++
+}
+
+func (ie *InformationElement) getQRP() {
+	if !ie.remain(1) {
+		return
+	}
+	ie.Format = append(ie.Format, QRP)
+	ie.Value = float64(ie.data[ie.offset])
+
+	ie.offset++
+}
+
+// Transient reports whether the step position (VTI) is in transient state, e.g. the tap-changer is in motion.
+// The position value is not settled yet and applications should not act on it.
+func (ie *InformationElement) Transient() bool {
+	return ie.TransientState
+}
+
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1367
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2637
 func (ie *InformationElement) getNVA() {
+	if !ie.remain(2) {
+		return
+	}
 	ie.Format = append(ie.Format, NVA)
-	ie.Value = float64(parseLittleEndianInt16(ie.data[ie.offset:ie.offset+2])) / 32768
+	ie.RawValue = int64(parseLittleEndianInt16(ie.data[ie.offset : ie.offset+2]))
+	ie.Value = float64(ie.RawValue) / 32768
 
 	ie.offset += 2
 }
@@ -59,8 +308,12 @@ func (ie *InformationElement) getNVA() {
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1398
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2641
 func (ie *InformationElement) getSVA() {
+	if !ie.remain(2) {
+		return
+	}
 	ie.Format = append(ie.Format, SVA)
-	ie.Value = float64(parseLittleEndianInt16(ie.data[ie.offset : ie.offset+2]))
+	ie.RawValue = int64(parseLittleEndianInt16(ie.data[ie.offset : ie.offset+2]))
+	ie.Value = float64(ie.RawValue)
 
 	ie.offset += 2
 }
@@ -68,14 +321,48 @@ func (ie *InformationElement) getSVA() {
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1417
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2633
 func (ie *InformationElement) getIEEESTD754() {
+	if !ie.remain(4) {
+		return
+	}
 	ie.Format = append(ie.Format, IEEE754STD)
 	ie.Value = float64(math.Float32frombits(parseLittleEndianUint32(ie.data[ie.offset : ie.offset+4])))
 	ie.offset += 4
 }
 
+// serializeNVA serializes the normalized value in [-1, 1), the value out of the range is clamped.
+func serializeNVA(value float64) []byte {
+	x := math.Round(value * 32768)
+	x = math.Max(math.MinInt16, math.Min(math.MaxInt16, x))
+	return serializeLittleEndianUint16(uint16(int16(x)))
+}
+
+func serializeSVA(value int16) []byte {
+	return serializeLittleEndianUint16(uint16(value))
+}
+
+func serializeIEEESTD754(value float32) []byte {
+	return serializeLittleEndianUint32(math.Float32bits(value))
+}
+
+// FixedTestBitPattern is the FBP carried by test command.
+const FixedTestBitPattern uint16 = 0x55AA
+
+func (ie *InformationElement) getFBP() {
+	if !ie.remain(2) {
+		return
+	}
+	ie.Format = append(ie.Format, FBP)
+	ie.Value = float64(parseLittleEndianUint16(ie.data[ie.offset : ie.offset+2]))
+
+	ie.offset += 2
+}
+
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1479
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2497
 func (ie *InformationElement) getQOS() {
+	if !ie.remain(1) {
+		return
+	}
 	ie.Format = append(ie.Format, QOS)
 
 	ie.offset += 1
@@ -84,8 +371,15 @@ func (ie *InformationElement) getQOS() {
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1496
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2509
 func (ie *InformationElement) getSCO() {
+	if !ie.remain(1) {
+		return
+	}
 	ie.Format = append(ie.Format, SCO)
 	ie.Value = float64(parseLittleEndianUint16([]byte{ie.data[ie.offset], 0x00}))
+	// | S/E | QU | RES | SCS |, SCS: 0b0 represents open; 0b1 represents close.
+	ie.SelectExecute = ie.data[ie.offset]&0x80 != 0
+	ie.Qualifier = (ie.data[ie.offset] >> 2) & 0x1f
+	ie.State = ie.data[ie.offset] & 0b1
 
 	ie.offset += 1
 }
@@ -93,8 +387,15 @@ func (ie *InformationElement) getSCO() {
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1514
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2525
 func (ie *InformationElement) getDCO() {
+	if !ie.remain(1) {
+		return
+	}
 	ie.Format = append(ie.Format, DCO)
 	ie.Value = float64(parseLittleEndianUint16([]byte{ie.data[ie.offset], 0x00}))
+	// | S/E | QU | DCS |, DCS: 0b01 represents open; 0b10 represents close; 0b00 and 0b11 are not permitted.
+	ie.SelectExecute = ie.data[ie.offset]&0x80 != 0
+	ie.Qualifier = (ie.data[ie.offset] >> 2) & 0x1f
+	ie.State = ie.data[ie.offset] & 0b11
 
 	ie.offset += 1
 }
@@ -102,8 +403,16 @@ func (ie *InformationElement) getDCO() {
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1532
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2541
 func (ie *InformationElement) getRCO() {
+	if !ie.remain(1) {
+		return
+	}
 	ie.Format = append(ie.Format, RCO)
 	ie.Value = float64(parseLittleEndianUint16([]byte{ie.data[ie.offset], 0x00}))
+	// | S/E | QU | RCS |, RCS: 0b01 represents next step lower; 0b10 represents next step higher; 0b00 and 0b11 are
+	// not permitted.
+	ie.SelectExecute = ie.data[ie.offset]&0x80 != 0
+	ie.Qualifier = (ie.data[ie.offset] >> 2) & 0x1f
+	ie.State = ie.data[ie.offset] & 0b11
 
 	ie.offset += 1
 }
@@ -111,6 +420,9 @@ func (ie *InformationElement) getRCO() {
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1318
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2461
 func (ie *InformationElement) getQDS() {
+	if !ie.remain(1) {
+		return
+	}
 	ie.Format = append(ie.Format, QDS)
 	ie.Quality = QualityDescriptor(ie.data[ie.offset] & 0xff)
 
@@ -119,380 +431,710 @@ func (ie *InformationElement) getQDS() {
 
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1453
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2605
+//
+// BCR (binary counter reading) is the 32-bit counter reading followed by the descriptor:
+//
+//	| <-              8 bits              -> |
+//	| IV  | CA  | CY  | Sequence Number (SQ) |
+//
+// SQ is the sequence number (0-31) of the reading, CY is set if the counter overflowed in the integration period, CA
+// is set if the counter was adjusted, and IV is set if the reading is invalid.
 func (ie *InformationElement) getBCR() {
+	if !ie.remain(5) {
+		return
+	}
 	ie.Format = append(ie.Format, BCR)
-	ie.Value = float64(parseLittleEndianUint32(ie.data[ie.offset : ie.offset+4])) // data[4] is the description information.
+	ie.Value = float64(parseLittleEndianUint32(ie.data[ie.offset : ie.offset+4]))
+
+	descriptor := ie.data[ie.offset+4]
+	ie.CounterSequence = descriptor & 0x1f
+	ie.CounterCarry = descriptor&0x20 != 0
+	ie.CounterAdjusted = descriptor&0x40 != 0
+	ie.Quality = QualityDescriptor(descriptor & 0x80)
 
 	ie.offset += 5
 }
 
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1084
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L2353
-func (ie *InformationElement) getCP24Time2a() {
-	millisecond := parseLittleEndianUint16(ie.data[ie.offset : ie.offset+2])
+//
+// CP24Time2a only carries minute, second and millisecond, so the year, month, day and hour are taken from the
+// reference time ref (the time when the ASDU is received by default). The hour nearest to ref is chosen to handle
+// the rollover around the hour boundary, e.g. 59:30 referred at 10:00:05 is 09:59:30.
+func (ie *InformationElement) getCP24Time2a(ref time.Time) {
+	if !ie.remain(3) {
+		return
+	}
+	ie.Format = append(ie.Format, CP24Time2a)
+	ie.Ts, ie.TimeIsInvalid = decodeCP24Time2a(ie.data[ie.offset:ie.offset+3], ref, ie.location())
+	ie.offset += 3
+}
+
+/*
+decodeCP24Time2a decodes the 3 bytes of CP24Time2a in the location loc, iv reports whether the time is invalid.
+
+  | <-                 8 bits                 -> |
+  | Milliseconds                          [LSB]  |
+  | Milliseconds                          [MSB]  |
+  | IV  | RES |            Minutes               |
+*/
+func decodeCP24Time2a(data []byte, ref time.Time, loc *time.Location) (ts time.Time, iv bool) {
+	millisecond := parseLittleEndianUint16(data[0:2])
 	nanosecond := (int(millisecond) % 1000) * int(time.Millisecond)
 	second := int(millisecond / 1000)
-	minute := int(ie.data[ie.offset+2] & 0x3f)
+	minute := int(data[2] & 0x3f)
+	iv = data[2]&0x80 == 0x80
 
-	// FIXME How to set year, month, day and hour for CP24Time2a?
-	ie.Ts = time.Date(0, time.January, 1, 0, minute, second, nanosecond, time.Local)
-	ie.offset += 3
+	ref = ref.In(loc)
+	ts = time.Date(ref.Year(), ref.Month(), ref.Day(), ref.Hour(), minute, second, nanosecond, loc)
+	if d := ts.Sub(ref); d > 30*time.Minute {
+		ts = ts.Add(-time.Hour)
+	} else if d < -30*time.Minute {
+		ts = ts.Add(time.Hour)
+	}
+	return ts, iv
+}
+
+// DefaultCP56YearBase is the default first year of the 100-year window which the 2-digit year of CP56Time2a is in,
+// that's to say, years 70-99 are 1970-1999 and years 0-69 are 2000-2069.
+const DefaultCP56YearBase = 1970
+
+// cp56Year converts the 2-digit year of CP56Time2a to the year in the window starting from base.
+func cp56Year(x byte, base int) int {
+	return base + (int(x)%100-base%100+100)%100
 }
 
 // https://github.com/wireshark/wireshark/blob/master/epan/dissectors/packet-iec104.c#L1161
 func (ie *InformationElement) getCP56Time2a() {
-	millisecond := parseLittleEndianUint16(ie.data[ie.offset : ie.offset+2])
+	if !ie.remain(7) {
+		return
+	}
+	ie.Format = append(ie.Format, CP56Time2a)
+	var weekday int
+	ie.Ts, ie.TimeIsInvalid, ie.SummerTime, weekday = decodeCP56Time2a(ie.data[ie.offset:ie.offset+7], ie.location(),
+		ie.cp56YearBase())
+	ie.Weekday = uint8(weekday)
+	ie.offset += 7
+}
+
+/*
+decodeCP56Time2a decodes the 7 bytes of CP56Time2a in the location loc, the 2-digit year is in the 100-year window
+starting from yearBase. iv reports whether the time is invalid, su reports whether it's summer time, and weekday is
+the day of week (1-7 means Monday-Sunday, 0 means not used).
+
+  | <-                 8 bits                 -> |
+  | Milliseconds                          [LSB]  |
+  | Milliseconds                          [MSB]  |
+  | IV  | RES |            Minutes               |
+  | SU  |   RES     |          Hours             |
+  | Day of week     |      Day of month          |
+  |        RES            |        Months        |
+  | RES |                 Years                  |
+*/
+func decodeCP56Time2a(data []byte, loc *time.Location, yearBase int) (ts time.Time, iv, su bool, weekday int) {
+	millisecond := parseLittleEndianUint16(data[0:2])
 	nanosecond := (int(millisecond) % 1000) * int(time.Millisecond)
 	second := int(millisecond / 1000)
-	minute := int(ie.data[ie.offset+2] & 0x3f)
-	hour := int(ie.data[ie.offset+3] & 0x1f)
-	day := int(ie.data[ie.offset+4] & 0x1f)
-	month := int(ie.data[ie.offset+5] & 0x0f)
-	year := int(ie.data[ie.offset+6]&0x7f) + 2000
-	if year < 70 {
-		year += 100
+	minute := int(data[2] & 0x3f)
+	hour := int(data[3] & 0x1f)
+	day := int(data[4] & 0x1f)
+	month := int(data[5] & 0x0f)
+	year := cp56Year(data[6]&0x7f, yearBase)
+
+	iv = data[2]&0x80 == 0x80
+	su = data[3]&0x80 == 0x80
+	weekday = int(data[4] >> 5)
+
+	ts = time.Date(year, time.Month(month), day, hour, minute, second, nanosecond, loc)
+	return applySummerTime(ts, su), iv, su, weekday
+}
+
+// applySummerTime chooses the time by SU when the wall clock is repeated at the end of the summer time, e.g. 02:30
+// happens twice in Europe/Berlin on the last Sunday of October, the first one with SU=1 is the summer time.
+func applySummerTime(ts time.Time, su bool) time.Time {
+	if ts.IsDST() == su {
+		return ts
+	}
+	alt := ts.Add(time.Hour)
+	if su {
+		alt = ts.Add(-time.Hour)
 	}
+	if alt.IsDST() == su && alt.Hour() == ts.Hour() && alt.Minute() == ts.Minute() {
+		return alt
+	}
+	return ts
+}
 
-	ie.Ts = time.Date(year, time.Month(month), day, hour, minute, second, nanosecond, time.Local)
-	ie.offset += 7
+var (
+	// scsNames are the names of SCS (single command state).
+	scsNames = [4]string{"open [分闸]", "close [合闸]", "", ""}
+	// dcsNames are the names of DCS (double command state), the empty name is not permitted.
+	dcsNames = [4]string{"", "open [分闸]", "close [合闸]", ""}
+	// rcsNames are the names of RCS (regulating step command state), the empty name is not permitted.
+	rcsNames = [4]string{"", "lower [降一步]", "higher [升一步]", ""}
+)
+
+// commandRsp decodes the confirmation of single, double or regulating step command into the phase and the state (SCS,
+// DCS or RCS) returned to the command sender.
+func (asdu *ASDU) commandRsp(ie *InformationElement, command string, states [4]string, term error) *cmdRsp {
+	var phase CommandPhase
+	switch {
+	case asdu.cot == CotActCon && ie.SelectExecute:
+		phase = CommandPhaseSelect
+	case asdu.cot == CotActCon:
+		phase = CommandPhaseExecute
+	case asdu.cot == CotDeactCon:
+		phase = CommandPhaseCancel
+	case asdu.cot == CotActTerm:
+		asdu.logger().Debugf("receive i frame: termination of %s", command)
+		return &cmdRsp{err: term, phase: CommandPhaseTerm, state: ie.State}
+	default:
+		asdu.logger().Debugf("receive i frame: %s with COT %d", command, asdu.cot)
+		return nil
+	}
+
+	state := states[ie.State&0b11]
+	if state == "" {
+		asdu.logger().Warnf("receive i frame: %s confirmation of %s with state %d not permitted", phase, command, ie.State)
+		if phase == CommandPhaseCancel {
+			return nil
+		}
+		return &cmdRsp{err: errUnexpectedCmd{phase: phase, state: ie.State}, phase: phase, state: ie.State}
+	}
+	asdu.logger().Debugf("receive i frame: %s confirmation of %s (QU %d) - %s", phase, command, ie.Qualifier, state)
+	if phase == CommandPhaseCancel {
+		return nil // no command sender waits for the cancellation
+	}
+	return &cmdRsp{phase: phase, state: ie.State}
 }
 
-func (asdu *ASDU) parseInformationElement(data []byte, ie *InformationElement) {
-	ie.data = data
+// SerializeCP16Time2a serializes the duration to the 2 bytes of CP16Time2a in milliseconds, it's the inverse of
+// decoding CP16Time2a. The duration is truncated to milliseconds and clamped to [0, 65535] ms.
+func SerializeCP16Time2a(d time.Duration) []byte {
+	ms := d.Milliseconds()
+	if ms < 0 {
+		ms = 0
+	} else if ms > math.MaxUint16 {
+		ms = math.MaxUint16
+	}
+	return serializeLittleEndianUint16(uint16(ms))
+}
 
-	switch asdu.typeID {
-	case MSpNa1:
-		ie.getSIQ()
-		switch asdu.cot {
-		case CotPerCyc:
-			_lg.Debugf("receive i frame: single point information of periodically/cyclically syncing at %d is %f "+
-				"with Quality[IV: %v, NT: %v, SB: %v, BL: %v] [全遥信 - 带品质描述/不带时标单点遥信]", ie.Address,
-				ie.Value, (ie.Quality&IV) == IV, (ie.Quality&NT) == NT, (ie.Quality&SB) == SB, (ie.Quality&BL) == BL)
-			asdu.sendSFrame = true
-		case CotSpont:
-			_lg.Debugf("receive i frame: single point information of spontenuous change at %d is %f "+
-				"with Quality[IV: %v, NT: %v, SB: %v, BL: %v] [变化遥信 - 带品质描述/不带时标单点遥信]", ie.Address,
-				ie.Value, (ie.Quality&IV) == IV, (ie.Quality&NT) == NT, (ie.Quality&SB) == SB, (ie.Quality&BL) == BL)
-			asdu.sendSFrame = true
-		case CotInrogen:
-			_lg.Debugf("receive i frame: single point information response of general interrogation at %d is %f "+
-				"with Quality[IV: %v, NT: %v, SB: %v, BL: %v] [总召唤响应 - 带品质描述/不带时标单点遥信]", ie.Address,
-				ie.Value, (ie.Quality&IV) == IV, (ie.Quality&NT) == NT, (ie.Quality&SB) == SB, (ie.Quality&BL) == BL)
+// SerializeCP56Time2a serializes the time in local time zone to the 7 bytes of CP56Time2a, it's the inverse of
+// decoding CP56Time2a. The SU bit is set in summer time, and the day of week is always filled.
+func SerializeCP56Time2a(t time.Time) []byte {
+	return serializeCP56Time2a(t, time.Local)
+}
+
+// serializeCP56Time2a serializes the time in the location loc to the 7 bytes of CP56Time2a, nil loc means time.Local.
+func serializeCP56Time2a(t time.Time, loc *time.Location) []byte {
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+
+	data := make([]byte, 7)
+	copy(data[0:2], serializeLittleEndianUint16(uint16(t.Second()*1000+t.Nanosecond()/int(time.Millisecond))))
+	data[2] = byte(t.Minute())
+	data[3] = byte(t.Hour())
+	if t.IsDST() {
+		data[3] |= 0x80
+	}
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7 // Sunday
+	}
+	data[4] = byte(weekday<<5) | byte(t.Day())
+	data[5] = byte(t.Month())
+	data[6] = byte(t.Year() % 100)
+	return data
+}
+
+// TypeDecoder decodes the information elements of one information object (IOA excluded) from data into ie, whose
+// TypeID, Address and Group are set already. It's expected to set Format by the elements decoded.
+type TypeDecoder func(ie *InformationElement, data []byte) error
+
+var (
+	typeDecodersMu sync.RWMutex
+	typeDecoders   = make(map[TypeID]TypeDecoder)
+)
+
+// RegisterTypeDecoder registers the decoder of the TypeID, which takes precedence over the decoding of the package,
+// and the nil decoder unregisters it. It's intended for the TypeIDs of special use and the vendor deviations of the
+// nonconformant peers, e.g. the fields transmitted big-endian. The data decoded by it is handled by the handler unless
+// the TypeID is in control direction (45-127).
+func RegisterTypeDecoder(typeID TypeID, decoder TypeDecoder) {
+	typeDecodersMu.Lock()
+	defer typeDecodersMu.Unlock()
+
+	if decoder == nil {
+		delete(typeDecoders, typeID)
+		return
+	}
+	typeDecoders[typeID] = decoder
+}
+
+func typeDecoder(typeID TypeID) (TypeDecoder, bool) {
+	typeDecodersMu.RLock()
+	defer typeDecodersMu.RUnlock()
+
+	decoder, ok := typeDecoders[typeID]
+	return decoder, ok
+}
+
+// DecodeElement decodes the information element of the TypeID from data, which doesn't contain the IOA. The optional
+// ref is the reference time to complete CP24Time2a, it's the current time by default. It fails with
+// ErrElementLength if the length of data doesn't match the TypeID.
+func DecodeElement(typeID TypeID, data []byte, ref ...time.Time) (*InformationElement, error) {
+	if _, registered := typeDecoder(typeID); !registered {
+		if want, ok := elementLen[typeID]; ok && len(data) != want {
+			return nil, errElementLength{typeID: typeID, length: len(data), want: want}
 		}
-		asdu.toBeHandled = true
-	case MSpTa1:
+	}
+	asdu := &ASDU{typeID: typeID, ref: time.Now()}
+	if len(ref) > 0 {
+		asdu.ref = ref[0]
+	}
+
+	ie := &InformationElement{TypeID: typeID}
+	if err := asdu.parseInformationElement(data, ie); err != nil {
+		return nil, fmt.Errorf("TypeID[%X]: %w", uint8(typeID), err)
+	}
+	if len(ie.Format) == 0 {
+		return nil, fmt.Errorf("unsupported type: TypeID[%X]", uint8(typeID))
+	}
+	// the length of the types decoded by the registered decoders is known after decoding
+	if ie.offset != len(data) {
+		return nil, errElementLength{typeID: typeID, length: len(data), want: ie.offset}
+	}
+	return ie, nil
+}
+
+// signalElements is the format table of the information elements of the signals in monitor direction by TypeID, the
+// elements are decoded in order.
+var signalElements = map[TypeID][]InformationElementType{
+	MSpNa1: {SIQ},
+	MSpTa1: {SIQ, CP24Time2a},
+	MDpNa1: {DIQ},
+	MDpTa1: {DIQ, CP24Time2a},
+	MStNa1: {VTI, QDS},
+	MStTa1: {VTI, QDS, CP24Time2a},
+	MBoNa1: {BSI, QDS},
+	MBoTa1: {BSI, QDS, CP24Time2a},
+	MMeNa1: {NVA, QDS},
+	MMeTa1: {NVA, QDS, CP24Time2a},
+	MMeNb1: {SVA, QDS},
+	MMeTb1: {SVA, QDS, CP24Time2a},
+	MMeNc1: {IEEE754STD, QDS},
+	MMeTc1: {IEEE754STD, QDS, CP24Time2a},
+	MItNa1: {BCR},
+	MItTa1: {BCR, CP24Time2a},
+	MEpTa1: {SEP, CP16Time2a, CP24Time2a},
+	MEpTb1: {SPE, QDP, CP16Time2a, CP24Time2a},
+	MEpTc1: {OCI, QDP, CP16Time2a, CP24Time2a},
+	MPsNa1: {SCD, QDS},
+	MMeNd1: {NVA},
+	MSpTb1: {SIQ, CP56Time2a},
+	MDpTb1: {DIQ, CP56Time2a},
+	MStTb1: {VTI, QDS, CP56Time2a},
+	MBoTb1: {BSI, QDS, CP56Time2a},
+	MMeTd1: {NVA, QDS, CP56Time2a},
+	MMeTe1: {SVA, QDS, CP56Time2a},
+	MMeTf1: {IEEE754STD, QDS, CP56Time2a},
+	MItTb1: {BCR, CP56Time2a},
+	MEpTd1: {SEP, CP16Time2a, CP56Time2a},
+	MEpTe1: {SPE, QDP, CP16Time2a, CP56Time2a},
+	MEpTf1: {OCI, QDP, CP16Time2a, CP56Time2a},
+}
+
+// signalNames describes the signals of TypeIDs in signalElements for logging, in English and the Chinese gloss.
+var signalNames = map[TypeID]struct{ text, gloss string }{
+	MSpNa1: {"single point information", "单点遥信"},
+	MSpTa1: {"single point information with time tag CP24Time2a", "带 24 位时标的单点遥信"},
+	MDpNa1: {"double point information", "双点遥信"},
+	MDpTa1: {"double point information with time tag CP24Time2a", "带 24 位时标的双点遥信"},
+	MStNa1: {"step position information", "步位置信息"},
+	MStTa1: {"step position information with time tag CP24Time2a", "带 24 位时标步位置信息"},
+	MBoNa1: {"bitstring of 32 bits", "32 比特串"},
+	MBoTa1: {"bitstring of 32 bits with time tag CP24Time2a", "带 24 位时标 32 比特串"},
+	MMeNa1: {"normalized value", "归一化值遥测"},
+	MMeTa1: {"normalized value with time tag CP24Time2a", "带 24 位时标归一化值遥测"},
+	MMeNb1: {"scaled value", "标度化值遥测"},
+	MMeTb1: {"scaled value with time tag CP24Time2a", "带 24 位时标标度化值遥测"},
+	MMeNc1: {"short floating point value", "单精度浮点数值遥测"},
+	MMeTc1: {"short floating point value with time tag CP24Time2a", "带 24 位时标单精度浮点数值遥测"},
+	MItNa1: {"integrated totals", "电度"},
+	MItTa1: {"integrated totals with time tag CP24Time2a", "带 24 位时标的电度"},
+	MEpTa1: {"event of protection equipment with time tag CP24Time2a", "带 24 位时标继电保护设备事件"},
+	MEpTb1: {"start events of protection equipment with time tag CP24Time2a", "带 24 位时标继电保护设备成组启动事件"},
+	MEpTc1: {"output circuit information of protection equipment with time tag CP24Time2a", "带 24 位时标继电保护设备成组输出电路信息"},
+	MPsNa1: {"packed single point information", "带变位检出的成组单点信息"},
+	MMeNd1: {"normalized value without quality descriptor", "不带品质描述的归一化值遥测"},
+	MSpTb1: {"single point information with time tag CP56Time2a", "带 56 位时标的单点遥信"},
+	MDpTb1: {"double point information with time tag CP56Time2a", "带 56 位时标的双点遥信"},
+	MStTb1: {"step position information with time tag CP56Time2a", "带 56 位时标步位置信息"},
+	MBoTb1: {"bitstring of 32 bits with time tag CP56Time2a", "带 56 位时标 32 比特串"},
+	MMeTd1: {"normalized value with time tag CP56Time2a", "带 56 位时标的归一化值遥测"},
+	MMeTe1: {"scaled value with time tag CP56Time2a", "带 56 位时标的标度化值遥测"},
+	MMeTf1: {"short floating point value with time tag CP56Time2a", "带 56 位时标的单精度值遥测"},
+	MItTb1: {"integrated totals with time tag CP56Time2a", "带 56 位时标的电度"},
+	MEpTd1: {"event of protection equipment with time tag CP56Time2a", "带 56 位时标继电保护设备事件"},
+	MEpTe1: {"start events of protection equipment with time tag CP56Time2a", "带 56 位时标继电保护设备成组启动事件"},
+	MEpTf1: {"output circuit information of protection equipment with time tag CP56Time2a", "带 56 位时标继电保护设备成组输出电路信息"},
+}
+
+// signalCOTNames describes the COTs of the signals for logging, which follow the text and precede the gloss of the
+// signals. The point information without time tag is glossed by pointGloss.
+var signalCOTNames = map[COT]struct{ text, gloss, pointGloss string }{
+	CotPerCyc:   {" of periodically/cyclically syncing", "全遥测", "全遥信"},
+	CotSpont:    {" of spontenuous change", "自发突变", "变化遥信"},
+	CotInrogen:  {" response of general interrogation", "总召唤响应", "总召唤响应"},
+	CotReq:      {" of request", "请求", "请求"},
+	CotReqcogen: {" of counter interrogation", "电度召唤", "电度召唤"},
+}
+
+// signalMessage describes the signals of the ASDU with the COT for logging, e.g.
+//
+//	single point information of spontenuous change [变化遥信 - 单点遥信]
+func (asdu *ASDU) signalMessage() string {
+	name := signalNames[asdu.typeID]
+	cot, ok := signalCOTNames[asdu.cot]
+	if !ok {
+		return fmt.Sprintf("%s [%s]", name.text, name.gloss)
+	}
+	gloss := cot.gloss
+	switch asdu.typeID {
+	case MSpNa1, MDpNa1, MPsNa1:
+		gloss = cot.pointGloss
+	}
+	return fmt.Sprintf("%s%s [%s - %s]", name.text, cot.text, gloss, name.gloss)
+}
+
+// getElement gets the element of the type, e.g. the ones in signalElements, ref completes the date and hour of
+// CP24Time2a.
+func (ie *InformationElement) getElement(x InformationElementType, ref time.Time) {
+	switch x {
+	case SCO:
+		ie.getSCO()
+	case DCO:
+		ie.getDCO()
+	case RCO:
+		ie.getRCO()
+	case QOS:
+		ie.getQOS()
+	case QOI:
+		ie.getQOI()
+	case QCC:
+		ie.getQCC()
+	case QRP:
+		ie.getQRP()
+	case FBP:
+		ie.getFBP()
+	case SIQ:
 		ie.getSIQ()
-		ie.getCP24Time2a()
-		switch asdu.cot {
-		case CotSpont:
-			_lg.Debugf("receive i frame: single point information of spontenuous change with 24-bit time tag "+
-				"at %d is %f [%s] [自发突变 - 带 24 位时标的单点遥信]", ie.Address, ie.Value, ie.Ts)
-		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MDpNa1:
+	case DIQ:
 		ie.getDIQ()
-		switch asdu.cot {
-		case CotPerCyc:
-			_lg.Debugf("receive i frame: double point information of periodically/cyclically syncing at %d is %f "+
-				"with Quality[IV: %v, NT: %v, SB: %v, BL: %v] [全遥信 - 带品质描述/不带时标双点遥信]", ie.Address,
-				ie.Value, (ie.Quality&IV) == IV, (ie.Quality&NT) == NT, (ie.Quality&SB) == SB, (ie.Quality&BL) == BL)
-			asdu.sendSFrame = true
-		case CotSpont:
-			_lg.Debugf("receive i frame: double point information of spontenuous change at %d is %f "+
-				"with Quality[IV: %v, NT: %v, SB: %v, BL: %v] [变化遥信 - 带品质描述/不带时标双点遥信]", ie.Address,
-				ie.Value, (ie.Quality&IV) == IV, (ie.Quality&NT) == NT, (ie.Quality&SB) == SB, (ie.Quality&BL) == BL)
-			asdu.sendSFrame = true
-		case CotInrogen:
-			_lg.Debugf("receive i frame: double point information response of general interrogation at %d is %f "+
-				"with Quality[IV: %v, NT: %v, SB: %v, BL: %v] [总召唤响应 - 带品质描述/不带时标双点遥信]", ie.Address,
-				ie.Value, (ie.Quality&IV) == IV, (ie.Quality&NT) == NT, (ie.Quality&SB) == SB, (ie.Quality&BL) == BL)
+	case VTI:
+		ie.getVTI()
+	case BSI:
+		ie.getBSI()
+	case SCD:
+		ie.getSCD()
+	case QDS:
+		ie.getQDS()
+	case NVA:
+		ie.getNVA()
+	case SVA:
+		ie.getSVA()
+	case IEEE754STD:
+		ie.getIEEESTD754()
+	case BCR:
+		ie.getBCR()
+	case SEP:
+		ie.getSEP()
+	case SPE:
+		ie.getSPE()
+	case OCI:
+		ie.getOCI()
+	case QDP:
+		ie.getQDP()
+	case CP16Time2a:
+		ie.getCP16Time2a()
+	case CP24Time2a:
+		ie.getCP24Time2a(ref)
+	case CP56Time2a:
+		ie.getCP56Time2a()
+	}
+}
+
+// signalHandling returns whether the signal of the TypeID and COT is handled by the handler, and whether it's
+// acknowledged by S-format frame at once.
+func signalHandling(typeID TypeID, cot COT) (handled, ack bool) {
+	switch typeID {
+	case MSpNa1, MDpNa1, MMeNd1:
+		return true, cot == CotPerCyc || cot == CotSpont
+	case MItNa1, MItTa1:
+		return cot == CotReqcogen, false
+	case MSpTb1:
+		return cot == CotSpont, true
+	}
+	return true, true
+}
+
+// signalFields returns the structured fields of the signal for logging, the fields of the elements absent in the
+// format of the signal are omitted.
+func (asdu *ASDU) signalFields(ie *InformationElement) map[string]interface{} {
+	fields := map[string]interface{}{
+		"type_id": fmt.Sprintf("%X", uint8(asdu.typeID)),
+		"cot":     asdu.cot,
+		"ioa":     ie.Address,
+		"value":   ie.Value,
+	}
+	for _, x := range ie.Format {
+		switch x {
+		case SIQ, DIQ, QDS, SEP, QDP:
+			fields["quality"] = ie.Quality.String()
+		case VTI:
+			fields["transient"] = ie.TransientState
+		case BSI:
+			fields["bitstring"] = fmt.Sprintf("%032b", ie.Bitstring)
+		case SCD:
+			fields["status"] = fmt.Sprintf("%016b", ie.StatusChange.Status)
+			fields["changed"] = fmt.Sprintf("%016b", ie.StatusChange.Changed)
+		case SPE, OCI:
+			fields["protection"] = ie.Protection
+		case BCR:
+			fields["quality"] = ie.Quality.String()
+			fields["sequence"] = ie.CounterSequence
+			fields["carry"] = ie.CounterCarry
+			fields["adjusted"] = ie.CounterAdjusted
+		case CP16Time2a:
+			fields["elapsed"] = ie.Elapsed
+		case CP24Time2a, CP56Time2a:
+			fields["ts"] = ie.Ts
+			if ie.TimeIsInvalid {
+				fields["ts_invalid"] = true
+			}
 		}
-		asdu.toBeHandled = true
-	case MDpTa1:
-		ie.getDIQ()
-		ie.getCP24Time2a()
-		switch asdu.cot {
-		case CotSpont:
-			_lg.Debugf("receive i frame: double point information of spontenuous change with 24-bit time tag "+
-				"at %d is %f [%s] [自发突变 - 带 24 位时标的双点遥信]", ie.Address, ie.Value, ie.Ts)
+	}
+	return fields
+}
+
+// String renders the address and the fields decoded from the elements in its format, e.g.
+// "IOA=16385 value=1.5 quality=OK ts=2022-08-01T10:20:30.4+08:00". The raw bytes are rendered if it isn't decoded.
+func (ie *InformationElement) String() string {
+	return fmt.Sprintf("IOA=%d %s", ie.Address, ie.describe())
+}
+
+// describe renders the fields decoded from the elements in the order of its format, the address excluded.
+func (ie *InformationElement) describe() string {
+	if len(ie.Format) == 0 {
+		return fmt.Sprintf("raw=[% X]", ie.encoded())
+	}
+	if ie.data == nil && len(ie.Raw) > 0 {
+		// the element built to send only has the raw bytes, which are decoded by its format to render
+		x := &InformationElement{data: ie.Raw, loc: ie.loc, yearBase: ie.yearBase}
+		for _, f := range ie.Format {
+			x.getElement(f, time.Now())
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeNa1:
-		ie.getNVA()
-		ie.getQDS()
-		switch asdu.cot {
-		default:
-			_lg.Debugf("receive i frame: normalized value with quality descriptor without time tag "+
-				"at %d is %f [不带时标归一化值遥测]", ie.Address, ie.Value)
+		if x.err == nil {
+			ie = x
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeTa1:
-		ie.getNVA()
-		ie.getQDS()
-		ie.getCP24Time2a()
-		switch asdu.cot {
-		default:
-			_lg.Debugf("receive i frame: normalized value with quality descriptor with time tag CP24Time2a "+
-				"at %d is %f [%s] [带 24 位时归一化值遥测]", ie.Address, ie.Value, ie.Ts)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "value=%g", ie.Value)
+	for _, x := range ie.Format {
+		switch x {
+		case SIQ, DIQ, QDS, SEP, QDP:
+			fmt.Fprintf(&b, " quality=%s", ie.Quality)
+		case VTI:
+			fmt.Fprintf(&b, " transient=%t", ie.TransientState)
+		case BSI:
+			fmt.Fprintf(&b, " bitstring=%032b", ie.Bitstring)
+		case SCD:
+			fmt.Fprintf(&b, " status=%016b changed=%016b", ie.StatusChange.Status, ie.StatusChange.Changed)
+		case NVA, SVA:
+			fmt.Fprintf(&b, " raw=%d", ie.RawValue)
+		case SPE, OCI:
+			fmt.Fprintf(&b, " protection=%06b", ie.Protection)
+		case SCO, DCO, RCO:
+			fmt.Fprintf(&b, " select=%t qualifier=%d state=%d", ie.SelectExecute, ie.Qualifier, ie.State)
+		case BCR:
+			fmt.Fprintf(&b, " quality=%s sequence=%d carry=%t adjusted=%t", ie.Quality, ie.CounterSequence,
+				ie.CounterCarry, ie.CounterAdjusted)
+		case QCC:
+			fmt.Fprintf(&b, " request=%d freeze=%d", ie.CounterRequest, ie.FreezeMode)
+		case CP16Time2a:
+			fmt.Fprintf(&b, " elapsed=%s", ie.Elapsed)
+		case CP24Time2a, CP56Time2a:
+			fmt.Fprintf(&b, " ts=%s", ie.Ts.Format(time.RFC3339Nano))
+			if ie.TimeIsInvalid {
+				b.WriteString(" ts_invalid=true")
+			}
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeNb1:
-		ie.getSVA()
-		ie.getQDS()
-		switch asdu.cot {
-		default:
-			_lg.Debugf("receive i frame: scaled value with quality descriptor without time tag "+
-				"at %d is %f [不带时标标度化值遥测]", ie.Address, ie.Value)
+	}
+	return b.String()
+}
+
+// parseInformationElement gets the elements of the TypeID from data, it fails if data is too short for them.
+func (asdu *ASDU) parseInformationElement(data []byte, ie *InformationElement) error {
+	ie.data = data
+	ie.loc, ie.yearBase = asdu.loc, asdu.yearBase
+
+	if decode, ok := typeDecoder(asdu.typeID); ok {
+		if err := decode(ie, data); err != nil {
+			return err
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeTb1:
-		ie.getSVA()
-		ie.getQDS()
-		ie.getCP24Time2a()
-		switch asdu.cot {
-		default:
-			_lg.Debugf("receive i frame: scaled value with quality descriptor with time tag CP24Time2a "+
-				"at %d is %f [%s] [带 24 位时标标度化值遥测]", ie.Address, ie.Value, ie.Ts)
+		ie.offset = len(data)
+		// the TypeIDs of special use (128-255) are handled like the ones in monitor direction
+		asdu.toBeHandled = asdu.toBeHandled || asdu.typeID < CScNa1 || asdu.typeID >= 128
+		return nil
+	}
+
+	if elements, ok := signalElements[asdu.typeID]; ok {
+		for _, x := range elements {
+			ie.getElement(x, asdu.ref)
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeNc1:
-		ie.getIEEESTD754()
-		ie.getQDS()
-		switch asdu.cot {
-		default:
-			_lg.Debugf("receive i frame: short floating point value with quality descriptor without time tag "+
-				"at %d is %f [不带时标单精度浮点数值遥测]", ie.Address, ie.Value)
+		handled, ack := signalHandling(asdu.typeID, asdu.cot)
+		asdu.toBeHandled = asdu.toBeHandled || handled
+		asdu.sendSFrame = asdu.sendSFrame || ack
+		if ie.err == nil {
+			logWithFields(asdu.logger(), asdu.signalFields(ie), "receive i frame: %s", asdu.signalMessage())
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeTc1:
-		ie.getIEEESTD754()
-		ie.getQDS()
-		ie.getIEEESTD754()
-		switch asdu.cot {
-		default:
-			_lg.Debugf("receive i frame: short floating point value with quality descriptor without time tag "+
-				"at %d is %f [%s] [带 24 位时标单精度浮点数值遥测]", ie.Address, ie.Value, ie.Ts)
+		return ie.err
+	}
+
+	switch asdu.typeID {
+	case CScNa1, CScTa1:
+		ie.getSCO()
+		if asdu.typeID == CScTa1 {
+			ie.getCP56Time2a()
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeNd1:
-		ie.getNVA()
-		switch asdu.cot {
-		case CotPerCyc:
-			_lg.Debugf("receive i frame: measured value, normalized value without quality descriptor at %d is %f "+
-				"[全遥测 - 不带品质描述/不带时标/归一化遥测]", ie.Address, ie.Value)
-			asdu.sendSFrame = true
-		case CotSpont:
-			_lg.Debugf("receive i frame: measured value, normalized value without quality descriptor at %d is %f "+
-				"[自发突变 - 不带品质描述/不带时标/归一化遥测]", ie.Address, ie.Value)
-			asdu.sendSFrame = true
-		case CotInrogen:
-			_lg.Debugf("receive i frame: measured value, normalized value without quality descriptor at %d is %f "+
-				"[总召唤响应 - 不带品质描述/不带时标/归一化遥测]", ie.Address, ie.Value)
+		asdu.cmdRsp = asdu.commandRsp(ie, "single command [单点命令]", scsNames, errSingleCmdTerm{})
+	case CDcNa1, CDcTa1:
+		ie.getDCO()
+		if asdu.typeID == CDcTa1 {
+			ie.getCP56Time2a()
 		}
-		asdu.toBeHandled = true
-	case MItNa1:
-		ie.getBCR()
-		switch asdu.cot {
-		case CotReqcogen:
-			_lg.Debugf("receive i frame: response of counter interrogation at %d is %f "+
-				"[总电度响应]", ie.Address, ie.Value)
-			asdu.toBeHandled = true
+		asdu.cmdRsp = asdu.commandRsp(ie, "double command [双点命令]", dcsNames, errDoubleCmdTerm{})
+	case CRcNa1:
+		ie.getRCO()
+		asdu.cmdRsp = asdu.commandRsp(ie, "regulating step command [步调节命令]", rcsNames, errStepCmdTerm{})
+	case CSeNa1, CSeNb1, CSeNc1, CSeTa1, CSeTb1, CSeTc1:
+		switch asdu.typeID {
+		case CSeNa1, CSeTa1:
+			ie.getNVA()
+		case CSeNb1, CSeTb1:
+			ie.getSVA()
+		case CSeNc1, CSeTc1:
+			ie.getIEEESTD754()
 		}
-	case MItTa1:
-		ie.getBCR()
-		ie.getCP24Time2a()
-		switch asdu.cot {
-		case CotReqcogen:
-			_lg.Debugf("receive i frame: response of counter interrogation at %d is %f [%s]"+
-				"[总电度响应]", ie.Address, ie.Value, ie.Ts)
-			asdu.toBeHandled = true
+		ie.getQOS()
+		if asdu.typeID >= CSeTa1 {
+			ie.getCP56Time2a()
 		}
-	case MSpTb1:
-		ie.getSIQ()
-		ie.getCP56Time2a()
 		switch asdu.cot {
-		case CotSpont:
-			_lg.Debugf("receive i frame: single point information of spontenuous change with 56-bit time tag "+
-				"at %d is %f [%s] [自发突变 - 带 56 位时标的单点遥信]", ie.Address, ie.Value, ie.Ts)
-			asdu.toBeHandled = true
+		case CotActCon:
+			asdu.logger().Debugf("receive i frame: confirmation of set-point command at %d is %f [设点命令确认]", ie.Address, ie.Value)
+			asdu.cmdRsp = &cmdRsp{}
+		case CotActTerm:
+			asdu.logger().Debugf("receive i frame: termination of set-point command at %d [设点命令激活终止]", ie.Address)
+		default:
+			asdu.logger().Debugf("receive i frame: set-point command at %d is %f [设点命令]", ie.Address, ie.Value)
 		}
-		asdu.sendSFrame = true
-	case MDpTb1:
-		ie.getDIQ()
-		ie.getCP56Time2a()
+	case CBoNa1:
+		ie.getBSI()
 		switch asdu.cot {
-		case CotSpont:
-			_lg.Debugf("receive i frame: double point information of spontenuous change with 56-bit time tag "+
-				"at %d is %f [%s] [自发突变 - 带 56 位时标的双点遥信]", ie.Address, ie.Value, ie.Ts)
-		case CotReq:
-			_lg.Debugf("receive i frame: double point information of request with 56-bit time tag "+
-				"at %d is %f [%s] [请求 - 带 56 位时标的双点遥信]", ie.Address, ie.Value, ie.Ts)
+		case CotActCon:
+			asdu.logger().Debugf("receive i frame: confirmation of bitstring command at %d is %08X [32比特串命令确认]", ie.Address, ie.Bitstring)
+			asdu.cmdRsp = &cmdRsp{bits: ie.Bitstring}
+		case CotActTerm:
+			asdu.logger().Debugf("receive i frame: termination of bitstring command at %d [32比特串命令激活终止]", ie.Address)
 		default:
-			_lg.Debugf("receive i frame: double point information with 56-bit time tag "+
-				"at %d is %f [%s] [带 56 位时标的双点遥信]", ie.Address, ie.Value, ie.Ts)
+			asdu.logger().Debugf("receive i frame: bitstring command at %d is %08X [32比特串命令]", ie.Address, ie.Bitstring)
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeTd1:
-		ie.getNVA()
-		ie.getQDS()
-		ie.getCP56Time2a()
+	case CTsNb1:
+		ie.getFBP()
 		switch asdu.cot {
-		case CotSpont:
-			_lg.Debugf("receive i frame: normalized value of spontenuous change with 56-bit time tag "+
-				"at %d is %f [%s] [自发突变 - 带 56 位时标的归一化值遥测]", ie.Address, ie.Value, ie.Ts)
-		case CotReq:
-			_lg.Debugf("receive i frame: normalized value of request with 56-bit time tag "+
-				"at %d is %f [%s] [请求 - 带 56 位时标的归一化值遥测]", ie.Address, ie.Value, ie.Ts)
+		case CotActCon:
+			asdu.logger().Debugf("receive i frame: confirmation of test command with FBP %04X [测试命令确认]", uint16(ie.Value))
+			asdu.cmdRsp = &cmdRsp{}
+			if !asdu.pn && uint16(ie.Value) != FixedTestBitPattern {
+				asdu.cmdRsp.err = fmt.Errorf("confirmation of test command with FBP %04X", uint16(ie.Value))
+			}
 		default:
-			_lg.Debugf("receive i frame: normalized value with 56-bit time tag "+
-				"at %d is %f [%s] [带 56 位时标的归一化值遥测]", ie.Address, ie.Value, ie.Ts)
+			asdu.logger().Debugf("receive i frame: test command with FBP %04X [测试命令]", uint16(ie.Value))
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeTe1:
-		ie.getSVA()
-		ie.getQDS()
-		ie.getCP56Time2a()
+	case CRpNc1:
+		ie.getQRP()
 		switch asdu.cot {
-		case CotSpont:
-			_lg.Debugf("receive i frame: scaled value of spontenuous change with 56-bit time tag "+
-				"at %d is %f [%s] [自发突变 - 带 56 位时标的标度化值遥测]", ie.Address, ie.Value, ie.Ts)
-		case CotReq:
-			_lg.Debugf("receive i frame: scaled value of request with 56-bit time tag "+
-				"at %d is %f [%s] [请求 - 带 56 位时标的标度化值遥测]", ie.Address, ie.Value, ie.Ts)
+		case CotActCon:
+			asdu.logger().Debugf("receive i frame: confirmation of reset process command with QRP %d [复位进程命令确认]", uint8(ie.Value))
+			asdu.cmdRsp = &cmdRsp{}
 		default:
-			_lg.Debugf("receive i frame: scaled value with 56-bit time tag "+
-				"at %d is %f [%s] [带 56 位时标的标度化值遥测]", ie.Address, ie.Value, ie.Ts)
+			asdu.logger().Debugf("receive i frame: reset process command with QRP %d [复位进程命令]", uint8(ie.Value))
 		}
-		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MMeTf1:
-		ie.getIEEESTD754()
-		ie.getQDS()
-		ie.getCP56Time2a()
+	case CCdNa1:
+		ie.getCP16Time2a()
 		switch asdu.cot {
-		case CotSpont:
-			_lg.Debugf("receive i frame: short floating point value of spontenuous change with 56-bit time tag "+
-				"at %d is %f [%s] [自发突变 - 带 56 位时标的单精度值遥测]", ie.Address, ie.Value, ie.Ts)
-		case CotReq:
-			_lg.Debugf("receive i frame: short floating point value of request with 56-bit time tag "+
-				"at %d is %f [%s] [请求 - 带 56 位时标的单精度值遥测]", ie.Address, ie.Value, ie.Ts)
+		case CotActCon:
+			asdu.logger().Debugf("receive i frame: confirmation of delay acquisition command with %s [延时获得命令确认]", ie.Elapsed)
+			asdu.cmdRsp = &cmdRsp{}
 		default:
-			_lg.Debugf("receive i frame: short floating point value with 56-bit time tag "+
-				"at %d is %f [%s] [带 56 位时标的单精度值遥测]", ie.Address, ie.Value, ie.Ts)
+			asdu.logger().Debugf("receive i frame: delay acquisition command with %s [延时获得命令]", ie.Elapsed)
 		}
 		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case MItTb1:
-		ie.getBCR()
+	case CCsNa1:
 		ie.getCP56Time2a()
 		switch asdu.cot {
-		case CotSpont:
-			_lg.Debugf("receive i frame: integrated totals of spontenuous change with 56-bit time tag "+
-				"at %d is %f [%s] [自发突变 - 带 56 位时标的电度]", ie.Address, ie.Value, ie.Ts)
-		case CotReqcogen:
-			_lg.Debugf("receive i frame: integrated totals of counter interrogation with 56-bit time tag "+
-				"at %d is %f [%s] [电度召唤 - 带 56 位时标的电度]", ie.Address, ie.Value, ie.Ts)
+		case CotActCon:
+			asdu.logger().Debugf("receive i frame: confirmation of clock synchronization at %s [时钟同步确认]", ie.Ts)
+			asdu.cmdRsp = &cmdRsp{}
 		default:
-			_lg.Debugf("receive i frame: short floating point value with 56-bit time tag "+
-				"at %d is %f [%s] [带 56 位时标的电度]", ie.Address, ie.Value, ie.Ts)
+			asdu.logger().Debugf("receive i frame: clock synchronization at %s [时钟同步]", ie.Ts)
 		}
 		asdu.toBeHandled = true
-		asdu.sendSFrame = true
-	case CScNa1:
-		ie.getSCO()
-		switch asdu.cot {
-		case CotActCon:
-			if ie.Value == 0x80 {
-				_lg.Debugf("receive i frame: select confirmation of single command - open [单点命令遥控选择确认 - 分闸]")
-				asdu.cmdRsp = &cmdRsp{}
-			} else if ie.Value == 0x81 {
-				_lg.Debugf("receive i frame: select confirmation of single command - close [单点命令遥控选择确认 - 合闸]")
-				asdu.cmdRsp = &cmdRsp{}
-			} else if ie.Value == 0x00 {
-				_lg.Debugf("receive i frame: execute confirmation of single command - open [单点命令遥控执行确认 - 分闸]")
-				asdu.cmdRsp = &cmdRsp{}
-			} else if ie.Value == 0x01 {
-				_lg.Debugf("receive i frame: execute confirmation of single command - close [单点命令遥控执行确认 - 合闸]")
-				asdu.cmdRsp = &cmdRsp{}
-			} else {
-				_lg.Debugf("receive i frame: confirmation of single command [单点命令确认]")
-			}
-		case CotDeactCon:
-			if ie.Value == 0x00 {
-				_lg.Debugf("receive i frame: undo confirmation of single command - open [单点命令遥控撤销确认 - 分闸]")
-			} else if ie.Value == 0x01 {
-				_lg.Debugf("receive i frame: undo confirmation of single command - close [单点命令遥控撤销确认 - 合闸]")
-			} else {
-				_lg.Debugf("receive i frame: confirmation of single command [单点命令激活确认]")
-			}
-		case CotActTerm:
-			_lg.Debugf("receive i frame: termination of single command [单点命令激活终止]")
-			asdu.cmdRsp = &cmdRsp{
-				err: errSingleCmdTerm{},
-			}
-		}
-	case CDcNa1:
-		ie.getDCO()
-		switch asdu.cot {
-		case CotActCon:
-			if ie.Value == 0x81 {
-				_lg.Debugf("receive i frame: select confirmation of double command - open [双点命令遥控选择确认 - 分闸]")
-				asdu.cmdRsp = &cmdRsp{}
-			} else if ie.Value == 0x82 {
-				_lg.Debugf("receive i frame: select confirmation of double command - close [双点命令遥控选择确认 - 合闸]")
-				asdu.cmdRsp = &cmdRsp{}
-			} else if ie.Value == 0x01 {
-				_lg.Debugf("receive i frame: execute confirmation of double command - open [双点命令遥控执行确认 - 分闸]")
-				asdu.cmdRsp = &cmdRsp{}
-			} else if ie.Value == 0x02 {
-				_lg.Debugf("receive i frame: execute confirmation of double command - close [双点命令遥控执行确认 - 合闸]")
-				asdu.cmdRsp = &cmdRsp{}
-			} else {
-				_lg.Debugf("receive i frame: confirmation of double command [双点命令激活确认]")
-			}
-		case CotDeactCon:
-			if ie.Value == 0x01 {
-				_lg.Debugf("receive i frame: undo confirmation of double command - open [双点命令遥控撤销确认 - 分闸]")
-			} else if ie.Value == 0x02 {
-				_lg.Debugf("receive i frame: undo confirmation of double command - close [双点命令遥控撤销确认 - 合闸]")
-			} else {
-				_lg.Debugf("receive i frame: undo confirmation of double command [双点命令遥控取消激活确认]")
-			}
-		case CotActTerm:
-			_lg.Debugf("receive i frame: termination of double command [双点命令激活终止]")
-			asdu.cmdRsp = &cmdRsp{
-				err: errSingleCmdTerm{},
-			}
-		}
 	case CIcNa1:
+		ie.getQOI()
+		// QOI is the COT of the answers, so the group is decoded as the COT
+		ie.Group = COT(ie.Value).InterrogationGroup()
 		switch asdu.cot {
+		case CotAct:
+			asdu.logger().Debugf("receive i frame: general interrogation with QOI %d [总召唤]", int(ie.Value))
+			asdu.toBeHandled = true
 		case CotActCon:
-			_lg.Debugf("receive i frame: confirmation of general interrogation [总召唤确认]")
+			asdu.logger().Debugf("receive i frame: confirmation of general interrogation with QOI %d [总召唤确认]", int(ie.Value))
+			asdu.toBeHandled = asdu.opt.handlesInterrogationConfirmations()
 		case CotActTerm:
-			_lg.Debugf("receive i frame: termination of general interrogation [总召唤结束]")
+			asdu.logger().Debugf("receive i frame: termination of general interrogation with QOI %d [总召唤结束]", int(ie.Value))
+			asdu.toBeHandled = asdu.opt.handlesInterrogationConfirmations()
 			asdu.sendSFrame = true
 		}
 	case CCiNa1:
+		ie.getQCC()
 		switch asdu.cot {
 		case CotActCon:
-			_lg.Debugf("receive i frame: confirmation of counter interrogation [总电度确认]")
+			asdu.logger().Debugf("receive i frame: confirmation of counter interrogation with request %d and freeze %d [总电度确认]",
+				ie.CounterRequest, ie.FreezeMode)
+			asdu.toBeHandled = asdu.opt.handlesInterrogationConfirmations()
 		case CotActTerm:
-			_lg.Debugf("receive i frame: termination of counter interrogation [总电度结束]")
+			asdu.logger().Debugf("receive i frame: termination of counter interrogation with request %d and freeze %d [总电度结束]",
+				ie.CounterRequest, ie.FreezeMode)
+			asdu.toBeHandled = asdu.opt.handlesInterrogationConfirmations()
 			asdu.sendSFrame = true
 		}
 	default:
-		_lg.Warnf("unsupported type: TypeID[%X], COT[%X]", asdu.typeID, asdu.cot)
+		asdu.logger().Warnf("unsupported type: TypeID[%X], COT[%X]", uint8(asdu.typeID), uint8(asdu.cot))
 	}
+	// the command is rejected by the negative confirmation
+	if asdu.pn && asdu.cmdRsp != nil && asdu.cmdRsp.err == nil {
+		asdu.logger().Warnf("receive i frame: negative confirmation of TypeID[%X] with COT %d", uint8(asdu.typeID), asdu.cot)
+		asdu.cmdRsp.err = errCommandRejected{typeID: asdu.typeID, cot: asdu.cot}
+	}
+	if asdu.cmdRsp != nil {
+		asdu.cmdRsp.typeID, asdu.cmdRsp.address = asdu.typeID, ie.Address
+	}
+	return ie.err
 }
 
 type InformationElementFormat []InformationElementType
@@ -755,6 +1397,72 @@ const (
 	FBP
 )
 
+/*
+StatusChangeDetection holds 16 packed single points, the status (ST) and the change detection (CD) of point n are
+bit n of Status and Changed respectively.
+
+  | <-         16 bits         -> | <-         16 bits         -> |
+  | ST16 ... ST1 (Status)         | CD16 ... CD1 (Changed)        |
+*/
+type StatusChangeDetection struct {
+	Status  uint16 `json:"status"`
+	Changed uint16 `json:"changed"`
+}
+
+// Point returns the status of point n (0-15) and whether it has changed since the last report.
+func (scd StatusChangeDetection) Point(n int) (status, changed bool) {
+	return scd.Status&(1<<n) != 0, scd.Changed&(1<<n) != 0
+}
+
+/*
+ProtectionQuality is the quality descriptor for events of protection equipment (QDP), which is also carried by the
+single event of protection equipment (SEP). Unlike QualityDescriptor of process information, it has the EI bit but no
+OV bit, since the elapsed time is measured rather than the value.
+
+  | <-                    8 bits                    -> |
+  | IV  | NT  | SB  | BL  | EI  |         RES          |
+*/
+type ProtectionQuality struct {
+	Invalid            bool `json:"invalid"`              // IV
+	NotTopical         bool `json:"not_topical"`          // NT
+	Substituted        bool `json:"substituted"`          // SB
+	Blocked            bool `json:"blocked"`              // BL
+	ElapsedTimeInvalid bool `json:"elapsed_time_invalid"` // EI, the elapsed time is not correctly acquired
+}
+
+func parseProtectionQuality(b byte) ProtectionQuality {
+	return ProtectionQuality{
+		Invalid:            b&byte(IV) != 0,
+		NotTopical:         b&byte(NT) != 0,
+		Substituted:        b&byte(SB) != 0,
+		Blocked:            b&byte(BL) != 0,
+		ElapsedTimeInvalid: b&byte(EI) != 0,
+	}
+}
+
+// IsValid reports whether none of the bits is set.
+func (q ProtectionQuality) IsValid() bool {
+	return q == ProtectionQuality{}
+}
+
+// Bits of InformationElement.Protection decoded from the start events of protection equipment (SPE).
+const (
+	SPEGS  uint8 = 1 << iota // general start of operation
+	SPESL1                   // start of operation phase L1
+	SPESL2                   // start of operation phase L2
+	SPESL3                   // start of operation phase L3
+	SPESIE                   // start of operation IE (earth current)
+	SPESRD                   // start of operation in reverse direction
+)
+
+// Bits of InformationElement.Protection decoded from the output circuit information of protection equipment (OCI).
+const (
+	OCIGC  uint8 = 1 << iota // general command to output circuit
+	OCICL1                   // command to output circuit phase L1
+	OCICL2                   // command to output circuit phase L2
+	OCICL3                   // command to output circuit phase L3
+)
+
 type QualityDescriptor byte
 
 const (
@@ -783,6 +1491,9 @@ const (
 	// - The value of the information object is beyond a predefined range of value (mainly applicable to analog values).
 	// - It is used primarily with analog or counter values.
 	OV QualityDescriptor = 1 << 0
+	// EI = ELAPSED TIME VALID (0) / ELAPSED TIME INVALID (1)
+	// - It's only used with events of protection equipment (SEP, QDP), the elapsed time is not correctly acquired.
+	EI QualityDescriptor = 1 << 3
 
 	// SPI (Single Point Information).
 	// - 0 means status OFF;
@@ -795,3 +1506,47 @@ const (
 	// - 3 means intermediate state;
 	DPI QualityDescriptor = 3
 )
+
+// qualityFlags are the quality flags in the order of String.
+var qualityFlags = []struct {
+	flag QualityDescriptor
+	name string
+}{
+	{IV, "IV"}, {NT, "NT"}, {SB, "SB"}, {BL, "BL"}, {EI, "EI"}, {OV, "OV"},
+}
+
+// String returns the flags set joined by "|", e.g. "IV|NT", or "OK" if none is set.
+func (q QualityDescriptor) String() string {
+	var b strings.Builder
+	for _, x := range qualityFlags {
+		if q&x.flag == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('|')
+		}
+		b.WriteString(x.name)
+	}
+	if b.Len() == 0 {
+		return "OK"
+	}
+	return b.String()
+}
+
+// IsInvalid reports whether IV is set.
+func (q QualityDescriptor) IsInvalid() bool { return q&IV != 0 }
+
+// IsNotTopical reports whether NT is set.
+func (q QualityDescriptor) IsNotTopical() bool { return q&NT != 0 }
+
+// IsSubstituted reports whether SB is set.
+func (q QualityDescriptor) IsSubstituted() bool { return q&SB != 0 }
+
+// IsBlocked reports whether BL is set.
+func (q QualityDescriptor) IsBlocked() bool { return q&BL != 0 }
+
+// IsOverflow reports whether OV is set.
+func (q QualityDescriptor) IsOverflow() bool { return q&OV != 0 }
+
+// IsElapsedTimeInvalid reports whether EI is set.
+func (q QualityDescriptor) IsElapsedTimeInvalid() bool { return q&EI != 0 }
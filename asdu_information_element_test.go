@@ -0,0 +1,951 @@
+package iec104
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func Test_cp56Year(t *testing.T) {
+	tests := []struct {
+		name string
+		base int
+		x    byte
+		want int
+	}{
+		{"0 in default window", DefaultCP56YearBase, 0, 2000},
+		{"69 in default window", DefaultCP56YearBase, 69, 2069},
+		{"70 in default window", DefaultCP56YearBase, 70, 1970},
+		{"99 in default window", DefaultCP56YearBase, 99, 1999},
+		{"0 in window from 2000", 2000, 0, 2000},
+		{"69 in window from 2000", 2000, 69, 2069},
+		{"70 in window from 2000", 2000, 70, 2070},
+		{"99 in window from 2000", 2000, 99, 2099},
+		{"21 in window from 2022", 2022, 21, 2121},
+		{"22 in window from 2022", 2022, 22, 2022},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cp56Year(tt.x, tt.base); got != tt.want {
+				t.Errorf("cp56Year() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInformationElement_getCP56Time2a(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want time.Time
+	}{
+		{
+			"year 0",
+			[]byte{0x10, 0x27, 0x1e, 0x0c, 0x0f, 0x06, 0x00}, // 10000ms, 30min, 12h, 15th, June
+			time.Date(2000, time.June, 15, 12, 30, 10, 0, time.Local),
+		},
+		{
+			"year 69",
+			[]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x45},
+			time.Date(2069, time.January, 1, 0, 0, 0, 0, time.Local),
+		},
+		{
+			"year 70",
+			[]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x46},
+			time.Date(1970, time.January, 1, 0, 0, 0, 0, time.Local),
+		},
+		{
+			"year 99",
+			[]byte{0xe7, 0x03, 0x3b, 0x17, 0x1f, 0x0c, 0x63}, // 999ms, 59min, 23h, 31st, December
+			time.Date(1999, time.December, 31, 23, 59, 0, 999*int(time.Millisecond), time.Local),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ie := &InformationElement{data: tt.data}
+			ie.getCP56Time2a()
+			if !ie.Ts.Equal(tt.want) {
+				t.Errorf("getCP56Time2a() = %v, want %v", ie.Ts, tt.want)
+			}
+			if ie.offset != 7 {
+				t.Errorf("offset = %d, want 7", ie.offset)
+			}
+		})
+	}
+}
+
+func TestInformationElement_getCP24Time2a(t *testing.T) {
+	ref := time.Date(2022, time.August, 1, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		ref  time.Time
+		data []byte
+		want time.Time
+	}{
+		{
+			"same hour",
+			ref,
+			[]byte{0x10, 0x27, 0x0f}, // 10000ms, 15min
+			time.Date(2022, time.August, 1, 10, 15, 10, 0, time.Local),
+		},
+		{
+			"previous hour",
+			time.Date(2022, time.August, 1, 10, 0, 5, 0, time.Local),
+			[]byte{0x30, 0x75, 0x3b}, // 30000ms, 59min
+			time.Date(2022, time.August, 1, 9, 59, 30, 0, time.Local),
+		},
+		{
+			"previous day",
+			time.Date(2022, time.August, 1, 0, 0, 5, 0, time.Local),
+			[]byte{0x30, 0x75, 0x3b}, // 30000ms, 59min
+			time.Date(2022, time.July, 31, 23, 59, 30, 0, time.Local),
+		},
+		{
+			"next hour",
+			time.Date(2022, time.August, 1, 10, 59, 50, 0, time.Local),
+			[]byte{0xd0, 0x07, 0x00}, // 2000ms, 0min
+			time.Date(2022, time.August, 1, 11, 0, 2, 0, time.Local),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ie := &InformationElement{data: tt.data}
+			ie.getCP24Time2a(tt.ref)
+			if !ie.Ts.Equal(tt.want) {
+				t.Errorf("getCP24Time2a() = %v, want %v", ie.Ts, tt.want)
+			}
+			if ie.offset != 3 {
+				t.Errorf("offset = %d, want 3", ie.offset)
+			}
+		})
+	}
+}
+
+func TestASDU_ParseCP24ReferenceClock(t *testing.T) {
+	ref := time.Date(2022, time.August, 1, 10, 30, 0, 0, time.Local)
+	// MSpTa1, CotSpont, IOA 1 is ON at 20min 1000ms
+	data := []byte{0x02, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01, 0xe8, 0x03, 0x14}
+
+	x := &ASDU{opt: &parseOption{cp24Clock: func() time.Time { return ref }}}
+	if err := x.Parse(data); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := time.Date(2022, time.August, 1, 10, 20, 1, 0, time.Local)
+	if got := x.Signals[0].Ts; !got.Equal(want) {
+		t.Errorf("Ts = %v, want %v", got, want)
+	}
+}
+
+func TestParseMSpTa1DateBase(t *testing.T) {
+	tests := []struct {
+		name string
+		base time.Time
+		data []byte
+		want time.Time
+	}{
+		{
+			"same hour",
+			time.Date(2022, time.August, 1, 10, 30, 0, 0, time.Local),
+			[]byte{0x10, 0x27, 0x0f}, // 15min 10000ms
+			time.Date(2022, time.August, 1, 10, 15, 10, 0, time.Local),
+		},
+		{
+			"rollover to the next hour",
+			time.Date(2022, time.August, 1, 10, 59, 58, 0, time.Local),
+			[]byte{0xd0, 0x07, 0x00}, // 0min 2000ms
+			time.Date(2022, time.August, 1, 11, 0, 2, 0, time.Local),
+		},
+		{
+			"rollover to the next day",
+			time.Date(2022, time.July, 31, 23, 59, 58, 0, time.Local),
+			[]byte{0xe8, 0x03, 0x00}, // 0min 1000ms
+			time.Date(2022, time.August, 1, 0, 0, 1, 0, time.Local),
+		},
+		{
+			"event of the previous day",
+			time.Date(2022, time.August, 1, 0, 0, 5, 0, time.Local),
+			[]byte{0x30, 0x75, 0x3b}, // 59min 30000ms
+			time.Date(2022, time.July, 31, 23, 59, 30, 0, time.Local),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// MSpTa1, CotSpont, IOA 1 is ON
+			data := append([]byte{0x02, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}, tt.data...)
+			x := &ASDU{opt: &parseOption{cp24Clock: func() time.Time { return tt.base }}}
+			if err := x.Parse(data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := x.Signals[0].Ts; !got.Equal(tt.want) {
+				t.Errorf("Ts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerializeCP56Time2a(t *testing.T) {
+	tests := []struct {
+		name string
+		ts   time.Time
+		want []byte
+	}{
+		{
+			"Monday",
+			time.Date(2022, time.August, 1, 10, 30, 15, 500*int(time.Millisecond), time.Local),
+			[]byte{0x8c, 0x3c, 0x1e, 0x0a, 0x21, 0x08, 0x16},
+		},
+		{
+			"Sunday",
+			time.Date(2069, time.December, 29, 23, 59, 59, 999*int(time.Millisecond), time.Local),
+			[]byte{0x5f, 0xea, 0x3b, 0x17, 0xfd, 0x0c, 0x45},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SerializeCP56Time2a(tt.ts)
+			if tt.ts.IsDST() {
+				tt.want[3] |= 0x80
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("SerializeCP56Time2a() = [% X], want [% X]", got, tt.want)
+			}
+			if ts, _, _, _ := decodeCP56Time2a(got, time.Local, DefaultCP56YearBase); !ts.Equal(tt.ts) {
+				t.Errorf("decodeCP56Time2a(SerializeCP56Time2a()) = %v, want %v", ts, tt.ts)
+			}
+		})
+	}
+}
+
+func TestInformationElement_getCP56Time2aFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		invalid bool
+		summer  bool
+		weekday uint8
+	}{
+		{"valid", []byte{0x8c, 0x3c, 0x1e, 0x0a, 0x21, 0x08, 0x16}, false, false, 1},
+		{"invalid", []byte{0x8c, 0x3c, 0x9e, 0x0a, 0x21, 0x08, 0x16}, true, false, 1},
+		{"summer time", []byte{0x8c, 0x3c, 0x1e, 0x8a, 0x21, 0x08, 0x16}, false, true, 1},
+		{"day of week not used", []byte{0x8c, 0x3c, 0x1e, 0x0a, 0x01, 0x08, 0x16}, false, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ie := &InformationElement{data: tt.data}
+			ie.getCP56Time2a()
+			if ie.TimeIsInvalid != tt.invalid || ie.SummerTime != tt.summer || ie.Weekday != tt.weekday {
+				t.Errorf("getCP56Time2a() = {IV %t, SU %t, weekday %d}, want {%t, %t, %d}", ie.TimeIsInvalid,
+					ie.SummerTime, ie.Weekday, tt.invalid, tt.summer, tt.weekday)
+			}
+		})
+	}
+
+	// IV of CP24Time2a
+	ie := &InformationElement{data: []byte{0x8c, 0x3c, 0x9e}}
+	ie.getCP24Time2a(time.Now())
+	if !ie.TimeIsInvalid {
+		t.Error("getCP24Time2a() TimeIsInvalid = false, want true")
+	}
+}
+
+func TestApplySummerTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("load location: %v", err)
+	}
+	// 02:30 is repeated on 2022-10-30 in Europe/Berlin, the first one is CEST (+02:00) and the second one is CET
+	summer := time.Date(2022, time.October, 30, 0, 30, 0, 0, time.UTC).In(berlin)
+	winter := time.Date(2022, time.October, 30, 1, 30, 0, 0, time.UTC).In(berlin)
+	for _, ts := range []time.Time{summer, winter} {
+		if got := applySummerTime(ts, true); !got.Equal(summer) {
+			t.Errorf("applySummerTime(%s, SU=1) = %s, want %s", ts, got, summer)
+		}
+		if got := applySummerTime(ts, false); !got.Equal(winter) {
+			t.Errorf("applySummerTime(%s, SU=0) = %s, want %s", ts, got, winter)
+		}
+	}
+	// the wall clock which isn't repeated isn't changed by SU
+	noon := time.Date(2022, time.October, 30, 12, 0, 0, 0, berlin)
+	if got := applySummerTime(noon, true); !got.Equal(noon) {
+		t.Errorf("applySummerTime(%s, SU=1) = %s, want %s", noon, got, noon)
+	}
+}
+
+func TestParseDoubleCommandConfirmation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cot     COT
+		dco     byte
+		want    *cmdRsp
+		wantErr bool
+	}{
+		{"select open", CotActCon, 0x81, &cmdRsp{phase: CommandPhaseSelect, state: 0b01}, false},
+		{"select close", CotActCon, 0x82, &cmdRsp{phase: CommandPhaseSelect, state: 0b10}, false},
+		{"select close with long pulse", CotActCon, 0x8a, &cmdRsp{phase: CommandPhaseSelect, state: 0b10}, false},
+		{"execute open", CotActCon, 0x01, &cmdRsp{phase: CommandPhaseExecute, state: 0b01}, false},
+		{"execute close", CotActCon, 0x02, &cmdRsp{phase: CommandPhaseExecute, state: 0b10}, false},
+		{"execute close with short pulse", CotActCon, 0x06, &cmdRsp{phase: CommandPhaseExecute, state: 0b10}, false},
+		{"cancel select open", CotDeactCon, 0x81, nil, false},
+		{"cancel select close", CotDeactCon, 0x82, nil, false},
+		{"cancel execute open", CotDeactCon, 0x01, nil, false},
+		{"cancel execute close", CotDeactCon, 0x02, nil, false},
+		{"termination open", CotActTerm, 0x01, &cmdRsp{phase: CommandPhaseTerm, state: 0b01}, true},
+		{"termination close", CotActTerm, 0x02, &cmdRsp{phase: CommandPhaseTerm, state: 0b10}, true},
+		{"select not permitted", CotActCon, 0x80, &cmdRsp{phase: CommandPhaseSelect, state: 0b00}, true},
+		{"execute not permitted", CotActCon, 0x03, &cmdRsp{phase: CommandPhaseExecute, state: 0b11}, true},
+		{"cancel not permitted", CotDeactCon, 0x83, nil, false},
+		{"activation", CotAct, 0x81, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &ASDU{}
+			if err := x.Parse([]byte{byte(CDcNa1), 0x01, byte(tt.cot), 0x00, 0x01, 0x00, 0x05, 0x00, 0x00, tt.dco}); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := x.cmdRsp
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("cmdRsp = %+v, want %+v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.phase != tt.want.phase || got.state != tt.want.state || (got.err != nil) != tt.wantErr {
+				t.Errorf("cmdRsp = {%s, %d, %v}, want {%s, %d, wantErr %v}",
+					got.phase, got.state, got.err, tt.want.phase, tt.want.state, tt.wantErr)
+			}
+			if signal := x.Signals[0]; signal.SelectExecute != (tt.dco&0x80 != 0) || signal.Qualifier != (tt.dco>>2)&0x1f {
+				t.Errorf("Signals[0] = {S/E %v, QU %d}, want DCO %02X", signal.SelectExecute, signal.Qualifier, tt.dco)
+			}
+		})
+	}
+}
+
+func TestParseStepCommandConfirmation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cot     COT
+		rco     byte
+		want    *cmdRsp
+		wantErr bool
+	}{
+		{"select lower", CotActCon, 0x81, &cmdRsp{phase: CommandPhaseSelect, state: 0b01}, false},
+		{"execute higher with long pulse", CotActCon, 0x0a, &cmdRsp{phase: CommandPhaseExecute, state: 0b10}, false},
+		{"cancel select higher", CotDeactCon, 0x82, nil, false},
+		{"termination higher", CotActTerm, 0x02, &cmdRsp{phase: CommandPhaseTerm, state: 0b10}, true},
+		{"execute not permitted", CotActCon, 0x03, &cmdRsp{phase: CommandPhaseExecute, state: 0b11}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &ASDU{}
+			if err := x.Parse([]byte{byte(CRcNa1), 0x01, byte(tt.cot), 0x00, 0x01, 0x00, 0x05, 0x00, 0x00, tt.rco}); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := x.cmdRsp
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("cmdRsp = %+v, want %+v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.phase != tt.want.phase || got.state != tt.want.state || (got.err != nil) != tt.wantErr {
+				t.Errorf("cmdRsp = {%s, %d, %v}, want {%s, %d, wantErr %v}",
+					got.phase, got.state, got.err, tt.want.phase, tt.want.state, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCommandWithTimeTagConfirmation(t *testing.T) {
+	ts := time.Date(2022, time.August, 1, 10, 30, 15, 0, time.Local)
+	tests := []struct {
+		name   string
+		typeID TypeID
+		co     byte // SCO or DCO
+		want   cmdRsp
+	}{
+		{"select single command close", CScTa1, 0x81, cmdRsp{phase: CommandPhaseSelect, state: 1}},
+		{"execute double command open of short pulse", CDcTa1, 0x05, cmdRsp{phase: CommandPhaseExecute, state: 0b01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append([]byte{byte(tt.typeID), 0x01, byte(CotActCon), 0x00, 0x01, 0x00, 0x01, 0x60, 0x00, tt.co},
+				SerializeCP56Time2a(ts)...)
+			x := &ASDU{}
+			if err := x.Parse(data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := x.cmdRsp; got == nil || got.phase != tt.want.phase || got.state != tt.want.state || got.err != nil {
+				t.Errorf("cmdRsp = %+v, want %+v", got, tt.want)
+			}
+			signal := x.Signals[0]
+			if signal.SelectExecute != (tt.co&0x80 != 0) || signal.Qualifier != (tt.co>>2)&0x1f || !signal.Ts.Equal(ts) {
+				t.Errorf("Signals[0] = {S/E %t, QU %d, Ts %s}, want %02X at %s", signal.SelectExecute,
+					signal.Qualifier, signal.Ts, tt.co, ts)
+			}
+		})
+	}
+}
+
+func Test_serializeNVA(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		want  []byte
+	}{
+		{"zero", 0, []byte{0x00, 0x00}},
+		{"half", 0.5, []byte{0x00, 0x40}},
+		{"minus one", -1, []byte{0x00, 0x80}},
+		{"clamp one", 1, []byte{0xff, 0x7f}},
+		{"clamp below minus one", -2, []byte{0x00, 0x80}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serializeNVA(tt.value); !bytes.Equal(got, tt.want) {
+				t.Errorf("serializeNVA(%f) = [% X], want [% X]", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStepPosition(t *testing.T) {
+	tests := []struct {
+		name          string
+		vti           byte
+		wantValue     float64
+		wantTransient bool
+	}{
+		{"zero", 0x00, 0, false},
+		{"one", 0x01, 1, false},
+		{"maximum", 0x3f, 63, false},
+		{"minus one", 0x7f, -1, false},
+		{"minimum", 0x40, -64, false},
+		{"zero in transient state", 0x80, 0, true},
+		{"maximum in transient state", 0xbf, 63, true},
+		{"minimum in transient state", 0xc0, -64, true},
+		{"minus three in transient state", 0xfd, -3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &ASDU{}
+			if err := x.Parse([]byte{byte(MStNa1), 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x05, 0x00, 0x00, tt.vti, 0x00}); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if signal := x.Signals[0]; signal.Value != tt.wantValue || signal.Transient() != tt.wantTransient {
+				t.Errorf("Signals[0] = {%f, transient %v}, want {%f, transient %v}",
+					signal.Value, signal.Transient(), tt.wantValue, tt.wantTransient)
+			}
+		})
+	}
+}
+
+func TestParseBitstring(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		wantBitstring uint32
+		wantQuality   QualityDescriptor
+		wantTs        time.Time
+	}{
+		{
+			"without time tag",
+			[]byte{byte(MBoNa1), 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x05, 0x00, 0x00, 0x78, 0x56, 0x34, 0x12, 0x00},
+			0x12345678, 0, time.Time{},
+		},
+		{
+			"invalid without time tag",
+			[]byte{byte(MBoNa1), 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x05, 0x00, 0x00, 0x01, 0x00, 0x00, 0x80, 0x80},
+			0x80000001, 0x80, time.Time{},
+		},
+		{
+			"with time tag CP56Time2a",
+			[]byte{byte(MBoTb1), 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x05, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0x00,
+				0x8c, 0x3c, 0x1e, 0x0a, 0x0f, 0x06, 0x16},
+			0xffffffff, 0, time.Date(2022, time.June, 15, 10, 30, 15, 500*int(time.Millisecond), time.Local),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &ASDU{}
+			if err := x.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			signal := x.Signals[0]
+			if signal.Bitstring != tt.wantBitstring || signal.Quality != tt.wantQuality || !signal.Ts.Equal(tt.wantTs) {
+				t.Errorf("Signals[0] = {%08X, %02X, %s}, want {%08X, %02X, %s}",
+					signal.Bitstring, signal.Quality, signal.Ts, tt.wantBitstring, tt.wantQuality, tt.wantTs)
+			}
+			if !bytes.Equal(signal.Raw, tt.data[9:13]) {
+				t.Errorf("Signals[0].Raw = [% X], want [% X]", signal.Raw, tt.data[9:13])
+			}
+		})
+	}
+}
+
+func TestParsePackedSinglePoint(t *testing.T) {
+	x := &ASDU{}
+	if err := x.Parse([]byte{byte(MPsNa1), 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x05, 0x00, 0x00,
+		0x05, 0x80, 0x04, 0x00, 0x00}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	scd := x.Signals[0].StatusChange
+	if scd.Status != 0x8005 || scd.Changed != 0x0004 {
+		t.Fatalf("StatusChange = {%04X, %04X}, want {8005, 0004}", scd.Status, scd.Changed)
+	}
+	tests := []struct {
+		n           int
+		wantStatus  bool
+		wantChanged bool
+	}{
+		{0, true, false},
+		{1, false, false},
+		{2, true, true},
+		{15, true, false},
+	}
+	for _, tt := range tests {
+		if status, changed := scd.Point(tt.n); status != tt.wantStatus || changed != tt.wantChanged {
+			t.Errorf("Point(%d) = {%v, %v}, want {%v, %v}", tt.n, status, changed, tt.wantStatus, tt.wantChanged)
+		}
+	}
+}
+
+func TestDecodeElement(t *testing.T) {
+	ref := time.Date(2022, time.August, 1, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		name        string
+		typeID      TypeID
+		data        []byte
+		wantValue   float64
+		wantQuality QualityDescriptor
+		wantTs      time.Time
+		wantErr     bool
+	}{
+		{"SIQ on", MSpNa1, []byte{0x01}, 1, 0, time.Time{}, false},
+		{"SIQ off and invalid", MSpNa1, []byte{0x80}, 0, IV, time.Time{}, false},
+		{"NVA", MMeNa1, []byte{0x00, 0x40, 0x00}, 0.5, 0, time.Time{}, false},
+		{"NVA overflow", MMeNa1, []byte{0x00, 0xc0, 0x01}, -0.5, 0x01, time.Time{}, false},
+		{
+			"SIQ with time tag CP24Time2a", MSpTa1, []byte{0x01, 0x10, 0x27, 0x0f}, 1, 0,
+			time.Date(2022, time.August, 1, 10, 15, 10, 0, time.Local), false,
+		},
+		{
+			"SIQ with time tag CP56Time2a", MSpTb1, []byte{0x01, 0x8c, 0x3c, 0x1e, 0x0a, 0x0f, 0x06, 0x16}, 1, 0,
+			time.Date(2022, time.June, 15, 10, 30, 15, 500*int(time.Millisecond), time.Local), false,
+		},
+		{
+			"NVA with time tag CP56Time2a", MMeTd1, []byte{0x00, 0x40, 0x00, 0x8c, 0x3c, 0x1e, 0x0a, 0x0f, 0x06, 0x16},
+			0.5, 0, time.Date(2022, time.June, 15, 10, 30, 15, 500*int(time.Millisecond), time.Local), false,
+		},
+		{"too short", MSpTb1, []byte{0x01, 0x8c, 0x3c}, 0, 0, time.Time{}, true},
+		{"too long", MMeNa1, []byte{0x00, 0x40, 0x00, 0x00}, 0, 0, time.Time{}, true},
+		{"unsupported", TypeID(0xff), []byte{0x01}, 0, 0, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeElement(tt.typeID, tt.data, ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeElement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, supported := elementLen[tt.typeID]; supported && !IsErrElementLength(err) {
+					t.Errorf("DecodeElement() error = %v, want %v", err, ErrElementLength)
+				}
+				return
+			}
+			if got.Value != tt.wantValue || got.Quality != tt.wantQuality || !got.Ts.Equal(tt.wantTs) {
+				t.Errorf("DecodeElement() = {%f, %02X, %s}, want {%f, %02X, %s}",
+					got.Value, got.Quality, got.Ts, tt.wantValue, tt.wantQuality, tt.wantTs)
+			}
+		})
+	}
+}
+
+func TestParseProtectionEvents(t *testing.T) {
+	ts := time.Date(2022, time.June, 15, 10, 30, 15, 500*int(time.Millisecond), time.Local)
+	cp56 := []byte{0x8c, 0x3c, 0x1e, 0x0a, 0x0f, 0x06, 0x16}
+	tests := []struct {
+		name           string
+		typeID         TypeID
+		data           []byte
+		wantValue      float64
+		wantProtection uint8
+		wantQuality    QualityDescriptor
+		wantElapsed    time.Duration
+	}{
+		{"event on", MEpTd1, append([]byte{0x02, 0xf4, 0x01}, cp56...), 2, 0, 0, 500 * time.Millisecond},
+		{"event with invalid elapsed time", MEpTd1, append([]byte{0x09, 0x00, 0x00}, cp56...), 1, 0, EI, 0},
+		{
+			"start events", MEpTe1, append([]byte{SPEGS | SPESL1 | SPESIE, 0x00, 0x10, 0x27}, cp56...),
+			0, SPEGS | SPESL1 | SPESIE, 0, 10 * time.Second,
+		},
+		{
+			"output circuit information", MEpTf1, append([]byte{OCIGC | OCICL3, 0x80, 0x2c, 0x01}, cp56...),
+			0, OCIGC | OCICL3, IV, 300 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &ASDU{}
+			data := append([]byte{byte(tt.typeID), 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x05, 0x00, 0x00}, tt.data...)
+			if err := x.Parse(data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			signal := x.Signals[0]
+			if signal.Value != tt.wantValue || signal.Protection != tt.wantProtection || signal.Quality != tt.wantQuality {
+				t.Errorf("Signals[0] = {%f, %06b, %02X}, want {%f, %06b, %02X}", signal.Value, signal.Protection,
+					signal.Quality, tt.wantValue, tt.wantProtection, tt.wantQuality)
+			}
+			if signal.Elapsed != tt.wantElapsed || !signal.Ts.Equal(ts) {
+				t.Errorf("Signals[0] = {elapsed %s, %s}, want {elapsed %s, %s}", signal.Elapsed, signal.Ts, tt.wantElapsed, ts)
+			}
+		})
+	}
+}
+
+func TestSerializeCP16Time2a(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want []byte
+	}{
+		{"zero", 0, []byte{0x00, 0x00}},
+		{"500ms", 500 * time.Millisecond, []byte{0xf4, 0x01}},
+		{"truncated to milliseconds", 1500*time.Microsecond + 300, []byte{0x01, 0x00}},
+		{"maximum", 65535 * time.Millisecond, []byte{0xff, 0xff}},
+		{"clamp overflow", time.Minute + 6*time.Second, []byte{0xff, 0xff}},
+		{"clamp negative", -time.Second, []byte{0x00, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SerializeCP16Time2a(tt.d)
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("SerializeCP16Time2a(%s) = [% X], want [% X]", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestElementLen(t *testing.T) {
+	for typeID, l := range elementLen {
+		if _, err := DecodeElement(typeID, make([]byte, l)); err != nil && !strings.Contains(err.Error(), "unsupported") {
+			t.Errorf("DecodeElement(%X, %d bytes) error = %v", uint8(typeID), l, err)
+		}
+	}
+}
+
+func TestInformationElement_FormatWithTimeTag(t *testing.T) {
+	for typeID, want := range signalElements {
+		ie, err := DecodeElement(typeID, make([]byte, elementLen[typeID]))
+		if err != nil {
+			t.Fatalf("DecodeElement(%s) error = %v", typeID, err)
+		}
+		// the time tags are in Format like the other elements
+		if fmt.Sprint(ie.Format) != fmt.Sprint(want) {
+			t.Errorf("DecodeElement(%s).Format = %v, want %v", typeID, ie.Format, want)
+		}
+	}
+}
+
+func TestInformationElement_getTruncated(t *testing.T) {
+	getters := map[string]struct {
+		get  func(ie *InformationElement)
+		size int
+	}{
+		"SIQ":        {(*InformationElement).getSIQ, 1},
+		"DIQ":        {(*InformationElement).getDIQ, 1},
+		"VTI":        {(*InformationElement).getVTI, 1},
+		"BSI":        {(*InformationElement).getBSI, 4},
+		"SCD":        {(*InformationElement).getSCD, 4},
+		"SEP":        {(*InformationElement).getSEP, 1},
+		"SPE":        {(*InformationElement).getSPE, 1},
+		"OCI":        {(*InformationElement).getOCI, 1},
+		"QDP":        {(*InformationElement).getQDP, 1},
+		"CP16Time2a": {(*InformationElement).getCP16Time2a, 2},
+		"QOI":        {(*InformationElement).getQOI, 1},
+		"QRP":        {(*InformationElement).getQRP, 1},
+		"NVA":        {(*InformationElement).getNVA, 2},
+		"SVA":        {(*InformationElement).getSVA, 2},
+		"IEEE754STD": {(*InformationElement).getIEEESTD754, 4},
+		"FBP":        {(*InformationElement).getFBP, 2},
+		"QOS":        {(*InformationElement).getQOS, 1},
+		"SCO":        {(*InformationElement).getSCO, 1},
+		"DCO":        {(*InformationElement).getDCO, 1},
+		"RCO":        {(*InformationElement).getRCO, 1},
+		"QDS":        {(*InformationElement).getQDS, 1},
+		"BCR":        {(*InformationElement).getBCR, 5},
+		"CP24Time2a": {func(ie *InformationElement) { ie.getCP24Time2a(time.Now()) }, 3},
+		"CP56Time2a": {(*InformationElement).getCP56Time2a, 7},
+	}
+	for name, getter := range getters {
+		t.Run(name, func(t *testing.T) {
+			ie := &InformationElement{data: make([]byte, getter.size-1)}
+			getter.get(ie)
+			if ie.err == nil || ie.offset != 0 || len(ie.Format) != 0 {
+				t.Errorf("get%s() of %d bytes: err = %v, offset = %d, Format = %v", name, getter.size-1, ie.err, ie.offset, ie.Format)
+			}
+
+			ie = &InformationElement{data: make([]byte, getter.size)}
+			getter.get(ie)
+			if ie.err != nil || ie.offset != getter.size {
+				t.Errorf("get%s() of %d bytes: err = %v, offset = %d", name, getter.size, ie.err, ie.offset)
+			}
+		})
+	}
+
+	// the errors of the getters fail parsing every truncated element, without recovering any panic
+	for typeID, size := range elementLen {
+		if _, err := DecodeElement(typeID, make([]byte, size)); err != nil {
+			continue // not decoded, e.g. C_TS_TA_1
+		}
+		for n := 0; n < size; n++ {
+			asdu := &ASDU{typeID: typeID, ref: time.Now()}
+			if err := asdu.parseInformationElement(make([]byte, n), &InformationElement{TypeID: typeID}); err == nil {
+				t.Errorf("parseInformationElement(%s of %d bytes) error = nil", typeID, n)
+			}
+		}
+	}
+
+	// MMeTf1 truncated in CP56Time2a
+	if _, err := DecodeElement(MMeTf1, []byte{0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00}); !IsErrElementLength(err) {
+		t.Errorf("DecodeElement() of the truncated element error = %v, want %v", err, ErrElementLength)
+	}
+}
+
+func TestQualityDescriptor(t *testing.T) {
+	tests := []struct {
+		q    QualityDescriptor
+		want string
+		is   [6]bool // IsInvalid, IsNotTopical, IsSubstituted, IsBlocked, IsOverflow, IsElapsedTimeInvalid
+	}{
+		{0, "OK", [6]bool{}},
+		{IV, "IV", [6]bool{true}},
+		{IV | NT, "IV|NT", [6]bool{true, true}},
+		{SB | BL | OV, "SB|BL|OV", [6]bool{false, false, true, true, true}},
+		{IV | EI, "IV|EI", [6]bool{true, false, false, false, false, true}},
+		{0xff, "IV|NT|SB|BL|EI|OV", [6]bool{true, true, true, true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.q.String(); got != tt.want {
+				t.Errorf("String() = %s, want %s", got, tt.want)
+			}
+			is := [6]bool{tt.q.IsInvalid(), tt.q.IsNotTopical(), tt.q.IsSubstituted(), tt.q.IsBlocked(),
+				tt.q.IsOverflow(), tt.q.IsElapsedTimeInvalid()}
+			if is != tt.is {
+				t.Errorf("Is*() = %v, want %v", is, tt.is)
+			}
+		})
+	}
+}
+
+func TestInformationElement_getCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		typeID    TypeID
+		command   byte
+		selected  bool
+		qualifier uint8
+		state     uint8
+	}{
+		{"select single command close", CScNa1, 0x81, true, 0, 1},
+		{"execute single command open of short pulse", CScNa1, 0x04, false, 1, 0},
+		{"select double command close of long pulse", CDcNa1, 0x8a, true, 2, 2},
+		{"execute double command open persistent", CDcNa1, 0x0d, false, 3, 1},
+		{"execute step command higher", CRcNa1, 0x02, false, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the command received by the controlled station: CotAct, COA=1, IOA=0x6001
+			asdu := &ASDU{}
+			if err := asdu.Parse([]byte{byte(tt.typeID), 0x01, 0x06, 0x00, 0x01, 0x00, 0x01, 0x60, 0x00, tt.command}); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			signal := asdu.Signals[0]
+			if signal.SelectExecute != tt.selected || signal.Qualifier != tt.qualifier || signal.State != tt.state {
+				t.Errorf("Signals[0] = {S/E %t, QU %d, state %d}, want {%t, %d, %d}", signal.SelectExecute,
+					signal.Qualifier, signal.State, tt.selected, tt.qualifier, tt.state)
+			}
+		})
+	}
+}
+
+func TestInformationElement_getBCR(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		value    float64
+		sequence uint8
+		carry    bool
+		adjusted bool
+		quality  QualityDescriptor
+	}{
+		{"valid", []byte{0x39, 0x30, 0x00, 0x00, 0x05}, 12345, 5, false, false, 0},
+		{"carry", []byte{0x01, 0x00, 0x00, 0x00, 0x3f}, 1, 31, true, false, 0},
+		{"adjusted and invalid", []byte{0xff, 0xff, 0xff, 0xff, 0xc0}, 4294967295, 0, false, true, IV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ie := &InformationElement{data: tt.data}
+			ie.getBCR()
+			if ie.Value != tt.value || ie.CounterSequence != tt.sequence || ie.CounterCarry != tt.carry ||
+				ie.CounterAdjusted != tt.adjusted || ie.Quality != tt.quality {
+				t.Errorf("getBCR() = %g, sequence %d, carry %t, adjusted %t, quality %s, want %g, %d, %t, %t, %s",
+					ie.Value, ie.CounterSequence, ie.CounterCarry, ie.CounterAdjusted, ie.Quality,
+					tt.value, tt.sequence, tt.carry, tt.adjusted, tt.quality)
+			}
+
+			// the descriptor is encoded back
+			ie.TypeID = MItNa1
+			if err := serializeElement(ie); err != nil {
+				t.Fatalf("serializeElement() error = %v", err)
+			}
+			if !bytes.Equal(ie.Raw, tt.data) {
+				t.Errorf("serializeElement() = [% X], want [% X]", ie.Raw, tt.data)
+			}
+		})
+	}
+}
+
+func TestInformationElement_getQDP(t *testing.T) {
+	tests := []struct {
+		name string
+		data byte
+		want ProtectionQuality
+	}{
+		{"valid", 0x00, ProtectionQuality{}},
+		{"elapsed time invalid", 0x08, ProtectionQuality{ElapsedTimeInvalid: true}},
+		{"invalid and not topical", 0xc0, ProtectionQuality{Invalid: true, NotTopical: true}},
+		{"substituted and blocked", 0x30, ProtectionQuality{Substituted: true, Blocked: true}},
+		{"reserved bits", 0x07, ProtectionQuality{}},
+		{"all bits", 0xff, ProtectionQuality{true, true, true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ie := &InformationElement{data: []byte{tt.data}}
+			ie.getQDP()
+			if ie.ProtectionQuality != tt.want {
+				t.Errorf("ProtectionQuality = %+v, want %+v", ie.ProtectionQuality, tt.want)
+			}
+			if ie.ProtectionQuality.IsValid() != (tt.want == ProtectionQuality{}) {
+				t.Errorf("IsValid() = %v", ie.ProtectionQuality.IsValid())
+			}
+			if ie.Quality != QualityDescriptor(tt.data&0xf8) {
+				t.Errorf("Quality = %s, want %s", ie.Quality, QualityDescriptor(tt.data&0xf8))
+			}
+		})
+	}
+}
+
+func TestASDU_signalFields(t *testing.T) {
+	ts := time.Date(2022, time.August, 1, 10, 30, 15, 500*int(time.Millisecond), time.Local)
+	tests := []struct {
+		name string
+		data []byte
+		want logrus.Fields
+	}{
+		{
+			"short floating point value",
+			shortFloatFrame(1),
+			logrus.Fields{"type_id": "D", "cot": CotSpont, "ioa": IOA(0x4001), "value": 230.5, "quality": "OK"},
+		},
+		{
+			"short floating point value with time tag CP56Time2a",
+			append([]byte{0x00, 0x00, 0x00, 0x00, byte(MMeTf1), 0x01, byte(CotReq), 0x00, 0x01, 0x00,
+				0x01, 0x40, 0x00, 0x00, 0x80, 0x66, 0x43, 0x80}, SerializeCP56Time2a(ts)...),
+			logrus.Fields{"type_id": "24", "cot": CotReq, "ioa": IOA(0x4001), "value": 230.5, "quality": "IV", "ts": ts},
+		},
+		{
+			"bitstring of 32 bits",
+			[]byte{0x00, 0x00, 0x00, 0x00, byte(MBoNa1), 0x01, byte(CotSpont), 0x00, 0x01, 0x00,
+				0x01, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00},
+			logrus.Fields{"type_id": "7", "cot": CotSpont, "ioa": IOA(1), "value": 0.0, "quality": "OK",
+				"bitstring": "00000000000000000000000000000101"},
+		},
+		{
+			"normalized value without quality descriptor",
+			[]byte{0x00, 0x00, 0x00, 0x00, byte(MMeNd1), 0x01, byte(CotSpont), 0x00, 0x01, 0x00,
+				0x02, 0x00, 0x00, 0x00, 0x40},
+			logrus.Fields{"type_id": "15", "cot": CotSpont, "ioa": IOA(2), "value": 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu := new(APDU)
+			if err := apdu.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := apdu.ASDU.signalFields(apdu.Signals[0])
+			if len(got) != len(tt.want) {
+				t.Errorf("signalFields() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if gv, ok := got[k].(time.Time); ok {
+					if !gv.Equal(v.(time.Time)) {
+						t.Errorf("signalFields()[%s] = %v, want %v", k, got[k], v)
+					}
+				} else if got[k] != v {
+					t.Errorf("signalFields()[%s] = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestASDU_signalMessage(t *testing.T) {
+	tests := []struct {
+		typeID TypeID
+		cot    COT
+		want   string
+	}{
+		{MSpNa1, CotPerCyc, "single point information of periodically/cyclically syncing [全遥信 - 单点遥信]"},
+		{MDpNa1, CotSpont, "double point information of spontenuous change [变化遥信 - 双点遥信]"},
+		{MSpTa1, CotSpont, "single point information with time tag CP24Time2a of spontenuous change [自发突变 - 带 24 位时标的单点遥信]"},
+		{MMeNd1, CotInrogen, "normalized value without quality descriptor response of general interrogation [总召唤响应 - 不带品质描述的归一化值遥测]"},
+		{MMeTf1, CotReq, "short floating point value with time tag CP56Time2a of request [请求 - 带 56 位时标的单精度值遥测]"},
+		{MItNa1, CotReqcogen, "integrated totals of counter interrogation [电度召唤 - 电度]"},
+		{MMeNa1, CotBack, "normalized value [归一化值遥测]"},
+	}
+	for _, tt := range tests {
+		asdu := &ASDU{typeID: tt.typeID, cot: tt.cot}
+		if got := asdu.signalMessage(); got != tt.want {
+			t.Errorf("signalMessage() of %s with COT %d = %q, want %q", tt.typeID, tt.cot, got, tt.want)
+		}
+	}
+}
+
+func TestSignalElements(t *testing.T) {
+	for typeID, elements := range signalElements {
+		if _, ok := signalNames[typeID]; !ok {
+			t.Errorf("TypeID[%X] has no name", uint8(typeID))
+		}
+		ie := &InformationElement{data: make([]byte, elementLen[typeID])}
+		for _, x := range elements {
+			ie.getElement(x, time.Now())
+		}
+		if ie.err != nil || ie.offset != elementLen[typeID] {
+			t.Errorf("TypeID[%X] gets %d bytes (error %v), want %d", uint8(typeID), ie.offset, ie.err, elementLen[typeID])
+		}
+	}
+}
+
+func TestRegisterTypeDecoder_toBeHandled(t *testing.T) {
+	// the data of TypeID 136 of special use decoded by the registered decoder is handled
+	const specialUse TypeID = 0x88
+	RegisterTypeDecoder(specialUse, func(ie *InformationElement, data []byte) error {
+		ie.Format = append(ie.Format, QDS)
+		ie.Quality = QualityDescriptor(data[0])
+		return nil
+	})
+	defer RegisterTypeDecoder(specialUse, nil)
+
+	x := &ASDU{}
+	if err := x.Parse([]byte{byte(specialUse), 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x01, 0x40, 0x00, 0x00}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !x.toBeHandled {
+		t.Error("the data decoded by the registered decoder isn't handled")
+	}
+}
@@ -1,6 +1,9 @@
 package iec104
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"fmt"
+)
 
 /*
 InformationObject . Each information object is addressed by Information Object
@@ -58,7 +61,7 @@ func (i *InformationObject) Data() []byte {
 	data := make([]byte, 0)
 	data = append(data, i.serializeIOA()...)
 	for _, ie := range i.ies {
-		data = append(data, ie.Raw...)
+		data = append(data, ie.encoded()...)
 	}
 	return data
 }
@@ -74,56 +77,160 @@ func (i *InformationObject) serializeIOA() []byte {
 	return data[:3]
 }
 
-func (i *InformationObject) parseCP24Time(data []byte) int32 {
-	if len(data) != 3 {
-		return 0
-	}
-	panic(any("implement me"))
-	return 0
+// elementLen is the length of the information elements of an information object (IOA excluded) by TypeID.
+var elementLen = map[TypeID]int{
+	MSpNa1: 1,  // SIQ
+	MSpTa1: 4,  // SIQ + CP24Time2a
+	MDpNa1: 1,  // DIQ
+	MDpTa1: 4,  // DIQ + CP24Time2a
+	MStNa1: 2,  // VTI + QDS
+	MStTa1: 5,  // VTI + QDS + CP24Time2a
+	MBoNa1: 5,  // BSI + QDS
+	MBoTa1: 8,  // BSI + QDS + CP24Time2a
+	MMeNa1: 3,  // NVA + QDS
+	MMeTa1: 6,  // NVA + QDS + CP24Time2a
+	MMeNb1: 3,  // SVA + QDS
+	MMeTb1: 6,  // SVA + QDS + CP24Time2a
+	MMeNc1: 5,  // IEEE754STD + QDS
+	MMeTc1: 8,  // IEEE754STD + QDS + CP24Time2a
+	MItNa1: 5,  // BCR
+	MItTa1: 8,  // BCR + CP24Time2a
+	MEpTa1: 6,  // SEP + CP16Time2a + CP24Time2a
+	MEpTb1: 7,  // SPE + QDP + CP16Time2a + CP24Time2a
+	MEpTc1: 7,  // OCI + QDP + CP16Time2a + CP24Time2a
+	MPsNa1: 5,  // SCD + QDS
+	MMeNd1: 2,  // NVA
+	MSpTb1: 8,  // SIQ + CP56Time2a
+	MDpTb1: 8,  // DIQ + CP56Time2a
+	MStTb1: 9,  // VTI + QDS + CP56Time2a
+	MBoTb1: 12, // BSI + QDS + CP56Time2a
+	MMeTd1: 10, // NVA + QDS + CP56Time2a
+	MMeTe1: 10, // SVA + QDS + CP56Time2a
+	MMeTf1: 12, // IEEE754STD + QDS + CP56Time2a
+	MItTb1: 12, // BCR + CP56Time2a
+	MEpTd1: 10, // SEP + CP16Time2a + CP56Time2a
+	MEpTe1: 11, // SPE + QDP + CP16Time2a + CP56Time2a
+	MEpTf1: 11, // OCI + QDP + CP16Time2a + CP56Time2a
+	CScNa1: 1,  // SCO
+	CDcNa1: 1,  // DCO
+	CRcNa1: 1,  // RCO
+	CSeNa1: 3,  // NVA + QOS
+	CSeNb1: 3,  // SVA + QOS
+	CSeNc1: 5,  // IEEE754STD + QOS
+	CBoNa1: 4,  // BSI
+	CScTa1: 8,  // SCO + CP56Time2a
+	CDcTa1: 8,  // DCO + CP56Time2a
+	CSeTa1: 10, // NVA + QOS + CP56Time2a
+	CSeTb1: 10, // SVA + QOS + CP56Time2a
+	CSeTc1: 12, // IEEE754STD + QOS + CP56Time2a
+	CIcNa1: 1,  // QOI
+	CCiNa1: 1,  // QCC
+	CRdNa1: 0,  //
+	CCsNa1: 7,  // CP56Time2a
+	CTsNb1: 2,  // FBP
+	CRpNc1: 1,  // QRP
+	CCdNa1: 2,  // CP16Time2a
+	CTsTa1: 9,  // TSC + CP56Time2a
 }
 
-func (i *InformationObject) parseCP56Time(data []byte) int64 {
-	if len(data) != 7 {
-		return 0
+// countObjects returns the number of information objects held by the body of the length, and the length of the
+// information elements of each object. The number is less than NOO if the body is too short for the declared objects.
+func (asdu *ASDU) countObjects(bodyLen int) (n, size int) {
+	n = int(asdu.nObjs)
+	if n == 0 {
+		return 0, 0
+	}
+	ioas := n // number of IOAs in the body
+	if asdu.sq {
+		ioas = 1
+	}
+	size, ok := elementLen[asdu.typeID]
+	if !ok {
+		// the length of elements of unknown types is derived from the body
+		if size = (bodyLen - ioas*IOALength) / n; size < 0 {
+			size = 0
+		}
+	}
+
+	if asdu.sq {
+		if bodyLen < IOALength {
+			return 0, size
+		}
+		if size > 0 && (bodyLen-IOALength)/size < n {
+			n = (bodyLen - IOALength) / size
+		}
+	} else if held := bodyLen / (IOALength + size); held < n {
+		n = held
 	}
-	panic(any("implement me"))
-	return 0
+	return n, size
 }
 
-func (asdu *ASDU) parseInformationObjects(asduBody []byte) {
+// parseInformationObjects decodes the information objects of the body into Signals, it fails if NOO is 0 or the body
+// doesn't hold any of the objects, and the objects are dropped if the body is malformed.
+func (asdu *ASDU) parseInformationObjects(asduBody []byte) (err error) {
 	ios := make([]*InformationObject, 0)
 	signals := make([]*InformationElement, 0)
 	defer func() {
 		asdu.ios = ios
 		asdu.Signals = signals
 	}()
+	defer func() {
+		if err != nil {
+			ios, signals = ios[:0], signals[:0]
+		}
+	}()
+
+	if asdu.nObjs == 0 {
+		return fmt.Errorf("invalid number of information objects: 0, body [% X]", asduBody)
+	}
+	n, size := asdu.countObjects(len(asduBody))
+	if n == 0 {
+		return fmt.Errorf("invalid information objects of TypeID[%X]: %d objects declared, body [% X]",
+			uint8(asdu.typeID), asdu.nObjs, asduBody)
+	}
+	if n < int(asdu.nObjs) {
+		// NOO is kept as received, only the objects held are decoded
+		asdu.logger().Warnf("ASDU declares %d information objects, but the body holds %d only: TypeID[%X], body [% X]",
+			asdu.nObjs, n, uint8(asdu.typeID), asduBody)
+	}
 
 	if asdu.sq {
 		io := &InformationObject{}
 		io.parseIOA(asduBody[:IOALength])
+		// the addresses of the sequence are increased from the IOA, the last one mustn't overflow 3 bytes
+		if last := uint64(io.ioa) + uint64(n-1); last > uint64(MaxIOA) {
+			return fmt.Errorf("invalid sequence of TypeID[%X]: %d objects from IOA %d overflow IOA %d",
+				uint8(asdu.typeID), n, io.ioa, MaxIOA)
+		}
 
-		size := (len(asduBody) - IOALength) / int(asdu.nObjs)
-		for i := 0; i < int(asdu.nObjs); i++ {
+		for i := 0; i < n; i++ {
 			ie := &InformationElement{
 				TypeID:  asdu.typeID,
 				Address: io.ioa + IOA(i),
+				Group:   asdu.cot.InterrogationGroup(),
+			}
+			if err := asdu.parseInformationElement(asduBody[IOALength+i*size:IOALength+(i+1)*size], ie); err != nil {
+				return fmt.Errorf("TypeID[%X], IOA %d: %w", uint8(asdu.typeID), ie.Address, err)
 			}
-			asdu.parseInformationElement(asduBody[IOALength+i*size:IOALength+(i+1)*size], ie)
 			io.ies = append(io.ies, ie)
 
 			signals = append(signals, ie)
 		}
+		ios = append(ios, io)
 	} else {
-		size := len(asduBody) / int(asdu.nObjs)
-		for i := 0; i < int(asdu.nObjs); i++ {
+		objLen := IOALength + size
+		for i := 0; i < n; i++ {
 			io := &InformationObject{}
-			io.parseIOA(asduBody[i*size : i*size+3])
+			io.parseIOA(asduBody[i*objLen : i*objLen+IOALength])
 			{
 				ie := &InformationElement{
 					TypeID:  asdu.typeID,
 					Address: io.ioa,
+					Group:   asdu.cot.InterrogationGroup(),
+				}
+				if err := asdu.parseInformationElement(asduBody[i*objLen+IOALength:(i+1)*objLen], ie); err != nil {
+					return fmt.Errorf("TypeID[%X], IOA %d: %w", uint8(asdu.typeID), ie.Address, err)
 				}
-				asdu.parseInformationElement(asduBody[i*size+IOALength:(i+1)*size], ie)
 				io.ies = []*InformationElement{ie}
 
 				signals = append(signals, ie)
@@ -131,10 +238,13 @@ func (asdu *ASDU) parseInformationObjects(asduBody []byte) {
 			ios = append(ios, io)
 		}
 	}
+	return nil
 }
 
 const (
 	IOALength = 3
+	// MaxIOA is the maximum IOA of 3 bytes.
+	MaxIOA IOA = 1<<(8*IOALength) - 1
 )
 
 type IOA uint32
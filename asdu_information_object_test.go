@@ -1,6 +1,8 @@
 package iec104
 
-import "testing"
+import (
+	"testing"
+)
 
 func TestInformationObject_parseIOA(t *testing.T) {
 	type args struct {
@@ -1,6 +1,12 @@
 package iec104
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
 
 var asdu = &ASDU{}
 
@@ -372,3 +378,674 @@ func TestParseCOA(t *testing.T) {
 		})
 	}
 }
+func TestInterrogationGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		cot  COT
+		want uint8
+	}{
+		{"spontaneous", CotSpont, 0},
+		{"station interrogation", CotInrogen, 0},
+		{"group 1", CotInro1, 1},
+		{"group 5", CotInro5, 5},
+		{"group 16", CotInro16, 16},
+		{"counter interrogation", CotReqcogen, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cot.InterrogationGroup(); got != tt.want {
+				t.Errorf("InterrogationGroup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+func TestParseGroupInterrogationResponse(t *testing.T) {
+	// MSpNa1, SQ=0, 2 objects, COT=CotInro5, ORG=0, COA=1
+	data := []byte{
+		0x01, 0x02, 0x19, 0x00, 0x01, 0x00,
+		0x01, 0x00, 0x00, 0x01,
+		0x02, 0x00, 0x00, 0x00,
+	}
+	x := new(ASDU)
+	if err := x.Parse(data); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(x.Signals) != 2 {
+		t.Fatalf("len(Signals) = %d, want 2", len(x.Signals))
+	}
+	for _, signal := range x.Signals {
+		if signal.Group != 5 {
+			t.Errorf("signal at %d: Group = %d, want 5", signal.Address, signal.Group)
+		}
+	}
+}
+func TestParseMMeTc1(t *testing.T) {
+	ref := time.Date(2022, time.August, 1, 10, 30, 0, 0, time.Local)
+	// MMeTc1, SQ=0, 2 objects, CotSpont, COA=1
+	data := []byte{
+		0x0e, 0x02, 0x03, 0x00, 0x01, 0x00,
+		0x64, 0x00, 0x00, 0x00, 0x80, 0x66, 0x43, 0x00, 0x39, 0x30, 0x11, // IOA 100: 230.5 at 17min 12345ms
+		0x65, 0x00, 0x00, 0x00, 0x00, 0x48, 0xc2, 0x80, 0xd0, 0x07, 0x12, // IOA 101: -50 (IV) at 18min 2000ms
+	}
+	x := &ASDU{opt: &parseOption{cp24Clock: func() time.Time { return ref }}}
+	if err := x.Parse(data); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []struct {
+		address IOA
+		value   float64
+		quality QualityDescriptor
+		ts      time.Time
+	}{
+		{100, 230.5, 0, time.Date(2022, time.August, 1, 10, 17, 12, 345*int(time.Millisecond), time.Local)},
+		{101, -50, IV, time.Date(2022, time.August, 1, 10, 18, 2, 0, time.Local)},
+	}
+	if len(x.Signals) != len(want) {
+		t.Fatalf("len(Signals) = %d, want %d", len(x.Signals), len(want))
+	}
+	for i, w := range want {
+		signal := x.Signals[i]
+		if signal.Address != w.address || signal.Value != w.value || signal.Quality != w.quality || !signal.Ts.Equal(w.ts) {
+			t.Errorf("Signals[%d] = {%d, %f, %s, %s}, want {%d, %f, %s, %s}", i,
+				signal.Address, signal.Value, signal.Quality, signal.Ts, w.address, w.value, w.quality, w.ts)
+		}
+	}
+}
+func TestASDU_DataRoundTrip(t *testing.T) {
+	siq := func(on byte) *InformationElement {
+		return &InformationElement{Format: []InformationElementType{SIQ}, Raw: []byte{on}}
+	}
+	tests := []struct {
+		name string
+		asdu *ASDU
+	}{
+		{
+			"sq=0",
+			&ASDU{typeID: MSpNa1, nObjs: 2, cot: CotSpont, org: 1, coa: 0x0102, ios: []*InformationObject{
+				{ioa: 1, ies: []*InformationElement{siq(0x01)}},
+				{ioa: 5, ies: []*InformationElement{siq(0x00)}},
+			}},
+		},
+		{
+			"sq=1",
+			&ASDU{typeID: MSpNa1, sq: true, nObjs: 3, cot: CotInrogen, coa: 1, ios: []*InformationObject{
+				{ioa: 10, ies: []*InformationElement{siq(0x01), siq(0x00), siq(0x01)}},
+			}},
+		},
+		{
+			"test",
+			&ASDU{typeID: CScNa1, nObjs: 1, t: true, cot: CotActCon, coa: 1, ios: []*InformationObject{
+				{ioa: 1, ies: []*InformationElement{{Format: []InformationElementType{SCO}, Raw: []byte{0x81}}}},
+			}},
+		},
+		{
+			"negative",
+			&ASDU{typeID: CScNa1, nObjs: 1, pn: true, cot: CotActCon, coa: 1, ios: []*InformationObject{
+				{ioa: 1, ies: []*InformationElement{{Format: []InformationElementType{SCO}, Raw: []byte{0x81}}}},
+			}},
+		},
+		{
+			"test and negative",
+			&ASDU{typeID: CScNa1, nObjs: 1, t: true, pn: true, cot: CotActCon, coa: 1, ios: []*InformationObject{
+				{ioa: 1, ies: []*InformationElement{{Format: []InformationElementType{SCO}, Raw: []byte{0x81}}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := new(ASDU)
+			if err := got.Parse(tt.asdu.Data()); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			want := tt.asdu
+			if got.typeID != want.typeID || got.sq != want.sq || got.nObjs != want.nObjs || got.t != want.t ||
+				got.pn != want.pn || got.cot != want.cot || got.org != want.org || got.coa != want.coa {
+				t.Errorf("Parse(Data()) = {%X %v %d %v %v %d %d %d}, want {%X %v %d %v %v %d %d %d}",
+					got.typeID, got.sq, got.nObjs, got.t, got.pn, got.cot, got.org, got.coa,
+					want.typeID, want.sq, want.nObjs, want.t, want.pn, want.cot, want.org, want.coa)
+			}
+			if len(got.Signals) != int(want.nObjs) {
+				t.Fatalf("len(Signals) = %d, want %d", len(got.Signals), want.nObjs)
+			}
+			i := 0
+			for _, io := range want.ios {
+				for j, ie := range io.ies {
+					address := io.ioa
+					if want.sq {
+						address += IOA(j)
+					}
+					signal := got.Signals[i]
+					if signal.Address != address || !bytes.Equal(signal.encoded(), ie.Raw) {
+						t.Errorf("Signals[%d] = {IOA %d, [% X]}, want {IOA %d, [% X]}", i, signal.Address,
+							signal.encoded(), address, ie.Raw)
+					}
+					i++
+				}
+			}
+		})
+	}
+}
+
+func TestASDU_COALength(t *testing.T) {
+	tests := []struct {
+		name   string
+		coaLen int
+		data   []byte
+		coa    COA
+	}{
+		{"2-byte COA", 2, []byte{0x01, 0x01, 0x03, 0x00, 0x34, 0x12, 0x01, 0x00, 0x00, 0x01}, 0x1234},
+		{"1-byte COA", 1, []byte{0x01, 0x01, 0x03, 0x00, 0x12, 0x01, 0x00, 0x00, 0x01}, 0x12},
+		{"1-byte global address", 1, []byte{0x01, 0x01, 0x03, 0x00, 0xff, 0x01, 0x00, 0x00, 0x01}, GlobalCOA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asdu := &ASDU{opt: &parseOption{coaLen: tt.coaLen}}
+			if err := asdu.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if asdu.coa != tt.coa {
+				t.Errorf("Parse() COA = %d, want %d", asdu.coa, tt.coa)
+			}
+			if len(asdu.Signals) != 1 || asdu.Signals[0].Address != 1 || asdu.Signals[0].Value != 1 {
+				t.Errorf("Parse() signals = %+v, want the single point 1 of IOA 1", asdu.Signals)
+			}
+			if got := asdu.Data(); !bytes.Equal(got, tt.data) {
+				t.Errorf("Data() = [% X], want [% X]", got, tt.data)
+			}
+		})
+	}
+
+	// the ASDU of 1-byte COA is too short to be parsed as 2-byte COA
+	if err := (&ASDU{}).Parse([]byte{0x64, 0x01, 0x06, 0x00, 0x01}); err == nil {
+		t.Errorf("Parse() of 5 bytes error = nil, want error")
+	}
+}
+
+func TestASDU_checkCOA(t *testing.T) {
+	tests := []struct {
+		coaLen  int
+		coa     COA
+		wantErr bool
+	}{
+		{2, 0x1234, false},
+		{2, GlobalCOA, false},
+		{1, 0x12, false},
+		{1, 0xfe, false},
+		{1, 0xff, true},
+		{1, 0x1234, true},
+		{1, GlobalCOA, false},
+	}
+	for _, tt := range tests {
+		asdu := &ASDU{coa: tt.coa, coaLen: tt.coaLen}
+		if err := asdu.checkCOA(); (err != nil) != tt.wantErr {
+			t.Errorf("checkCOA() of %d-byte COA %d error = %v, wantErr %v", tt.coaLen, tt.coa, err, tt.wantErr)
+		}
+	}
+}
+
+func TestASDU_COTLength(t *testing.T) {
+	tests := []struct {
+		name           string
+		cotLen, coaLen int
+		data           []byte
+		org            ORG
+	}{
+		{"2-byte COT", 2, 2, []byte{0x01, 0x01, 0x03, 0x05, 0x12, 0x00, 0x01, 0x00, 0x00, 0x01}, 5},
+		{"1-byte COT", 1, 2, []byte{0x01, 0x01, 0x03, 0x12, 0x00, 0x01, 0x00, 0x00, 0x01}, 0},
+		{"1-byte COT and COA", 1, 1, []byte{0x01, 0x01, 0x03, 0x12, 0x01, 0x00, 0x00, 0x01}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asdu := &ASDU{opt: &parseOption{cotLen: tt.cotLen, coaLen: tt.coaLen}}
+			if err := asdu.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if asdu.cot != CotSpont || asdu.org != tt.org || asdu.coa != 0x12 {
+				t.Errorf("Parse() COT, ORG, COA = %d, %d, %d, want %d, %d, %d", asdu.cot, asdu.org, asdu.coa,
+					CotSpont, tt.org, 0x12)
+			}
+			if len(asdu.Signals) != 1 || asdu.Signals[0].Address != 1 || asdu.Signals[0].Value != 1 {
+				t.Errorf("Parse() signals = %+v, want the single point 1 of IOA 1", asdu.Signals)
+			}
+			if got := asdu.Data(); !bytes.Equal(got, tt.data) {
+				t.Errorf("Data() = [% X], want [% X]", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestASDU_Accessors(t *testing.T) {
+	apdu := new(APDU)
+	// MMeNc1, CotSpont, ORG=2, COA=0x1234
+	if err := apdu.Parse([]byte{0x00, 0x00, 0x00, 0x00, 0x0d, 0x01, 0x03, 0x02, 0x34, 0x12,
+		0x01, 0x40, 0x00, 0x00, 0x00, 0xc0, 0x3f, 0x00}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if apdu.TypeID() != MMeNc1 || apdu.COT() != CotSpont || apdu.OriginatorAddress() != 2 || apdu.CommonAddress() != 0x1234 {
+		t.Errorf("TypeID, COT, ORG, COA = %s, %s, %d, %d, want %s, %s, 2, 4660", apdu.TypeID(), apdu.COT(),
+			apdu.OriginatorAddress(), apdu.CommonAddress(), MMeNc1, CotSpont)
+	}
+}
+
+func TestParseMSpTb1MultipleObjects(t *testing.T) {
+	// MSpTb1, SQ=0, 3 objects, CotSpont, COA=1
+	data := []byte{
+		0x1e, 0x03, 0x03, 0x00, 0x01, 0x00,
+		0x01, 0x00, 0x00, 0x01, 0x10, 0x27, 0x1e, 0x0a, 0x01, 0x08, 0x16, // IOA 1: ON at 2022-08-01 10:30:10.000
+		0x02, 0x00, 0x00, 0x00, 0xf4, 0x2e, 0x1f, 0x0a, 0x01, 0x08, 0x16, // IOA 2: OFF at 2022-08-01 10:31:12.020
+		0x00, 0x01, 0x00, 0x81, 0x00, 0x00, 0x00, 0x00, 0x02, 0x08, 0x16, // IOA 256: ON (IV) at 2022-08-02 00:00:00.000
+	}
+	x := &ASDU{}
+	if err := x.Parse(data); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []struct {
+		address IOA
+		value   float64
+		quality QualityDescriptor
+		ts      time.Time
+	}{
+		{1, 1, 0, time.Date(2022, time.August, 1, 10, 30, 10, 0, time.Local)},
+		{2, 0, 0, time.Date(2022, time.August, 1, 10, 31, 12, 20*int(time.Millisecond), time.Local)},
+		{256, 1, IV, time.Date(2022, time.August, 2, 0, 0, 0, 0, time.Local)},
+	}
+	if len(x.Signals) != len(want) {
+		t.Fatalf("len(Signals) = %d, want %d", len(x.Signals), len(want))
+	}
+	for i, w := range want {
+		signal := x.Signals[i]
+		if signal.Address != w.address || signal.Value != w.value || signal.Quality != w.quality || !signal.Ts.Equal(w.ts) {
+			t.Errorf("Signals[%d] = {%d, %f, %s, %s}, want {%d, %f, %s, %s}", i,
+				signal.Address, signal.Value, signal.Quality, signal.Ts, w.address, w.value, w.quality, w.ts)
+		}
+	}
+}
+
+func TestParseMMeTb1MultipleObjects(t *testing.T) {
+	ref := time.Date(2022, time.August, 1, 10, 5, 0, 0, time.Local)
+	// MMeTb1, SQ=0, 2 objects, CotSpont, COA=1
+	data := []byte{
+		0x0c, 0x02, 0x03, 0x00, 0x01, 0x00,
+		0x01, 0x40, 0x00, 0xe8, 0x03, 0x00, 0x10, 0x27, 0x0f, // IOA 16385: 1000 at 15min 10000ms
+		0x02, 0x40, 0x00, 0x18, 0xfc, 0x80, 0xf4, 0x2e, 0x3b, // IOA 16386: -1000 (IV) at 59min 12020ms of the previous hour
+	}
+	x := &ASDU{opt: &parseOption{cp24Clock: func() time.Time { return ref }}}
+	if err := x.Parse(data); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []struct {
+		address IOA
+		value   float64
+		quality QualityDescriptor
+		ts      time.Time
+	}{
+		{16385, 1000, 0, time.Date(2022, time.August, 1, 10, 15, 10, 0, time.Local)},
+		{16386, -1000, IV, time.Date(2022, time.August, 1, 9, 59, 12, 20*int(time.Millisecond), time.Local)},
+	}
+	if len(x.Signals) != len(want) {
+		t.Fatalf("len(Signals) = %d, want %d", len(x.Signals), len(want))
+	}
+	for i, w := range want {
+		signal := x.Signals[i]
+		if signal.Address != w.address || signal.Value != w.value || signal.Quality != w.quality || !signal.Ts.Equal(w.ts) {
+			t.Errorf("Signals[%d] = {%d, %f, %s, %s}, want {%d, %f, %s, %s}", i,
+				signal.Address, signal.Value, signal.Quality, signal.Ts, w.address, w.value, w.quality, w.ts)
+		}
+	}
+}
+
+func TestParseNOOMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantNOO NOO // NOO declared by the frame
+		want    []IOA
+	}{
+		{
+			"more objects declared than held",
+			[]byte{
+				0x01, 0x7f, 0x03, 0x00, 0x01, 0x00, // MSpNa1, SQ=0, 127 objects
+				0x01, 0x00, 0x00, 0x01,
+				0x02, 0x00, 0x00, 0x00,
+			},
+			127,
+			[]IOA{1, 2},
+		},
+		{
+			"truncated object",
+			[]byte{
+				0x0d, 0x02, 0x03, 0x00, 0x01, 0x00, // MMeNc1, SQ=0, 2 objects
+				0x01, 0x00, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x00,
+				0x02, 0x00, 0x00, 0x00, 0x00,
+			},
+			2,
+			[]IOA{1},
+		},
+		{
+			"sequence longer than held",
+			[]byte{
+				0x01, 0x85, 0x03, 0x00, 0x01, 0x00, // MSpNa1, SQ=1, 5 objects
+				0x0a, 0x00, 0x00, 0x01, 0x00, 0x01,
+			},
+			5,
+			[]IOA{10, 11, 12},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &ASDU{}
+			if err := x.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(x.Signals) != len(tt.want) {
+				t.Fatalf("len(Signals) = %d, want %d", len(x.Signals), len(tt.want))
+			}
+			// NOO is kept as received, though the objects are clamped
+			if x.nObjs != tt.wantNOO {
+				t.Errorf("nObjs = %d, want %d", x.nObjs, tt.wantNOO)
+			}
+			for i, address := range tt.want {
+				if x.Signals[i].Address != address {
+					t.Errorf("Signals[%d].Address = %d, want %d", i, x.Signals[i].Address, address)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSequenceObject(t *testing.T) {
+	// MSpNa1, SQ=1, 3 objects from IOA 10
+	data := []byte{0x01, 0x83, 0x03, 0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x01, 0x00, 0x01}
+	x := &ASDU{}
+	if err := x.Parse(data); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	// the sequence is a single information object holding the elements of all addresses
+	if len(x.ios) != 1 {
+		t.Fatalf("len(ios) = %d, want 1", len(x.ios))
+	}
+	if io := x.ios[0]; io.ioa != 10 || len(io.ies) != 3 {
+		t.Fatalf("ios[0] = {IOA %d, %d elements}, want {IOA 10, 3 elements}", io.ioa, len(io.ies))
+	}
+	for i, ie := range x.ios[0].ies {
+		if ie != x.Signals[i] {
+			t.Errorf("ios[0].ies[%d] isn't Signals[%d]", i, i)
+		}
+	}
+}
+
+func TestParseMalformedObjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"no object declared", []byte{0x01, 0x00, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}, true},
+		{"no object declared in sequence", []byte{0x01, 0x80, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}, true},
+		{"empty body", []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00}, true},
+		{"truncated IOA", []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00}, true},
+		{"truncated element", []byte{0x0d, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x80}, true},
+		{"truncated sequence", []byte{0x0d, 0xff, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x80}, true},
+		{"overflowed sequence", []byte{0x01, 0x83, 0x03, 0x00, 0x01, 0x00, 0xfe, 0xff, 0xff, 0x01, 0x00, 0x01}, true},
+		{"sequence ending at the max IOA", []byte{0x01, 0x82, 0x03, 0x00, 0x01, 0x00, 0xfe, 0xff, 0xff, 0x01, 0x00}, false},
+		{"unsupported type", []byte{0x7f, 0x7f, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0xaa}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &ASDU{}
+			if err := x.Parse(tt.data); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// the objects truncated before the first one is complete fail to parse
+	for typeID, size := range elementLen {
+		if size == 0 {
+			continue
+		}
+		data := append([]byte{byte(typeID), 0x01, 0x03, 0x00, 0x01, 0x00}, make([]byte, IOALength+size)...)
+		for n := (&ASDU{}).headerLen(); n < len(data); n++ {
+			x := &ASDU{}
+			if err := x.Parse(data[:n]); err == nil {
+				t.Errorf("Parse(%s truncated to %d bytes) error = nil", typeID, n)
+			}
+		}
+	}
+
+	// garbage bodies of every TypeID never panic
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		headerLen := (&ASDU{}).headerLen()
+		data := make([]byte, headerLen+r.Intn(MaxApduLen-ApduHeaderLen-headerLen+1))
+		r.Read(data)
+		data[0] = byte(i % 128)
+		x := &ASDU{}
+		_ = x.Parse(data)
+		if len(x.Signals) > int(x.nObjs) {
+			t.Fatalf("Parse([% X]) decodes %d signals, more than NOO %d", data, len(x.Signals), x.nObjs)
+		}
+	}
+}
+
+func TestASDU_parseSingleCommandConfirmation(t *testing.T) {
+	tests := []struct {
+		name  string
+		sco   byte
+		cot   COT
+		phase CommandPhase
+		state uint8
+		err   error
+	}{
+		{"select open", 0x80, CotActCon, CommandPhaseSelect, 0, nil},
+		{"execute close", 0x01, CotActCon, CommandPhaseExecute, 1, nil},
+		{"execute close of short pulse", 0x05, CotActCon, CommandPhaseExecute, 1, nil},
+		{"select close of persistent output", 0x8d, CotActCon, CommandPhaseSelect, 1, nil},
+		{"termination", 0x01, CotActTerm, CommandPhaseTerm, 1, ErrSingleCmdTerm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu := new(APDU)
+			data := []byte{0x00, 0x00, 0x00, 0x00, byte(CScNa1), 0x01, byte(tt.cot), 0x00, 0x01, 0x00,
+				0x01, 0x60, 0x00, tt.sco}
+			if err := apdu.Parse(data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			rsp := apdu.ASDU.cmdRsp
+			if rsp == nil {
+				t.Fatal("cmdRsp = nil")
+			}
+			if rsp.phase != tt.phase || rsp.state != tt.state || !errors.Is(rsp.err, tt.err) {
+				t.Errorf("cmdRsp = {%v, %d, %v}, want {%v, %d, %v}", rsp.phase, rsp.state, rsp.err, tt.phase, tt.state, tt.err)
+			}
+		})
+	}
+}
+
+func TestASDU_parseInterrogationConfirmation(t *testing.T) {
+	tests := []struct {
+		name   string
+		typeID TypeID
+		cot    COT
+		q      byte // QOI or QCC
+	}{
+		{"confirmation of general interrogation", CIcNa1, CotActCon, QOIStation},
+		{"termination of group interrogation", CIcNa1, CotActTerm, QOIGroup3},
+		{"confirmation of counter interrogation", CCiNa1, CotActCon, 0x45},
+		{"termination of counter interrogation", CCiNa1, CotActTerm, 0x82},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte{byte(tt.typeID), 0x01, byte(tt.cot), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, tt.q}
+			// they are only logged by default
+			for _, enabled := range []bool{false, true} {
+				x := &ASDU{opt: &parseOption{interrogationConfirmations: enabled}}
+				if err := x.Parse(data); err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if x.toBeHandled != enabled {
+					t.Errorf("toBeHandled = %v with the confirmations enabled %v", x.toBeHandled, enabled)
+				}
+			}
+		})
+	}
+}
+
+func TestASDU_parseCommandTermination(t *testing.T) {
+	tests := []struct {
+		name   string
+		typeID TypeID
+		co     byte // SCO, DCO or RCO
+		want   error
+		other  []error // the terminations of the other commands
+	}{
+		{"single command", CScNa1, 0x01, ErrSingleCmdTerm, []error{ErrDoubleCmdTerm, ErrStepCmdTerm}},
+		{"double command", CDcNa1, 0x02, ErrDoubleCmdTerm, []error{ErrSingleCmdTerm, ErrStepCmdTerm}},
+		{"regulating step command", CRcNa1, 0x02, ErrStepCmdTerm, []error{ErrSingleCmdTerm, ErrDoubleCmdTerm}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu := new(APDU)
+			data := []byte{0x00, 0x00, 0x00, 0x00, byte(tt.typeID), 0x01, byte(CotActTerm), 0x00, 0x01, 0x00,
+				0x01, 0x60, 0x00, tt.co}
+			if err := apdu.Parse(data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			rsp := apdu.ASDU.cmdRsp
+			if rsp == nil {
+				t.Fatal("cmdRsp = nil")
+			}
+			if !errors.Is(rsp.err, tt.want) {
+				t.Errorf("cmdRsp.err = %v, want %v", rsp.err, tt.want)
+			}
+			for _, other := range tt.other {
+				if errors.Is(rsp.err, other) {
+					t.Errorf("cmdRsp.err = %v, mustn't match %v", rsp.err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestASDU_DataReserializesParsed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			"normalized values (SQ=1)",
+			[]byte{0x09, 0x83, 0x14, 0x00, 0x01, 0x00, 0x01, 0x40, 0x00,
+				0x00, 0x40, 0x00, 0xff, 0x7f, 0x80, 0x00, 0x80, 0x00},
+		},
+		{
+			"bitstrings (SQ=1)",
+			[]byte{0x07, 0x82, 0x03, 0x00, 0x01, 0x00, 0x10, 0x00, 0x00,
+				0x01, 0x02, 0x03, 0x04, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x40},
+		},
+		{
+			"single points at the max IOA (SQ=1)",
+			[]byte{0x01, 0x82, 0x14, 0x00, 0x01, 0x00, 0xfe, 0xff, 0xff, 0x01, 0x80},
+		},
+		{
+			"short floating points with time tag CP56Time2a (SQ=0)",
+			[]byte{0x24, 0x02, 0x03, 0x00, 0x01, 0x00,
+				0x01, 0x40, 0x00, 0x00, 0x00, 0xc0, 0x3f, 0x00, 0x10, 0x27, 0x0f, 0x0a, 0x21, 0x08, 0x16,
+				0x05, 0x40, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x80, 0x20, 0x4e, 0x0f, 0x0a, 0x21, 0x08, 0x16},
+		},
+		{
+			"unsupported type (SQ=1)",
+			[]byte{0x7f, 0x82, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0xaa, 0xbb},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := &ASDU{}
+			if err := x.Parse(tt.data); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := x.Data(); !bytes.Equal(got, tt.data) {
+				t.Errorf("Data() = [% X], want [% X]", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestTypeID_String(t *testing.T) {
+	tests := []struct {
+		typeID TypeID
+		want   string
+	}{
+		{MSpNa1, "M_SP_NA_1"},
+		{MMeTf1, "M_ME_TF_1"},
+		{CSeTc1, "C_SE_TC_1"},
+		{CIcNa1, "C_IC_NA_1"},
+		{0, "TypeID(0)"},
+		{200, "TypeID(200)"},
+	}
+	for _, tt := range tests {
+		if got := tt.typeID.String(); got != tt.want {
+			t.Errorf("TypeID(%d).String() = %q, want %q", uint8(tt.typeID), got, tt.want)
+		}
+	}
+}
+
+func TestCOT_String(t *testing.T) {
+	tests := []struct {
+		cot  COT
+		want string
+	}{
+		{CotSpont, "spont"},
+		{CotActCon, "actcon"},
+		{CotInrogen, "inrogen"},
+		{CotInro16, "inro16"},
+		{CotReqco4, "reqco4"},
+		{CotUnknownObjectAddress, "unknown object address"},
+		{0, "COT(0)"},
+		{50, "COT(50)"},
+	}
+	for _, tt := range tests {
+		if got := tt.cot.String(); got != tt.want {
+			t.Errorf("COT(%d).String() = %q, want %q", uint8(tt.cot), got, tt.want)
+		}
+	}
+}
+
+func TestASDU_StringOfBuilt(t *testing.T) {
+	ts := time.Date(2022, 8, 1, 10, 20, 30, 0, time.UTC)
+	command := &ASDU{typeID: CScNa1, nObjs: 1, cot: CotAct, coa: 1, ios: []*InformationObject{
+		{ioa: 0x6001, ies: []*InformationElement{{Format: []InformationElementType{SCO}, Raw: []byte{0x85}}}},
+	}}
+	built, err := NewASDU(MMeTf1, CotSpont).SetCommonAddress(1).
+		AddElement(&InformationElement{Address: 0x4001, Value: 1.5, Ts: ts}).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		asdu *ASDU
+		want string
+	}{
+		{
+			"single command",
+			command,
+			"C_SC_NA_1 SQ=0 NOO=1 COT=act(6) ORG=0 COA=1\n  IOA=24577 value=133 select=true qualifier=1 state=1",
+		},
+		{
+			"short floating point",
+			built,
+			"M_ME_TF_1 SQ=0 NOO=1 COT=spont(3) ORG=0 COA=1\n  IOA=16385 value=1.5 quality=OK ts=" +
+				ts.In(time.Local).Format(time.RFC3339Nano),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.asdu.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,83 +3,383 @@ package iec104
 import (
 	"context"
 	"crypto/tls"
-	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 func NewClient(option *ClientOption) *Client {
-	return &Client{
+	c := &Client{
 		ClientOption: option,
 
-		org: ORG(0),
-		coa: COA(0x0001),
-
-		sendChan:   make(chan []byte, 1),
-		recvChan:   make(chan *APDU),
-		dataChan:   make(chan *APDU),
-		cmdRspChan: make(chan *cmdRsp, 0),
+		sendChan:     make(chan []byte, 1),
+		recvChan:     make(chan *APDU),
+		dataChan:     make(chan *APDU, option.dataBufferSize),
+		activityChan: make(chan struct{}, 1),
+		testFCChan:   make(chan struct{}, 1),
+		closed:       make(chan struct{}),
 	}
+	c.windowCond = sync.NewCond(&c.windowMu)
+	return c
 }
 
 // Client in IEC 104 is also called as master or controlling station.
 type Client struct {
 	*ClientOption
-	conn net.Conn // network channel with the iec104 substation/server
 
+	connMu     sync.RWMutex    // guards parent to closed, which are replaced when reconnecting
+	parent     context.Context // context passed to ConnectContext, from which the context of each connection derives
+	conn       net.Conn        // network channel with the iec104 substation/server
+	connClosed bool            // conn is closed, by losing it or Close
+	ctx        context.Context // done when the connection is closed
 	cancel     context.CancelFunc
-	sendChan   chan []byte // send data to server
-	recvChan   chan *APDU  // receive apdu from server
-	dataChan   chan *APDU  // make Client owner to handle data received from server by themselves
-	cmdRspChan chan *cmdRsp
+	closed     chan struct{}  // closed by Close to stop reconnecting
+	wg         sync.WaitGroup // goroutines serving the connection
+
+	dialContext func(ctx context.Context, network, address string) (net.Conn, error) // dials tcp, net.Dialer if nil
+
+	sendChan chan []byte // send data to server
+	recvChan chan *APDU  // receive apdu from server
+	dataChan chan *APDU  // make Client owner to handle data received from server by themselves
+
+	activityChan chan struct{} // notified when data is sent or received
+	testFCChan   chan struct{} // notified when TESTFR con is received
 
-	org      ORG    // originator address to identify controlling station when there are multiple controlling stations
-	coa      COA    // common address (or station address)
 	ssn, rsn uint16 // send sequence number, receive sequence number
-	ifn      uint16 // i-format frame number (for send S-frame data regularity)
+	ifn      uint16 // i-format frame number received but not acknowledged (S-frame is sent when it reaches w)
+
+	sendMu     sync.Mutex  // serializes SendIFrame, so that the I-format frames are numbered and queued in order
+	cmdMu      sync.Mutex  // serializes the commands, each holds it from sending to the last confirmation
+	windowMu   sync.Mutex  // guards ssn, rsn, ifn, ackSsn and unacked
+	windowCond *sync.Cond  // broadcast when the acknowledged send sequence number advances or the connection is closed
+	ackSsn     uint16      // send sequence number acknowledged by the server with its receive sequence number
+	unacked    *sendBuffer // I-format frames sent but not acknowledged, from ackSsn to ssn
+
+	status              int32 // statusInitial, statusConnected, statusDisconnected or statusClosed
+	lastDataAt          int64 // unix nanoseconds when the last I-format frame is sent or received
+	dataTransferStarted int32 // 1 after STARTDT con is received, 0 after STOPDT con is received
+
+	stats stats
+	cp56  cp56Clock
+
+	readsMu sync.Mutex
+	reads   map[IOA][]chan *InformationElement // pending reads waiting for the response with COT CotReq
 
-	status int32 // initial, connected, disconnected
+	cmdsMu sync.Mutex
+	cmds   map[cmdKey][]chan *cmdRsp // pending commands waiting for the confirmations of their TypeID and IOA
+
+	signalsMu sync.RWMutex
+	signals   chan *InformationElement // monitor signals streamed to Signals, nil until Signals is called
+
+	scalingsMu sync.RWMutex
+	scalings   map[IOA]scaling // scalings of the normalized and scaled values by address
+
+	builtMu   sync.Mutex
+	lastBuilt []byte // last frame built in the dry-run mode
 }
 
+const (
+	statusInitial int32 = iota
+	statusConnected
+	statusDisconnected // the connection is lost, and the client is reconnecting by AutoReconnectRule
+	statusClosed       // the client is closed by Close
+)
+
+// Connect establishes the connection and starts the data transfer by STARTDT. When the connection is lost later,
+// the client reconnects by AutoReconnectRule until Close is called. It's ConnectContext with context.Background.
 func (c *Client) Connect() error {
-	if err := c.dial(); err != nil {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext is Connect bound to the context. Dialing and STARTDT are cancelled when ctx is done, and the
+// goroutines serving the connection and the reconnections later derive from ctx, so cancelling ctx after the client
+// is connected closes it like Close.
+func (c *Client) ConnectContext(ctx context.Context) error {
+	c.connMu.Lock()
+	if atomic.CompareAndSwapInt32(&c.status, statusClosed, statusInitial) {
+		c.closed = make(chan struct{})
+	}
+	c.parent = ctx
+	closed := c.closed
+	c.connMu.Unlock()
+
+	// the common address of the station must fit in the length of COA
+	if err := (&ASDU{coa: c.coa, coaLen: c.coaLen}).checkCOA(); err != nil {
+		return err
+	}
+	if err := c.connect(); err != nil {
+		return err
+	}
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = c.Close()
+			case <-closed:
+			}
+		}()
+	}
+	return nil
+}
+func (c *Client) connect() error {
+	// wait for the goroutines serving the lost connection
+	c.wg.Wait()
+
+	c.connMu.RLock()
+	parent := c.parent
+	c.connMu.RUnlock()
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	conn, err := c.dial(parent)
+	if err != nil {
 		return err
 	}
 
 	// After the establishment of a TCP connection, send and receive sequence number should be set to zero.
-	c.ssn, c.rsn = 0, 0
+	c.windowMu.Lock()
+	c.ssn, c.rsn, c.ifn, c.ackSsn = 0, 0, 0, 0
+	c.unacked.reset()
+	c.unacked = newSendBuffer(c.k)
+	// the senders waiting for the acknowledgements of the lost connection aren't blocked by its window any more
+	c.windowCond.Broadcast()
+	c.windowMu.Unlock()
+	c.drainChans()
+
+	ctx, cancel := context.WithCancel(parent)
+	c.connMu.Lock()
+	c.conn, c.connClosed, c.ctx, c.cancel = conn, false, ctx, cancel
+	c.connMu.Unlock()
+	c.touchData()
+	serves := []func(context.Context){c.writingToSocket, c.readingFromSocket, c.handlingData, c.testingConnection}
+	if c.maxIdle > 0 {
+		serves = append(serves, c.closingIdle)
+	}
+	for _, serve := range serves {
+		c.wg.Add(1)
+		go func(serve func(context.Context)) {
+			defer c.wg.Done()
+			serve(ctx)
+		}(serve)
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	c.cancel = cancel
-	go c.writingToSocket(ctx)
-	go c.readingFromSocket(ctx)
-	go c.handlingData(ctx)
+	if err := c.StartDataTransfer(parent); err != nil {
+		c.closeConn()
+		return err
+	}
+	for {
+		status := atomic.LoadInt32(&c.status)
+		if status == statusClosed {
+			c.closeConn()
+			return errConnectionClosed{}
+		}
+		if atomic.CompareAndSwapInt32(&c.status, status, statusConnected) {
+			break
+		}
+	}
+	c.stats.recordConnected(time.Now())
 
 	c.onConnectHandler(c)
+	// the connection may be lost before the client is connected, which isn't handled by lost
+	if ctx.Err() != nil && parent.Err() == nil &&
+		atomic.CompareAndSwapInt32(&c.status, statusConnected, statusDisconnected) {
+		go c.reconnect()
+	}
 	return nil
 }
-func (c *Client) dial() (err error) {
-	schema, address, timeout := c.server.Scheme, c.server.Host, c.connectTimeout
-	var conn net.Conn
+
+// drainChans drops the frames and notifications left by the lost connection.
+func (c *Client) drainChans() {
+	for {
+		select {
+		case <-c.sendChan:
+		case <-c.testFCChan:
+		case <-c.activityChan:
+		default:
+			return
+		}
+	}
+}
+
+// lost closes the connection lost by the reason, notifies the OnErrorHandler, and reconnects in background if the
+// client was connected.
+func (c *Client) lost(reason error) {
+	c.connMu.Lock()
+	conn, ctx := c.conn, c.ctx
+	if ctx.Err() != nil {
+		// the connection is closed already
+		c.connMu.Unlock()
+		return
+	}
+	c.cancel()
+	c.connClosed = true
+	c.connMu.Unlock()
+	c.wakeSenders()
+
+	c.logger().Errorf("%v, close the connection", reason)
+	_ = conn.Close()
+	c.stats.recordError()
+	if IsErrT1Timeout(reason) {
+		c.stats.recordTimeout()
+	}
+	if c.onErrorHandler != nil {
+		c.onErrorHandler(c, reason)
+	}
+	if atomic.CompareAndSwapInt32(&c.status, statusConnected, statusDisconnected) {
+		go c.reconnect()
+	}
+}
+
+// reconnect reconnects by AutoReconnectRule until it succeeds, the retries are used up or the client is closed.
+func (c *Client) reconnect() {
+	c.onDisconnectHandler(c)
+
+	c.connMu.RLock()
+	closed := c.closed
+	c.connMu.RUnlock()
+
+	rule := c.autoReconnectRule
+	for attempt := 1; rule.retries == ReconnectInfinitely || attempt <= rule.retries; attempt++ {
+		timer := time.NewTimer(rule.interval)
+		select {
+		case <-closed:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		c.logger().Infof("reconnect to %s, attempt %d", c.server.Host, attempt)
+		err := c.connect()
+		if err == nil {
+			return
+		}
+		if IsErrConnectionClosed(err) {
+			return
+		}
+		c.logger().Warnf("reconnect to %s: %v", c.server.Host, err)
+	}
+	c.logger().Errorf("give up reconnecting to %s after %d attempts", c.server.Host, rule.retries)
+}
+
+// logger returns the logger set by ClientOption.SetLogger, or the logger of the package.
+func (c *Client) logger() Logger {
+	if c.lg != nil {
+		return c.lg
+	}
+	return pkgLogger()
+}
+
+// closeConn cancels the context of the current connection and closes it, it returns nil if it's closed already.
+func (c *Client) closeConn() error {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.cancel == nil || c.connClosed {
+		return nil
+	}
+	c.cancel()
+	c.connClosed = true
+	c.wakeSenders()
+	return c.conn.Close()
+}
+
+// remoteAddr returns the remote address of the current connection, it's read under connMu since the connection is
+// replaced when reconnecting.
+func (c *Client) remoteAddr() net.Addr {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.RemoteAddr()
+}
+
+// connCtx returns the context of the current connection, which is done when the connection is closed.
+func (c *Client) connCtx() context.Context {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.ctx
+}
+
+// dial dials the server within the connect timeout, it's cancelled when ctx is done.
+func (c *Client) dial(ctx context.Context) (conn net.Conn, err error) {
+	schema, address := c.server.Scheme, c.server.Host
+	dialer := &net.Dialer{Timeout: c.connectTimeout}
+	dialContext := dialer.DialContext
+	if c.dialContext != nil {
+		dialContext = c.dialContext
+	}
 	switch schema {
 	case "tcp":
-		conn, err = net.DialTimeout("tcp", address, timeout)
+		conn, err = dialContext(ctx, "tcp", address)
 	case "ssl", "tls", "tcps":
-		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, c.tc)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.tc}).DialContext(ctx, "tcp", address)
 	default:
-		return fmt.Errorf("unknown schema: %s", schema)
+		return nil, fmt.Errorf("unknown schema: %s", schema)
 	}
-	if err != nil {
+	return
+}
+
+// StartDataTransfer sends STARTDT act and waits for STARTDT con within t1 or until the context is done.
+// The data transfer is started automatically by Connect, it's used to restart it after StopDataTransfer.
+func (c *Client) StartDataTransfer(ctx context.Context) error {
+	if err := c.transferData(ctx, UFrameFunctionStartDTA, UFrameFunctionStartDTC, "STARTDT"); err != nil {
 		return err
 	}
-	c.conn = conn
-	return
+	atomic.StoreInt32(&c.dataTransferStarted, 1)
+	return nil
+}
+
+// StopDataTransfer sends STOPDT act and waits for STOPDT con within t1 or until the context is done.
+func (c *Client) StopDataTransfer(ctx context.Context) error {
+	if err := c.transferData(ctx, UFrameFunctionStopDTA, UFrameFunctionStopDTC, "STOPDT"); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&c.dataTransferStarted, 0)
+	return nil
+}
+
+// IsDataTransferStarted reports whether the data transfer is started by STARTDT and not stopped by STOPDT.
+func (c *Client) IsDataTransferStarted() bool {
+	return atomic.LoadInt32(&c.dataTransferStarted) == 1
+}
+
+// transferData sends the act of STARTDT or STOPDT, and waits for the con.
+func (c *Client) transferData(ctx context.Context, act, con UFrameFunction, frame string) error {
+	connCtx := c.connCtx()
+	if connCtx == nil || connCtx.Err() != nil {
+		return errConnectionClosed{}
+	}
+	c.sendUFrame(act)
+
+	timer := time.NewTimer(c.t1)
+	defer timer.Stop()
+	for {
+		select {
+		case apdu := <-c.recvChan:
+			if uFrame, ok := apdu.frame.(*UFrame); ok && uFrame.Cmd[0] == con[0] {
+				return nil
+			}
+			c.logger().Warnf("receive unexpected frame while waiting for %s con: [% X]", frame, apdu.frame.Data())
+		case <-timer.C:
+			c.stats.recordTimeout()
+			return errT1Timeout{frame: frame}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-connCtx.Done():
+			return errConnectionClosed{}
+		}
+	}
 }
 
 func (c *Client) writingToSocket(ctx context.Context) {
-	_lg.Info("start goroutine for writing to socket")
+	c.logger().Infof("start goroutine for writing to socket")
 	defer func() {
-		_lg.Info("stop goroutine for writing to socket")
+		c.logger().Infof("stop goroutine for writing to socket")
 	}()
 
 	for {
@@ -87,16 +387,23 @@ func (c *Client) writingToSocket(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case data := <-c.sendChan:
+			_ = c.conn.SetWriteDeadline(c.writeDeadline())
 			if _, err := c.conn.Write(data); err != nil {
-				_lg.Errorf("write to socket: %s", err.Error())
+				if ctx.Err() != nil {
+					// the connection is closed by ourselves
+					return
+				}
+				c.lost(fmt.Errorf("write to socket: %w", err))
+				return
 			}
+			c.notifyActivity()
 		}
 	}
 }
 func (c *Client) readingFromSocket(ctx context.Context) {
-	_lg.Info("start goroutine for reading from socket")
+	c.logger().Infof("start goroutine for reading from socket")
 	defer func() {
-		_lg.Info("stop goroutine for reading from socket")
+		c.logger().Infof("stop goroutine for reading from socket")
 	}()
 
 	for {
@@ -106,8 +413,14 @@ func (c *Client) readingFromSocket(ctx context.Context) {
 		default:
 			apdu, err := c.readFromSocket(ctx)
 			if err != nil {
-				panic(any(fmt.Errorf("read from socket: %v", err)))
+				if ctx.Err() != nil {
+					// the connection is closed by ourselves
+					return
+				}
+				c.lost(fmt.Errorf("read from socket: %w", err))
+				return
 			}
+			c.notifyActivity()
 
 			switch apdu.frame.Type() {
 			case FrameTypeU:
@@ -115,45 +428,141 @@ func (c *Client) readingFromSocket(ctx context.Context) {
 				if ok {
 					switch uFrame.Cmd[0] {
 					case UFrameFunctionStartDTA[0]:
-						_lg.Debugf("receive u frame: StartDTA")
+						c.logger().Debugf("receive u frame: StartDTA")
 					case UFrameFunctionStartDTC[0]:
-						_lg.Debugf("receive u frame: StartDTC")
-						c.recvChan <- apdu
+						c.logger().Debugf("receive u frame: StartDTC")
+						select {
+						case c.recvChan <- apdu:
+						case <-ctx.Done():
+						}
 					case UFrameFunctionStopDTA[0]:
-						_lg.Debugf("receive u frame: StopDTA")
+						c.logger().Debugf("receive u frame: StopDTA")
 					case UFrameFunctionStopDTC[0]:
-						_lg.Debugf("receive u frame: StopDTC")
-						c.recvChan <- apdu
+						c.logger().Debugf("receive u frame: StopDTC")
+						select {
+						case c.recvChan <- apdu:
+						case <-ctx.Done():
+						}
 					case UFrameFunctionTestFA[0]:
-						_lg.Debugf("receive u frame: TestFA")
+						c.logger().Debugf("receive u frame: TestFA")
 						c.sendUFrame(UFrameFunctionTestFC)
 					case UFrameFunctionTestFC[0]:
-						_lg.Debugf("receive u frame: TestFC")
-						c.sendUFrame(UFrameFunctionTestFC)
+						c.logger().Debugf("receive u frame: TestFC")
+						select {
+						case c.testFCChan <- struct{}{}:
+						default:
+						}
 					}
 				}
 			}
 		}
 	}
 }
+
+// readFromSocket reads the next frame within the read timeout, the deadline is reset for each frame.
 func (c *Client) readFromSocket(ctx context.Context) (*APDU, error) {
+	_ = c.conn.SetReadDeadline(c.readDeadline())
 	apduLen, err := c.readApduHeader()
 	if err != nil {
 		return nil, err
 	}
-	// c.conn.SetDeadline(time.Now().Add(c.timeout))
 
-	apdu, err := c.readApduBody(apduLen)
+	apdu, err := c.readApduBody(ctx, apduLen)
 	if err != nil {
 		return nil, err
 	}
 	return apdu, nil
 }
 
+// testingConnection sends TESTFR act if there is no data sent or received in t3, and closes the connection if
+// TESTFR con isn't received in t1.
+func (c *Client) testingConnection(ctx context.Context) {
+	c.logger().Infof("start goroutine for testing connection")
+	defer func() {
+		c.logger().Infof("stop goroutine for testing connection")
+	}()
+
+	idle := time.NewTimer(c.t3)
+	defer idle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.activityChan:
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(c.t3)
+		case <-idle.C:
+			select {
+			case <-c.testFCChan: // drop the stale confirmation
+			default:
+			}
+			c.sendUFrame(UFrameFunctionTestFA)
+
+			confirm := time.NewTimer(c.t1)
+			select {
+			case <-ctx.Done():
+				confirm.Stop()
+				return
+			case <-c.testFCChan:
+				confirm.Stop()
+			case <-confirm.C:
+				c.lost(errT1Timeout{frame: "TESTFR"})
+				return
+			}
+			idle.Reset(c.t3)
+		}
+	}
+}
+
+// touchData records the time when an I-format frame is sent or received.
+func (c *Client) touchData() {
+	atomic.StoreInt64(&c.lastDataAt, time.Now().UnixNano())
+}
+
+// closingIdle closes the client if no I-format frame is sent or received in maxIdle.
+func (c *Client) closingIdle(ctx context.Context) {
+	c.logger().Infof("start goroutine for closing idle connection")
+	defer func() {
+		c.logger().Infof("stop goroutine for closing idle connection")
+	}()
+
+	timer := time.NewTimer(c.maxIdle)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			idle := time.Since(time.Unix(0, atomic.LoadInt64(&c.lastDataAt)))
+			if idle < c.maxIdle {
+				timer.Reset(c.maxIdle - idle)
+				continue
+			}
+			c.logger().Infof("close the connection idle for %s", idle)
+			if err := c.Close(); err != nil {
+				c.logger().Warnf("close the idle connection: %v", err)
+			}
+			return
+		}
+	}
+}
+
+func (c *Client) notifyActivity() {
+	select {
+	case c.activityChan <- struct{}{}:
+	default:
+	}
+}
+
 func (c *Client) handlingData(ctx context.Context) {
-	_lg.Info("start goroutine for handling data received from server")
+	c.logger().Infof("start goroutine for handling data received from server")
 	defer func() {
-		_lg.Info("stop goroutine for handling data received from server")
+		c.logger().Infof("stop goroutine for handling data received from server")
 	}()
 
 	for {
@@ -162,7 +571,7 @@ func (c *Client) handlingData(ctx context.Context) {
 			return
 		case apdu := <-c.dataChan:
 			if err := c.handleData(apdu); err != nil {
-				_lg.Warnf("handle iFrame, got: %v", err)
+				c.logger().Warnf("handle iFrame, got: %v", err)
 			}
 		}
 	}
@@ -170,29 +579,99 @@ func (c *Client) handlingData(ctx context.Context) {
 func (c *Client) handleData(apdu *APDU) error {
 	defer func() {
 		if err := recover(); err != nil {
-			_lg.Errorf("client handler: %+v", err)
+			c.logger().Errorf("client handler: %+v", err)
 		}
 	}()
 
-	_lg.Debugf("handle iFrame: TypeID: %X, COT: %X", apdu.ASDU.typeID, apdu.ASDU.cot)
+	c.logger().Debugf("handle iFrame: TypeID: %X, COT: %X", uint8(apdu.ASDU.typeID), uint8(apdu.ASDU.cot))
+
+	if answerer, ok := c.handler.(InterrogationAnswerer); ok && apdu.typeID == CIcNa1 && apdu.cot == CotAct {
+		if err := c.answerInterrogation(answerer, apdu); err != nil {
+			return err
+		}
+	}
+	if c.signalFunc != nil && apdu.typeID < CScNa1 {
+		for _, signal := range apdu.Signals {
+			c.signalFunc(signal)
+		}
+	}
+	if apdu.typeID < CScNa1 {
+		c.streamSignals(apdu.Signals)
+	}
+	if c.handler == nil {
+		return nil
+	}
+	return handleClientData(c.handler, apdu)
+}
+
+// DefaultSignalBufferSize is the number of signals buffered by the channel returned by Client.Signals.
+const DefaultSignalBufferSize = 1024
+
+// Signals returns the channel streaming the signals decoded from the data in monitor direction (TypeID 1-44), which
+// is the pull model alternative to ClientHandler, and the data is still handled by the ClientHandler if it's set.
+// Signals are streamed since the first call, the same channel is returned by the later calls. The channel buffers
+// DefaultSignalBufferSize signals, the signals received while it's full are dropped rather than blocking the
+// handling of data, so the caller should keep receiving from it. It's never closed.
+func (c *Client) Signals() <-chan *InformationElement {
+	c.signalsMu.Lock()
+	defer c.signalsMu.Unlock()
+
+	if c.signals == nil {
+		c.signals = make(chan *InformationElement, DefaultSignalBufferSize)
+	}
+	return c.signals
+}
+
+// streamSignals passes the signals to the channel returned by Signals, and drops them if it's full.
+func (c *Client) streamSignals(signals []*InformationElement) {
+	c.signalsMu.RLock()
+	ch := c.signals
+	c.signalsMu.RUnlock()
+	if ch == nil {
+		return
+	}
+	for _, signal := range signals {
+		select {
+		case ch <- signal:
+		default:
+			c.logger().Warnf("drop the signal of IOA %d since the signal channel is full", signal.Address)
+		}
+	}
+}
+
+// answerInterrogation answers the general interrogation received from the peer by the points of the answerer.
+func (c *Client) answerInterrogation(answerer InterrogationAnswerer, apdu *APDU) error {
+	return answerInterrogationBy(answerer, apdu, c.SendIFrame)
+}
+
+// handleClientData dispatches the APDU to the method of handler by TypeID. Data with COT CotReq is the response of
+// read command, so it's dispatched to ReadCommandHandler. Data with COT CotPerCyc or CotBack is the periodic refresh,
+// so it's dispatched to CyclicDataHandler.
+func handleClientData(h ClientHandler, apdu *APDU) error {
+	switch apdu.cot {
+	case CotReq:
+		return h.ReadCommandHandler(apdu)
+	case CotPerCyc, CotBack:
+		return h.CyclicDataHandler(apdu)
+	}
 
 	switch apdu.typeID {
 	case CIcNa1:
-		return c.handler.GeneralInterrogationHandler(apdu)
+		return h.GeneralInterrogationHandler(apdu)
 	case CCiNa1:
-		return c.handler.CounterInterrogationHandler(apdu)
+		return h.CounterInterrogationHandler(apdu)
 	case CRdNa1:
-		return c.handler.ReadCommandHandler(apdu)
+		return h.ReadCommandHandler(apdu)
 	case CCsNa1:
-		return c.handler.ClockSynchronizationHandler(apdu)
+		return h.ClockSynchronizationHandler(apdu)
 	case CTsNb1, CTsTa1:
-		return c.handler.TestCommandHandler(apdu)
+		return h.TestCommandHandler(apdu)
 	case CRpNc1:
-		return c.handler.ResetProcessCommandHandler(apdu)
+		return h.ResetProcessCommandHandler(apdu)
 	case CCdNa1:
-		return c.handler.DelayAcquisitionCommandHandler(apdu)
+		return h.DelayAcquisitionCommandHandler(apdu)
 	default:
-		return c.handler.APDUHandler(apdu)
+		return h.APDUHandler(apdu)
 	}
 }
 
@@ -200,83 +679,278 @@ func (c *Client) handleData(apdu *APDU) error {
 func (c *Client) readApduHeader() (uint8, error) { //
 	buf := make([]byte, 2)
 
-	n, err := c.conn.Read(buf)
-	if err != nil {
+	// the header may be segmented by TCP, so it's read until both bytes are received
+	if _, err := io.ReadFull(c.conn, buf); err != nil {
 		return 0, err
 	}
-	if n != 2 {
-		return 0, errors.New("invalid data: empty")
-	} else if buf[0] != startByte {
+	if buf[0] != startByte {
 		return 0, fmt.Errorf("invalid data: unexpected start - % X, expected start - % X", buf[0], startByte)
 	}
+	if err := checkApduLen(buf[1]); err != nil {
+		return 0, err
+	}
 	return buf[1], nil
 }
-func (c *Client) readApduBody(apduLen uint8) (*APDU, error) {
+func (c *Client) readApduBody(ctx context.Context, apduLen uint8) (*APDU, error) {
 	apduData := make([]byte, apduLen)
-	n, err := c.conn.Read(apduData)
-	if err != nil {
+	if _, err := io.ReadFull(c.conn, apduData); err != nil {
 		return nil, err
 	}
-	for n < int(apduLen) {
-		bufLen := int(apduLen) - n
-		buf := make([]byte, bufLen)
-		m, err := c.conn.Read(buf)
-		if err != nil {
-			return nil, err
-		}
-		apduData = append(apduData[:n], buf[:m]...)
-		n = len(apduData)
-	}
-	_lg.Debugf("receive: [% X]", append([]byte{startByte, apduLen}, apduData...))
+	c.logger().Debugf("receive: [% X]", append([]byte{startByte, apduLen}, apduData...))
 
-	apdu := new(APDU)
+	clock := c.cp24Clock
+	if clock == nil && c.cp24ReferenceCP56 {
+		clock = c.cp56.now
+	}
+	apdu := &APDU{opt: &parseOption{cp24Clock: clock, lg: c.lg, cotLen: c.cotLen, coaLen: c.coaLen, loc: c.loc,
+		cp56YearBase: c.cp56YearBase, interrogationConfirmations: c.interrogationConfirmations}}
 	if err := apdu.Parse(apduData); err != nil {
 		return nil, err
 	}
+	if c.cp24ReferenceCP56 && apdu.ASDU != nil {
+		c.cp56.record(apdu.Signals)
+	}
+	if apdu.ASDU != nil {
+		c.scale(apdu.Signals)
+	}
 
 	switch apdu.frame.Type() {
+	case FrameTypeS:
+		c.ack(apdu.frame.(*SFrame).RecvSN)
 	case FrameTypeI:
-		if apdu.ASDU.cmdRsp != nil {
-			c.cmdRspChan <- apdu.ASDU.cmdRsp
+		c.touchData()
+		c.ack(apdu.frame.(*IFrame).RecvSN)
+		// count the frame received before it's delivered, so the frames sent by the commands confirmed by it
+		// acknowledge it by their N(R)
+		unacked, duplicate, err := c.incRsn(apdu.frame.(*IFrame).SendSN)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate {
+			c.logger().Warnf("drop the I-format frame received again: N(S)=%d", apdu.frame.(*IFrame).SendSN)
+			return apdu, nil
+		}
+		c.stats.recordType(apdu.ASDU.typeID, time.Now())
+		if apdu.ASDU.cmdRsp != nil && !c.isOriginator(apdu.ASDU.org) {
+			c.logger().Debugf("drop the confirmation directed to originator %d", apdu.ASDU.org)
+			apdu.ASDU.cmdRsp = nil
+		}
+		if apdu.ASDU.cmdRsp != nil && !c.resolveCmd(apdu.ASDU.cmdRsp) {
+			c.logger().Warnf("drop the confirmation not waited by any command: TypeID[%X], IOA[%d], COT[%X]",
+				uint8(apdu.ASDU.typeID), apdu.ASDU.cmdRsp.address, uint8(apdu.ASDU.cot))
+		}
+		if apdu.ASDU.cot == CotReq && c.isOriginator(apdu.ASDU.org) {
+			c.resolveReads(apdu)
 		}
 		if apdu.ASDU.toBeHandled {
-			c.dataChan <- apdu
+			if err := c.deliverData(ctx, apdu); err != nil {
+				return nil, err
+			}
 		}
-		if apdu.ASDU.sendSFrame {
-			c.SendTestFrame()
+		// acknowledge after w I-format frames are received
+		if apdu.ASDU.sendSFrame || unacked >= c.w {
+			c.sendAck()
 		}
-
-		c.incRsn()
 	}
 
 	return apdu, nil
 }
 
+// deliverData passes the APDU to the goroutine handling data, and applies the DataFullPolicy if the buffer is full.
+func (c *Client) deliverData(ctx context.Context, apdu *APDU) error {
+	select {
+	case c.dataChan <- apdu:
+		return nil
+	default:
+	}
+
+	switch c.dataFullPolicy {
+	case DataFullPolicyDrop:
+		c.stats.recordDataDropped()
+		c.logger().Warnf("drop the data since the buffer is full: TypeID[%X], COT[%X]", uint8(apdu.ASDU.typeID), uint8(apdu.ASDU.cot))
+		return nil
+	case DataFullPolicyClose:
+		return fmt.Errorf("data buffer of size %d is full", cap(c.dataChan))
+	default:
+		select {
+		case c.dataChan <- apdu:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// isOriginator reports whether the confirmation with the originator address is directed to the client, it's always
+// true if the originator matching is disabled.
+func (c *Client) isOriginator(org ORG) bool {
+	// ORG isn't transmitted with 1-byte COT, so every confirmation is the client's
+	return !c.originatorMatching || c.cotLen == 1 || org == c.org
+}
+
+// addRead registers a pending read of the address, the returned channel receives the signal requested.
+func (c *Client) addRead(address IOA) chan *InformationElement {
+	c.readsMu.Lock()
+	defer c.readsMu.Unlock()
+
+	if c.reads == nil {
+		c.reads = make(map[IOA][]chan *InformationElement)
+	}
+	ch := make(chan *InformationElement, 1)
+	c.reads[address] = append(c.reads[address], ch)
+	return ch
+}
+
+// removeRead unregisters the pending read of the address.
+func (c *Client) removeRead(address IOA, ch chan *InformationElement) {
+	c.readsMu.Lock()
+	defer c.readsMu.Unlock()
+
+	chs := c.reads[address]
+	for i := range chs {
+		if chs[i] == ch {
+			chs = append(chs[:i], chs[i+1:]...)
+			break
+		}
+	}
+	if len(chs) == 0 {
+		delete(c.reads, address)
+	} else {
+		c.reads[address] = chs
+	}
+}
+
+// resolveReads delivers the signals with COT CotReq to the pending reads of their addresses.
+func (c *Client) resolveReads(apdu *APDU) {
+	c.readsMu.Lock()
+	defer c.readsMu.Unlock()
+
+	for _, signal := range apdu.Signals {
+		for _, ch := range c.reads[signal.Address] {
+			ch <- signal
+		}
+		delete(c.reads, signal.Address)
+	}
+}
+
+// cmdKey correlates the confirmation with the pending command by TypeID and IOA.
+type cmdKey struct {
+	typeID  TypeID
+	address IOA
+}
+
+// addCmd registers a pending command of the TypeID and address, the returned channel receives its confirmations
+// until it's removed by removeCmd, so the late confirmations of the previous commands aren't taken as its own.
+func (c *Client) addCmd(typeID TypeID, address IOA) chan *cmdRsp {
+	c.cmdsMu.Lock()
+	defer c.cmdsMu.Unlock()
+
+	if c.cmds == nil {
+		c.cmds = make(map[cmdKey][]chan *cmdRsp)
+	}
+	key := cmdKey{typeID: typeID, address: address}
+	ch := make(chan *cmdRsp, 1)
+	c.cmds[key] = append(c.cmds[key], ch)
+	return ch
+}
+
+// removeCmd unregisters the pending command of the TypeID and address.
+func (c *Client) removeCmd(typeID TypeID, address IOA, ch chan *cmdRsp) {
+	c.cmdsMu.Lock()
+	defer c.cmdsMu.Unlock()
+
+	key := cmdKey{typeID: typeID, address: address}
+	chs := c.cmds[key]
+	for i := range chs {
+		if chs[i] == ch {
+			chs = append(chs[:i], chs[i+1:]...)
+			break
+		}
+	}
+	if len(chs) == 0 {
+		delete(c.cmds, key)
+	} else {
+		c.cmds[key] = chs
+	}
+}
+
+// resolveCmd delivers the confirmation to the first pending command of its TypeID and IOA, it reports false if no
+// command waits for it.
+func (c *Client) resolveCmd(rsp *cmdRsp) bool {
+	c.cmdsMu.Lock()
+	defer c.cmdsMu.Unlock()
+
+	for _, ch := range c.cmds[cmdKey{typeID: rsp.typeID, address: rsp.address}] {
+		select {
+		case ch <- rsp:
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// IsConnected reports whether the connection is established and the data transfer is started by Connect, it's false
+// while the client is reconnecting.
 func (c *Client) IsConnected() bool {
-	return true
+	return atomic.LoadInt32(&c.status) == statusConnected
 }
 
-func (c *Client) Close() {
-	c.onDisconnectHandler(c)
+// Close stops the data transfer by STOPDT, closes the connection and stops reconnecting, the OnDisconnectHandler is
+// called if it's connected. It returns the error of STOPDT or closing the socket, and nil if the client is closed
+// already or the connection has been lost.
+func (c *Client) Close() error {
+	var err error
+	if atomic.SwapInt32(&c.status, statusClosed) == statusConnected {
+		// STOPDT isn't sent if the connection is done by the context passed to ConnectContext
+		if c.IsDataTransferStarted() && c.connCtx().Err() == nil {
+			err = c.StopDataTransfer(context.Background())
+		}
+		c.onDisconnectHandler(c)
+	}
 
-	if c.cancel != nil {
-		c.cancel()
+	c.connMu.Lock()
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+	c.connMu.Unlock()
+	// the connection lost is closed already
+	if closeErr := c.closeConn(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SendGeneralInterrogation sends the station interrogation, i.e. the general interrogation with QOIStation.
+func (c *Client) SendGeneralInterrogation() error {
+	return c.sendInterrogation(QOIStation)
+}
+
+// SendGroupInterrogation sends the general interrogation of the group (1-16), whose QOI is QOIGroup1-QOIGroup16. The
+// confirmation and termination are dispatched to GeneralInterrogationHandler if they are enabled by
+// ClientOption.SetInterrogationConfirmations, whose signal carries the QOI in Value and the group in Group.
+func (c *Client) SendGroupInterrogation(group uint8) error {
+	if group < 1 || group > 16 {
+		return fmt.Errorf("invalid interrogation group: %d", group)
 	}
+	return c.sendInterrogation(QOIStation + group)
 }
 
-func (c *Client) SendGeneralInterrogation() {
+func (c *Client) sendInterrogation(qoi byte) error {
 	ios := []*InformationObject{
 		{
 			ioa: 0x000000,
 			ies: []*InformationElement{
 				{
 					Format: []InformationElementType{QOI},
-					Raw:    []byte{0x14},
+					Raw:    []byte{qoi},
 				},
 			},
 		},
 	}
-	c.SendIFrame(&ASDU{
+	return c.SendIFrame(&ASDU{
 		typeID: CIcNa1,
 		sq:     false,
 		nObjs:  NOO(len(ios)),
@@ -286,19 +960,23 @@ func (c *Client) SendGeneralInterrogation() {
 	})
 }
 
-func (c *Client) SendCounterInterrogation() {
+// SendCounterInterrogation sends the counter interrogation of the request with the freeze mode, e.g.
+// CounterRequestGeneral with FreezeWithoutReset to freeze and read all the integrated totals. The confirmation and
+// termination are dispatched to CounterInterrogationHandler if they are enabled by
+// ClientOption.SetInterrogationConfirmations, whose signal carries the QCC decoded.
+func (c *Client) SendCounterInterrogation(request CounterRequest, freeze FreezeMode) error {
 	ios := []*InformationObject{
 		{
 			ioa: 0x000000,
 			ies: []*InformationElement{
 				{
 					Format: []InformationElementType{QCC},
-					Raw:    []byte{0x45},
+					Raw:    []byte{serializeQCC(request, freeze)},
 				},
 			},
 		},
 	}
-	c.SendIFrame(&ASDU{
+	return c.SendIFrame(&ASDU{
 		typeID: CCiNa1,
 		sq:     false,
 		nObjs:  NOO(len(ios)),
@@ -308,15 +986,107 @@ func (c *Client) SendCounterInterrogation() {
 	})
 }
 
-func (c *Client) SendSingleCommand(address IOA, close bool) error {
-	// select
-	ie := &InformationElement{
-		Format: []InformationElementType{SCO},
-	}
+// SendSingleCommand sends the single command of the address, which is selected before executed. The optional qu sets
+// the qualifier of command, e.g. QUShortPulse, it's QUNone by default. It's safe to call from multiple goroutines,
+// the commands are sent one by one, each after the previous one is confirmed or fails.
+func (c *Client) SendSingleCommand(address IOA, close bool, qu ...CommandQualifier) error {
+	return c.sendSingleCommand(CScNa1, address, close, true, commandQualifier(qu))
+}
+
+// SendSingleCommandWithTime sends the single command with time tag CP56Time2a (C_SC_TA_1) of the address, which is
+// selected before executed. Each phase is tagged with the current time, by which the controlled station validates
+// the freshness of the command.
+func (c *Client) SendSingleCommandWithTime(address IOA, close bool, qu ...CommandQualifier) error {
+	return c.sendSingleCommand(CScTa1, address, close, true, commandQualifier(qu))
+}
+
+// SendSingleCommandDirect sends the single command of the address, which is executed directly without selecting, for
+// the devices not implementing select before operate.
+func (c *Client) SendSingleCommandDirect(address IOA, close bool, qu ...CommandQualifier) error {
+	return c.sendSingleCommand(CScNa1, address, close, false, commandQualifier(qu))
+}
+
+func (c *Client) sendSingleCommand(typeID TypeID, address IOA, close, selectExecute bool, qu byte) error {
+	sco := qu
 	if close {
-		ie.Raw = []byte{0x81}
-	} else {
-		ie.Raw = []byte{0x80}
+		sco |= 0x01
+	}
+
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	rsp := c.addCmd(typeID, address)
+	defer c.removeCmd(typeID, address, rsp)
+	if selectExecute {
+		if err := c.sendCommand(typeID, SCO, address, 0x80|sco); err != nil {
+			return err
+		}
+		if _, err := c.recvCmdRsp(rsp); err != nil {
+			return err
+		}
+	}
+
+	if err := c.sendCommand(typeID, SCO, address, sco); err != nil {
+		return err
+	}
+	_, err := c.recvCmdRsp(rsp)
+	return err
+}
+
+// SendDoubleCommand sends the double command of the address, which is selected before executed. The optional qu sets
+// the qualifier of command, e.g. QUShortPulse, it's QUNone by default. It's safe to call from multiple goroutines,
+// the commands are sent one by one, each after the previous one is confirmed or fails.
+func (c *Client) SendDoubleCommand(address IOA, close bool, qu ...CommandQualifier) error {
+	return c.sendDoubleCommand(CDcNa1, address, close, true, commandQualifier(qu))
+}
+
+// SendDoubleCommandWithTime sends the double command with time tag CP56Time2a (C_DC_TA_1) of the address, which is
+// selected before executed. Each phase is tagged with the current time, by which the controlled station validates
+// the freshness of the command.
+func (c *Client) SendDoubleCommandWithTime(address IOA, close bool, qu ...CommandQualifier) error {
+	return c.sendDoubleCommand(CDcTa1, address, close, true, commandQualifier(qu))
+}
+
+// SendDoubleCommandDirect sends the double command of the address, which is executed directly without selecting, for
+// the devices not implementing select before operate.
+func (c *Client) SendDoubleCommandDirect(address IOA, close bool, qu ...CommandQualifier) error {
+	return c.sendDoubleCommand(CDcNa1, address, close, false, commandQualifier(qu))
+}
+
+func (c *Client) sendDoubleCommand(typeID TypeID, address IOA, close, selectExecute bool, qu byte) error {
+	state := uint8(0b01) // DCS of open
+	if close {
+		state = 0b10
+	}
+
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	rsp := c.addCmd(typeID, address)
+	defer c.removeCmd(typeID, address, rsp)
+	if selectExecute {
+		if err := c.sendCommand(typeID, DCO, address, 0x80|qu|state); err != nil {
+			return err
+		}
+		if err := c.waitCmdRsp(rsp, CommandPhaseSelect, state); err != nil {
+			return err
+		}
+	}
+
+	if err := c.sendCommand(typeID, DCO, address, qu|state); err != nil {
+		return err
+	}
+	return c.waitCmdRsp(rsp, CommandPhaseExecute, state)
+}
+
+// sendCommand sends the command of the address whose information element is the single byte of the format, e.g. SCO,
+// DCO or RCO, with COT CotAct. The command with time tag, i.e. CScTa1 or CDcTa1, is tagged with the current time.
+func (c *Client) sendCommand(typeID TypeID, format InformationElementType, address IOA, b byte) error {
+	ie := &InformationElement{
+		Format: []InformationElementType{format},
+		Raw:    []byte{b},
+	}
+	if typeID == CScTa1 || typeID == CDcTa1 {
+		ie.Format = append(ie.Format, CP56Time2a)
+		ie.Raw = append(ie.Raw, serializeCP56Time2a(time.Now(), c.loc)...)
 	}
 	ios := []*InformationObject{
 		{
@@ -324,186 +1094,686 @@ func (c *Client) SendSingleCommand(address IOA, close bool) error {
 			ies: []*InformationElement{ie},
 		},
 	}
-	c.SendIFrame(&ASDU{
-		typeID: CScNa1,
+	return c.SendIFrame(&ASDU{
+		typeID: typeID,
 		sq:     false,
 		nObjs:  NOO(len(ios)),
 		t:      false,
 		cot:    CotAct,
 		ios:    ios,
 	})
-	select {
-	case rsp := <-c.cmdRspChan:
-		if rsp.err != nil {
-			return rsp.err
+}
+
+// SendStepCommand sends the regulating step command of the address. If selectExecute is true, the command is selected
+// before executed as SendSingleCommand does, otherwise it's executed directly. The optional qu sets the qualifier of
+// command, it's QUNone by default.
+func (c *Client) SendStepCommand(address IOA, step StepDirection, selectExecute bool, qu ...CommandQualifier) error {
+	if step != StepLower && step != StepHigher {
+		return fmt.Errorf("invalid step direction: %d", step)
+	}
+
+	rco := commandQualifier(qu) | byte(step)
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	rsp := c.addCmd(CRcNa1, address)
+	defer c.removeCmd(CRcNa1, address, rsp)
+	if selectExecute {
+		if err := c.sendCommand(CRcNa1, RCO, address, 0x80|rco); err != nil {
+			return err
+		}
+		if err := c.waitCmdRsp(rsp, CommandPhaseSelect, uint8(step)); err != nil {
+			return err
 		}
 	}
 
-	// execute
-	ie = &InformationElement{
-		Format: []InformationElementType{SCO},
+	if err := c.sendCommand(CRcNa1, RCO, address, rco); err != nil {
+		return err
 	}
-	if close {
-		ie.Raw = []byte{0x01}
-	} else {
-		ie.Raw = []byte{0x00}
+	return c.waitCmdRsp(rsp, CommandPhaseExecute, uint8(step))
+}
+
+// recvCmdRsp waits for the confirmation of command registered by addCmd, it fails with ErrConnectionClosed if the connection is closed
+// before the confirmation is received, or with ErrCommandTimeout if it isn't received within the command timeout.
+func (c *Client) recvCmdRsp(ch chan *cmdRsp) (*cmdRsp, error) {
+	if c.dryRun {
+		return nil, nil
+	}
+	var timeout <-chan time.Time
+	if c.cmdTimeout > 0 {
+		timer := time.NewTimer(c.cmdTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case rsp := <-ch:
+		return rsp, rsp.err
+	case <-c.connCtx().Done():
+		return nil, errConnectionClosed{}
+	case <-timeout:
+		c.stats.recordTimeout()
+		return nil, errCommandTimeout{timeout: c.cmdTimeout}
+	}
+}
+
+// waitCmdRsp waits for the confirmation of double or regulating step command, and checks its phase and state.
+func (c *Client) waitCmdRsp(ch chan *cmdRsp, phase CommandPhase, state uint8) error {
+	rsp, err := c.recvCmdRsp(ch)
+	if err != nil || rsp == nil {
+		return err
+	}
+	if rsp.phase != phase || rsp.state != state {
+		return errUnexpectedCmd{phase: rsp.phase, state: rsp.state}
+	}
+	return nil
+}
+
+// SendReadCommand sends the read command of the address, and waits within t1 for the response with COT CotReq
+// carrying the current value, which is dispatched to ReadCommandHandler as well. Since reading is idempotent, the read
+// command timed out is resent up to the retries set by SetReadRetries.
+func (c *Client) SendReadCommand(address IOA) error {
+	err := c.sendReadCommand(address)
+	for retry := 1; retry <= c.readRetries && IsErrT1Timeout(err); retry++ {
+		c.logger().Warnf("%v, retry %d", err, retry)
+		err = c.sendReadCommand(address)
 	}
-	ios = []*InformationObject{
+	return err
+}
+
+// sendReadCommand sends the read command once. Each attempt registers its own pending read, which is resolved by the
+// first response of the address, so the late response of the previous attempt completes the retry instead of being
+// delivered twice.
+func (c *Client) sendReadCommand(address IOA) error {
+	read := c.addRead(address)
+	defer c.removeRead(address, read)
+
+	if err := c.SendIFrame(&ASDU{
+		typeID: CRdNa1,
+		sq:     false,
+		nObjs:  1,
+		t:      false,
+		cot:    CotReq,
+		ios:    []*InformationObject{{ioa: address}},
+	}); err != nil {
+		return err
+	}
+	if c.dryRun {
+		return nil
+	}
+
+	timer := time.NewTimer(c.t1)
+	defer timer.Stop()
+	select {
+	case <-read:
+		return nil
+	case <-c.connCtx().Done():
+		return errConnectionClosed{}
+	case <-timer.C:
+		c.stats.recordTimeout()
+		return errT1Timeout{frame: fmt.Sprintf("read command of IOA %d", address)}
+	}
+}
+
+// SendSetpointNormalized sends the set-point command of normalized value in [-1, 1) with the qualifier QOS, and waits
+// for the activation confirmation.
+func (c *Client) SendSetpointNormalized(address IOA, value float64, qos byte) error {
+	return c.sendSetpoint(CSeNa1, address, &InformationElement{
+		Format: []InformationElementType{NVA, QOS},
+		Raw:    append(serializeNVA(value), qos),
+	})
+}
+
+// SendSetpointScaled sends the set-point command of scaled value with the qualifier QOS, and waits for the
+// activation confirmation.
+func (c *Client) SendSetpointScaled(address IOA, value int16, qos byte) error {
+	return c.sendSetpoint(CSeNb1, address, &InformationElement{
+		Format: []InformationElementType{SVA, QOS},
+		Raw:    append(serializeSVA(value), qos),
+	})
+}
+
+// SendSetpointShortFloat sends the set-point command of short floating point value with the qualifier QOS, and
+// waits for the activation confirmation.
+func (c *Client) SendSetpointShortFloat(address IOA, value float32, qos byte) error {
+	return c.sendSetpoint(CSeNc1, address, &InformationElement{
+		Format: []InformationElementType{IEEE754STD, QOS},
+		Raw:    append(serializeIEEESTD754(value), qos),
+	})
+}
+
+// SendSetpointShortFloatWithTime sends the set-point command of short floating point value with time tag CP56Time2a
+// (C_SE_TC_1) with the qualifier QOS, and waits for the activation confirmation. The command is tagged with the
+// current time, by which the controlled station validates the freshness of the command.
+func (c *Client) SendSetpointShortFloatWithTime(address IOA, value float32, qos byte) error {
+	raw := append(serializeIEEESTD754(value), qos)
+	return c.sendSetpoint(CSeTc1, address, &InformationElement{
+		Format: []InformationElementType{IEEE754STD, QOS, CP56Time2a},
+		Raw:    append(raw, serializeCP56Time2a(time.Now(), c.loc)...),
+	})
+}
+
+func (c *Client) sendSetpoint(typeID TypeID, address IOA, ie *InformationElement) error {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	rsp := c.addCmd(typeID, address)
+	defer c.removeCmd(typeID, address, rsp)
+
+	ios := []*InformationObject{
 		{
 			ioa: address,
 			ies: []*InformationElement{ie},
 		},
 	}
-	c.SendIFrame(&ASDU{
-		typeID: CScNa1,
+	if err := c.SendIFrame(&ASDU{
+		typeID: typeID,
 		sq:     false,
 		nObjs:  NOO(len(ios)),
 		t:      false,
 		cot:    CotAct,
 		ios:    ios,
-	})
-	select {
-	case rsp := <-c.cmdRspChan:
-		if rsp.err != nil {
-			return rsp.err
-		}
+	}); err != nil {
+		return err
+	}
+
+	if _, err := c.recvCmdRsp(rsp); err != nil {
+		return err
 	}
 	return nil
 }
 
-func (c *Client) SendDoubleCommand(address IOA, close bool) error {
-	ie := &InformationElement{
-		Format: []InformationElementType{DCO},
-	}
-	if close {
-		ie.Raw = []byte{0x82}
-	} else {
-		ie.Raw = []byte{0x81}
-	}
+// SendBitstringCommand sends the bitstring of 32 bits command (C_BO_NA_1) of the address, e.g. to drive the grouped
+// digital outputs atomically, and waits for the activation confirmation, which fails if it doesn't echo the bits.
+func (c *Client) SendBitstringCommand(address IOA, bits uint32) error {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	ch := c.addCmd(CBoNa1, address)
+	defer c.removeCmd(CBoNa1, address, ch)
+
 	ios := []*InformationObject{
 		{
 			ioa: address,
-			ies: []*InformationElement{ie},
+			ies: []*InformationElement{
+				{
+					Format: []InformationElementType{BSI},
+					Raw:    serializeLittleEndianUint32(bits),
+				},
+			},
 		},
 	}
-	c.SendIFrame(&ASDU{
-		typeID: CDcNa1,
+	if err := c.SendIFrame(&ASDU{
+		typeID: CBoNa1,
 		sq:     false,
 		nObjs:  NOO(len(ios)),
 		t:      false,
 		cot:    CotAct,
 		ios:    ios,
-	})
+	}); err != nil {
+		return err
+	}
 
-	select {
-	case rsp := <-c.cmdRspChan:
-		if rsp.err != nil {
-			return rsp.err
-		}
+	rsp, err := c.recvCmdRsp(ch)
+	if err != nil || rsp == nil {
+		return err
+	}
+	if rsp.bits != bits {
+		return fmt.Errorf("confirmation of bitstring command with BSI %08X, expected %08X", rsp.bits, bits)
 	}
+	return nil
+}
+
+// SendTestCommand sends the test command with the fixed test bit pattern, and waits for the activation confirmation
+// echoing the pattern.
+func (c *Client) SendTestCommand() error {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	rsp := c.addCmd(CTsNb1, 0x000000)
+	defer c.removeCmd(CTsNb1, 0x000000, rsp)
 
-	// execute
-	ie = &InformationElement{
-		Format: []InformationElementType{DCO},
+	ios := []*InformationObject{
+		{
+			ioa: 0x000000,
+			ies: []*InformationElement{
+				{
+					Format: []InformationElementType{FBP},
+					Raw:    serializeLittleEndianUint16(FixedTestBitPattern),
+				},
+			},
+		},
 	}
-	if close {
-		ie.Raw = []byte{0x02}
-	} else {
-		ie.Raw = []byte{0x01}
+	if err := c.SendIFrame(&ASDU{
+		typeID: CTsNb1,
+		sq:     false,
+		nObjs:  NOO(len(ios)),
+		t:      false,
+		cot:    CotAct,
+		ios:    ios,
+	}); err != nil {
+		return err
 	}
-	ios = []*InformationObject{
+
+	if _, err := c.recvCmdRsp(rsp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SendResetProcess sends reset process command with the qualifier qrp (QRPGeneralReset or QRPResetEventBuffer) to
+// command the controlled station to reinitialize, and waits for the activation confirmation.
+func (c *Client) SendResetProcess(qrp byte) error {
+	if qrp == 0 {
+		return fmt.Errorf("invalid qualifier of reset process command: %d", qrp)
+	}
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	rsp := c.addCmd(CRpNc1, 0x000000)
+	defer c.removeCmd(CRpNc1, 0x000000, rsp)
+
+	ios := []*InformationObject{
 		{
-			ioa: address,
-			ies: []*InformationElement{ie},
+			ioa: 0x000000,
+			ies: []*InformationElement{
+				{
+					Format: []InformationElementType{QRP},
+					Raw:    []byte{qrp},
+				},
+			},
 		},
 	}
-	c.SendIFrame(&ASDU{
-		typeID: CDcNa1,
+	if err := c.SendIFrame(&ASDU{
+		typeID: CRpNc1,
 		sq:     false,
 		nObjs:  NOO(len(ios)),
 		t:      false,
 		cot:    CotAct,
 		ios:    ios,
+	}); err != nil {
+		return err
+	}
+
+	if _, err := c.recvCmdRsp(rsp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SendDelayAcquisition sends the transmission delay measured by the client to the controlled station by delay
+// acquisition command with COT CotSpont, which is used to correct the following clock synchronization. The delay
+// is sent in CP16Time2a, so it must be in [0, 65535] ms. No confirmation is expected for CotSpont.
+func (c *Client) SendDelayAcquisition(delay time.Duration) error {
+	if delay < 0 || delay > math.MaxUint16*time.Millisecond {
+		return fmt.Errorf("invalid delay of delay acquisition command: %s", delay)
+	}
+
+	ios := []*InformationObject{
+		{
+			ioa: 0x000000,
+			ies: []*InformationElement{
+				{
+					Format: []InformationElementType{CP16Time2a},
+					Raw:    SerializeCP16Time2a(delay),
+				},
+			},
+		},
+	}
+	return c.SendIFrame(&ASDU{
+		typeID: CCdNa1,
+		sq:     false,
+		nObjs:  NOO(len(ios)),
+		t:      false,
+		cot:    CotSpont,
+		ios:    ios,
 	})
+}
 
-	select {
-	case rsp := <-c.cmdRspChan:
-		if rsp.err != nil {
-			return rsp.err
-		}
+// SendClockSync sends the clock synchronization command with the time, and waits for the activation confirmation.
+func (c *Client) SendClockSync(t time.Time) error {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+	rsp := c.addCmd(CCsNa1, 0x000000)
+	defer c.removeCmd(CCsNa1, 0x000000, rsp)
+
+	ios := []*InformationObject{
+		{
+			ioa: 0x000000,
+			ies: []*InformationElement{
+				{
+					Format: []InformationElementType{CP56Time2a},
+					Raw:    serializeCP56Time2a(t, c.loc),
+				},
+			},
+		},
+	}
+	if err := c.SendIFrame(&ASDU{
+		typeID: CCsNa1,
+		sq:     false,
+		nObjs:  NOO(len(ios)),
+		t:      false,
+		cot:    CotAct,
+		ios:    ios,
+	}); err != nil {
+		return err
+	}
+
+	if _, err := c.recvCmdRsp(rsp); err != nil {
+		return err
 	}
 	return nil
 }
 
-func (c *Client) SendIFrame(asdu *ASDU) {
-	apci := &IFrame{
-		SendSN: c.ssn,
-		RecvSN: c.rsn,
+// BroadcastClockSync broadcasts the clock synchronization command with the time to the global address GlobalCOA, so
+// that all stations synchronize their clocks simultaneously. Broadcast synchronization isn't confirmed individually,
+// so it doesn't wait for the activation confirmation, and the confirmations answered by stations are ignored.
+func (c *Client) BroadcastClockSync(t time.Time) error {
+	ios := []*InformationObject{
+		{
+			ioa: 0x000000,
+			ies: []*InformationElement{
+				{
+					Format: []InformationElementType{CP56Time2a},
+					Raw:    serializeCP56Time2a(t, c.loc),
+				},
+			},
+		},
 	}
+	return c.SendIFrame(&ASDU{
+		typeID: CCsNa1,
+		sq:     false,
+		nObjs:  NOO(len(ios)),
+		t:      false,
+		cot:    CotAct,
+		coa:    GlobalCOA,
+		ios:    ios,
+	})
+}
+
+// SendIFrame sends the ASDU in I-format frame, it blocks while there are k I-format frames not acknowledged by the
+// server. It's safe to call from multiple goroutines, the frames are sent in the order of their send sequence numbers.
+// The ASDU whose COA exceeds the range of 1-byte COA set by ClientOption.SetCOALength isn't sent but fails, and it
+// fails with ErrConnectionClosed if the connection is closed or lost while blocking.
+func (c *Client) SendIFrame(asdu *ASDU) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
 	asdu.org = c.org
-	asdu.coa = c.coa
+	asdu.cotLen, asdu.coaLen = c.cotLen, c.coaLen
+	if asdu.coa == 0 {
+		// 0 isn't used as COA, so the ASDU is sent to the station of the client
+		asdu.coa = c.coa
+	}
+	if err := asdu.checkCOA(); err != nil {
+		return err
+	}
+
+	if !c.dryRun {
+		if err := c.waitSendWindow(); err != nil {
+			return err
+		}
+	}
+	ssn, rsn := c.seq()
+	apci := &IFrame{
+		SendSN: ssn,
+		RecvSN: rsn,
+	}
 	c.sendIFrame(apci, asdu)
+	return nil
 }
 
 func (c *Client) sendIFrame(apci *IFrame, asdu *ASDU) {
+	frame := buildFrame(append(apci.Data(), asdu.Data()...))
+	if c.dryRun {
+		// the frame isn't sent, so it's neither held for acknowledgement nor limited by the window
+		c.incSsn()
+		c.send(frame)
+		return
+	}
+
+	// hold the frame until it's acknowledged within t1, it must be buffered before ssn is increased
+	c.windowMu.Lock()
+	ssn := apci.SendSN
+	timer := time.AfterFunc(c.t1, func() { c.ackTimeout(ssn) })
+	if !c.unacked.push(sentFrame{ssn: ssn, frame: frame, timer: timer}) {
+		timer.Stop()
+		c.logger().Warnf("send buffer is full, I-format frame N(S)=%d isn't held for acknowledgement", ssn)
+	}
+	c.windowMu.Unlock()
 	c.incSsn()
 
-	frame := c.buildFrame(append(apci.Data(), asdu.Data()...))
-	_lg.Debugf("send i frame: [% X]", frame)
-	c.sendChan <- frame
+	c.logger().Debugf("send i frame: [% X]", frame)
+	c.send(frame)
+	c.touchData()
 }
 
+// SendTestFrame sends the S-format frame acknowledging the I-format frames received.
+//
+// Deprecated: it doesn't send TESTFR as its name suggests. The acknowledgement is sent automatically after w I-format
+// frames are received, and TESTFR is sent automatically after t3 of idle.
 func (c *Client) SendTestFrame() {
+	c.sendAck()
+}
+
+// sendAck sends the S-format frame acknowledging the I-format frames received, whose N(R) is the receive sequence
+// number.
+func (c *Client) sendAck() {
+	_, rsn := c.seq()
 	c.sendSFrame(&SFrame{
-		RecvSN: c.rsn,
+		RecvSN: rsn,
 	})
 }
 func (c *Client) sendSFrame(x *SFrame) {
-	frame := c.buildFrame(x.Data())
-	_lg.Debugf("send s frame: [% X]", frame)
-	c.sendChan <- frame
+	frame := buildFrame(x.Data())
+	c.logger().Debugf("send s frame: [% X]", frame)
+	c.send(frame)
 }
 
 func (c *Client) sendUFrame(x UFrameFunction) {
-	name := ""
-	frame := c.buildFrame(x)
-	switch x[0] {
-	case UFrameFunctionStartDTA[0]:
-		name = "StartDTA"
-	case UFrameFunctionStartDTC[0]:
-		name = "StartDTC"
-	case UFrameFunctionStopDTA[0]:
-		name = "StopDTA"
-	case UFrameFunctionStopDTC[0]:
-		name = "StopDTC"
-	case UFrameFunctionTestFA[0]:
-		name = "TestFA"
-	case UFrameFunctionTestFC[0]:
-		name = "TestFC"
-	}
-	_lg.Debugf("send u frame: %s - [% X]", name, frame)
-	c.sendChan <- frame
-}
-
-func (c *Client) buildFrame(data []byte) []byte {
-	frame := make([]byte, 0, 0)
-	iBytes := serializeBigEndianUint16(uint16(len(data)))
-	frame = append(frame, startByte)
-	frame = append(frame, iBytes[1])
-	frame = append(frame, data...)
-	return frame
-}
-
-func (c *Client) incRsn() {
-	c.rsn++
-	if c.rsn == 1<<15 {
-		c.rsn = 0
+	frame := buildFrame(x)
+	c.logger().Debugf("send u frame: %s - [% X]", uFrameName(x[0]), frame)
+	c.send(frame)
+}
+
+// LastBuiltFrame returns the last frame built in the dry-run mode set by SetDryRun, nil if no frame is built.
+func (c *Client) LastBuiltFrame() []byte {
+	c.builtMu.Lock()
+	defer c.builtMu.Unlock()
+	if c.lastBuilt == nil {
+		return nil
 	}
+	return append([]byte(nil), c.lastBuilt...)
+}
+
+// send passes the frame to the goroutine writing to socket, the frame is dropped if the connection is down, so that
+// it doesn't block after the goroutine stops.
+func (c *Client) send(frame []byte) {
+	if c.dryRun {
+		c.logger().Debugf("build the frame in dry-run mode: [% X]", frame)
+		c.builtMu.Lock()
+		c.lastBuilt = frame
+		c.builtMu.Unlock()
+		return
+	}
+	ctx := c.connCtx()
+	if ctx == nil {
+		c.logger().Warnf("drop the frame since the client isn't connected: [% X]", frame)
+		return
+	}
+	select {
+	case c.sendChan <- frame:
+	case <-ctx.Done():
+		c.logger().Warnf("drop the frame since the connection is closed: [% X]", frame)
+	}
+}
+
+// seq returns the send and receive sequence numbers of the frame to send, the I-format frames received are
+// acknowledged by the frame.
+func (c *Client) seq() (ssn, rsn uint16) {
+	c.windowMu.Lock()
+	defer c.windowMu.Unlock()
+
+	c.ifn = 0
+	return c.ssn, c.rsn
+}
+
+// incRsn increases the receive sequence number after the I-format frame of the send sequence number is received, and
+// returns the number of I-format frames received but not acknowledged. The frame received again is reported as
+// duplicate without increasing, and the frame after a gap of lost frames fails with errSequence.
+func (c *Client) incRsn(ssn uint16) (unacked uint16, duplicate bool, err error) {
+	c.windowMu.Lock()
+	defer c.windowMu.Unlock()
+
+	switch {
+	case seqLess(ssn, c.rsn):
+		return c.ifn, true, nil
+	case ssn != c.rsn:
+		return c.ifn, false, errSequence{received: ssn, expected: c.rsn}
+	}
+	c.rsn = seqNext(c.rsn)
+	c.ifn++
+	return c.ifn, false, nil
 }
 
 func (c *Client) incSsn() {
-	c.ssn++
-	if c.rsn == 1<<15 {
-		c.ssn = 0
+	c.windowMu.Lock()
+	defer c.windowMu.Unlock()
+
+	c.ssn = seqNext(c.ssn)
+}
+
+// outstanding returns the number of I-format frames sent but not acknowledged, c.windowMu must be held.
+func (c *Client) outstanding() uint16 {
+	return seqDistance(c.ackSsn, c.ssn)
+}
+
+// outstandingFrames returns the number of I-format frames sent but not acknowledged.
+func (c *Client) outstandingFrames() int {
+	c.windowMu.Lock()
+	defer c.windowMu.Unlock()
+
+	return c.unacked.len()
+}
+
+// ackTimeout closes the connection if the I-format frame of the send sequence number isn't acknowledged within t1.
+func (c *Client) ackTimeout(ssn uint16) {
+	c.windowMu.Lock()
+	unacked := c.unacked.contains(ssn)
+	c.windowMu.Unlock()
+	if !unacked {
+		return
+	}
+
+	c.lost(errT1Timeout{frame: fmt.Sprintf("I-format frame N(S)=%d", ssn)})
+}
+
+// waitSendWindow blocks while k I-format frames are not acknowledged, OnWindowFull is called when it starts blocking.
+// It fails with ErrConnectionClosed if the connection is closed or lost while blocking.
+func (c *Client) waitSendWindow() error {
+	ctx := c.connCtx()
+	c.windowMu.Lock()
+	if c.outstanding() < c.k {
+		c.windowMu.Unlock()
+		return nil
+	}
+	c.windowMu.Unlock()
+
+	c.stats.recordWindowFull()
+	if c.onWindowFull != nil {
+		c.onWindowFull()
+	}
+
+	c.windowMu.Lock()
+	defer c.windowMu.Unlock()
+	for c.outstanding() >= c.k {
+		if ctx == nil || ctx.Err() != nil {
+			return errConnectionClosed{}
+		}
+		c.windowCond.Wait()
+	}
+	return nil
+}
+
+// wakeSenders wakes up the senders waiting for the send window after the context of the connection is cancelled, so
+// that they fail instead of waiting for the acknowledgements never coming.
+func (c *Client) wakeSenders() {
+	c.windowMu.Lock()
+	defer c.windowMu.Unlock()
+
+	c.windowCond.Broadcast()
+}
+
+// ack acknowledges the I-format frames sent before the receive sequence number of the server.
+func (c *Client) ack(rsn uint16) {
+	c.windowMu.Lock()
+	defer c.windowMu.Unlock()
+
+	if !seqInWindow(rsn, c.ackSsn, c.ssn) {
+		c.logger().Warnf("receive sequence number %d is out of the send window [%d, %d]", rsn, c.ackSsn, c.ssn)
+		return
+	}
+	acked := seqDistance(c.ackSsn, rsn)
+	c.ackSsn = rsn
+	c.unacked.release(int(acked))
+	c.windowCond.Broadcast()
+}
+
+// cp56Clock follows the clock of the controlled station by the last CP56Time2a received, it's safe for concurrent use.
+type cp56Clock struct {
+	mu       sync.Mutex
+	ts       time.Time // the latest CP56Time2a received
+	received time.Time // the host time when ts is received
+}
+
+// record records the latest CP56Time2a of the signals.
+func (c *cp56Clock) record(signals []*InformationElement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, signal := range signals {
+		if !signal.hasFormat(CP56Time2a) || signal.Ts.Before(c.ts) {
+			continue
+		}
+		c.ts, c.received = signal.Ts, time.Now()
+	}
+}
+
+// now returns the current time of the station, which is the host time if no CP56Time2a is received.
+func (c *cp56Clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ts.IsZero() {
+		return time.Now()
+	}
+	return c.ts.Add(time.Since(c.received))
+}
+
+// scaling converts the integer transmitted to the engineering value by value = raw * scale + offset.
+type scaling struct {
+	scale, offset float64
+}
+
+// SetScaling sets the scaling of the normalized value (NVA) and the scaled value (SVA) of the address, the Value of
+// the signals received is RawValue * scale + offset instead of RawValue / 32768 for NVA or RawValue for SVA.
+func (c *Client) SetScaling(address IOA, scale, offset float64) {
+	c.scalingsMu.Lock()
+	defer c.scalingsMu.Unlock()
+
+	if c.scalings == nil {
+		c.scalings = make(map[IOA]scaling)
+	}
+	c.scalings[address] = scaling{scale: scale, offset: offset}
+}
+
+// scale applies the scalings to the normalized and scaled values of the signals.
+func (c *Client) scale(signals []*InformationElement) {
+	c.scalingsMu.RLock()
+	defer c.scalingsMu.RUnlock()
+
+	if len(c.scalings) == 0 {
+		return
+	}
+	for _, signal := range signals {
+		x, ok := c.scalings[signal.Address]
+		if !ok || !signal.hasFormat(NVA) && !signal.hasFormat(SVA) {
+			continue
+		}
+		signal.Value = float64(signal.RawValue)*x.scale + x.offset
 	}
 }
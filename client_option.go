@@ -9,8 +9,13 @@ import (
 
 const (
 	DefaultConnectTimeout    = 30 * time.Second
-	DefaultReconnectRetries  = 0
+	DefaultStartDTTimeout    = 15 * time.Second // t1
+	DefaultTestFrameInterval = 20 * time.Second // t3
+	DefaultK                 = 12               // maximum number of I-format frames not acknowledged
+	DefaultW                 = 8                // maximum number of I-format frames received before acknowledgement
+	DefaultReconnectRetries  = ReconnectInfinitely
 	DefaultReconnectInterval = 1 * time.Minute
+	DefaultCommonAddress     = COA(0x0001)
 )
 
 func NewClientOption(server string, handler ClientHandler) (*ClientOption, error) {
@@ -27,19 +32,22 @@ func NewClientOption(server string, handler ClientHandler) (*ClientOption, error
 	return &ClientOption{
 		server:         remoteURL,
 		connectTimeout: DefaultConnectTimeout,
+		t1:             DefaultStartDTTimeout,
+		t3:             DefaultTestFrameInterval,
+		k:              DefaultK,
+		w:              DefaultW,
+		coa:            DefaultCommonAddress,
+		cotLen:         CotLen,
+		coaLen:         CoaLen,
 		autoReconnectRule: &AutoReconnectRule{
 			retries:  DefaultReconnectRetries,
 			interval: DefaultReconnectInterval,
 		},
 		onConnectHandler: func(c *Client) {
-			_lg.Printf("connected with %s", c.conn.RemoteAddr())
-			c.sendUFrame(UFrameFunctionStartDTA)
-			<-c.recvChan
+			c.logger().Printf("connected with %s", c.remoteAddr())
 		},
 		onDisconnectHandler: func(c *Client) {
-			_lg.Printf("disconnected with %s", c.conn.RemoteAddr())
-			c.sendUFrame(UFrameFunctionStopDTA)
-			<-c.recvChan // receive StopDTC
+			c.logger().Printf("disconnected with %s", c.remoteAddr())
 		},
 		handler: handler,
 		tc:      nil,
@@ -49,21 +57,62 @@ func NewClientOption(server string, handler ClientHandler) (*ClientOption, error
 type ClientOption struct {
 	server            *url.URL
 	connectTimeout    time.Duration
+	t1                time.Duration // timeout of waiting for the confirmation of STARTDT and TESTFR
+	t3                time.Duration // timeout of idle to send TESTFR
+	k                 uint16        // maximum number of I-format frames not acknowledged
+	w                 uint16        // maximum number of I-format frames received before acknowledgement
+	dataBufferSize    int           // number of received APDUs buffered for the handler, 0 means unbuffered
+	dataFullPolicy    DataFullPolicy
 	autoReconnectRule *AutoReconnectRule
+	readRetries       int           // times of resending the read command timed out
+	cmdTimeout        time.Duration // timeout of waiting for the confirmation of command, 0 means no timeout
+	maxIdle           time.Duration // close the connection without I-format frames sent or received in it, 0 means never
+	readTimeout       time.Duration // deadline of reading each frame from the socket, 0 means derived from t1 and t3
+	writeTimeout      time.Duration // deadline of writing each frame to the socket, 0 means t1
+	org               ORG           // originator address identifying the client among multiple controlling stations
+	coa               COA           // common address (or station address) of the ASDUs sent without their own
+	cotLen            int           // length of COT in bytes, 1 or 2
+	coaLen            int           // length of COA in bytes, 1 or 2
 
 	onConnectHandler    OnConnectHandler
 	onDisconnectHandler OnDisconnectHandler
+	onErrorHandler      OnErrorHandler
+	onWindowFull        func()
 
-	handler ClientHandler
+	originatorMatching         bool // drop the confirmations whose originator address isn't the client's
+	dryRun                     bool // build the frames without sending them
+	interrogationConfirmations bool // handle the confirmations and terminations of interrogations
+
+	handler    ClientHandler
+	signalFunc func(signal *InformationElement)
 
 	tc *tls.Config
+	lg Logger // logger of the client, the logger of the package if it's nil
+
+	loc               *time.Location // location of the time tags, time.Local if it's nil
+	cp56YearBase      int            // first year of the window of the 2-digit year of CP56Time2a
+	cp24Clock         func() time.Time
+	cp24ReferenceCP56 bool // complete CP24Time2a by the last CP56Time2a received instead of the host clock
 }
 
+// ReconnectInfinitely is the retries of AutoReconnectRule by which the client keeps reconnecting until it succeeds or
+// Close is called. It's the default, since a controlling station is expected to restore the link to the controlled
+// station by itself.
+const ReconnectInfinitely = 0
+
+// AutoReconnectRule is the rule of reconnecting after the connection is lost, the client reconnects up to retries
+// times spaced by interval.
 type AutoReconnectRule struct {
 	retries  int
 	interval time.Duration
 }
 
+// NewAutoReconnectRule returns the rule reconnecting up to retries times spaced by interval. Note that retries 0 is
+// ReconnectInfinitely rather than no reconnection, a positive retries gives up after the attempts.
+func NewAutoReconnectRule(retries int, interval time.Duration) *AutoReconnectRule {
+	return &AutoReconnectRule{retries: retries, interval: interval}
+}
+
 func (o *ClientOption) SetConnectTimeout(timeout time.Duration) *ClientOption {
 	if timeout > 0 {
 		o.connectTimeout = timeout
@@ -71,6 +120,125 @@ func (o *ClientOption) SetConnectTimeout(timeout time.Duration) *ClientOption {
 	return o
 }
 
+// SetStartDTTimeout sets the timeout (t1) of waiting for the confirmation of STARTDT after the connection is established.
+// It's also the timeout of waiting for the confirmation of TESTFR.
+func (o *ClientOption) SetStartDTTimeout(timeout time.Duration) *ClientOption {
+	if timeout > 0 {
+		o.t1 = timeout
+	}
+	return o
+}
+
+// SetTestFrameInterval sets the timeout (t3) of idle, TESTFR act is sent if there is no data sent or received in it.
+func (o *ClientOption) SetTestFrameInterval(interval time.Duration) *ClientOption {
+	if interval > 0 {
+		o.t3 = interval
+	}
+	return o
+}
+
+// SetReadTimeout sets the deadline of reading each frame from the socket, which is reset after each frame received.
+// The connection is lost and the client reconnects by AutoReconnectRule if nothing is received in it, e.g. the link
+// is half-open. It's 0 by default, which means t3 + 2*t1, long enough for TESTFR to be sent after t3 and confirmed
+// within t1 on the live link.
+func (o *ClientOption) SetReadTimeout(timeout time.Duration) *ClientOption {
+	if timeout >= 0 {
+		o.readTimeout = timeout
+	}
+	return o
+}
+
+// SetWriteTimeout sets the deadline of writing each frame to the socket. The connection is lost and the client
+// reconnects by AutoReconnectRule if the frame isn't written in it. It's 0 by default, which means t1.
+func (o *ClientOption) SetWriteTimeout(timeout time.Duration) *ClientOption {
+	if timeout >= 0 {
+		o.writeTimeout = timeout
+	}
+	return o
+}
+
+// readDeadline returns the deadline of reading the next frame from now.
+func (o *ClientOption) readDeadline() time.Time {
+	if o.readTimeout > 0 {
+		return time.Now().Add(o.readTimeout)
+	}
+	return time.Now().Add(o.t3 + 2*o.t1)
+}
+
+// writeDeadline returns the deadline of writing the next frame from now.
+func (o *ClientOption) writeDeadline() time.Time {
+	if o.writeTimeout > 0 {
+		return time.Now().Add(o.writeTimeout)
+	}
+	return time.Now().Add(o.t1)
+}
+
+// SetWindowSizes sets k, the maximum number of I-format frames sent but not acknowledged by the server, and w, the
+// maximum number of I-format frames received before the client acknowledges them by S-format frame. Both must be in
+// [1, 32767] and w must not exceed k, otherwise the sizes are not changed.
+func (o *ClientOption) SetWindowSizes(k, w uint16) *ClientOption {
+	if k > 0 && k < 1<<15 && w > 0 && w <= k {
+		o.k, o.w = k, w
+	}
+	return o
+}
+
+// DataFullPolicy decides what the client does with the received APDU when the data buffer of the handler is full.
+type DataFullPolicy int
+
+const (
+	// DataFullPolicyBlock blocks reading from the socket until the handler catches up.
+	DataFullPolicyBlock DataFullPolicy = iota
+	// DataFullPolicyDrop drops the APDU and counts it, see Client.DroppedDataCount.
+	DataFullPolicyDrop
+	// DataFullPolicyClose closes the connection.
+	DataFullPolicyClose
+)
+
+// SetDataBuffer sets the number of received APDUs buffered for the handler, and the policy when the buffer is full.
+// The APDUs aren't buffered by default, so reading from the socket waits for the handler, a buffer opts in so that a
+// slow handler doesn't block reading until the buffer is full.
+func (o *ClientOption) SetDataBuffer(size int, policy DataFullPolicy) *ClientOption {
+	if size >= 0 {
+		o.dataBufferSize = size
+	}
+	o.dataFullPolicy = policy
+	return o
+}
+
+// SetMaxIdle sets the duration after which the connection without application data, i.e. I-format frames sent or
+// received, is closed by Close to free the resources. Unlike the TESTFR sent after t3 to keep the connection alive, the
+// client doesn't reconnect then. It's 0 by default, which means never.
+func (o *ClientOption) SetMaxIdle(d time.Duration) *ClientOption {
+	if d >= 0 {
+		o.maxIdle = d
+	}
+	return o
+}
+
+// SetCommandTimeout sets the timeout of waiting for each confirmation of commands, e.g. the confirmations of both
+// select and execute, after which the command fails with ErrCommandTimeout. It's 0 by default, which means waiting
+// until the confirmation is received or the connection is closed. The confirmation received after the timeout is
+// dropped by the next command.
+func (o *ClientOption) SetCommandTimeout(d time.Duration) *ClientOption {
+	if d >= 0 {
+		o.cmdTimeout = d
+	}
+	return o
+}
+
+// SetReadRetries sets the times of resending the read command which isn't answered within t1, it's 0 by default.
+// Only read commands are retried, other commands aren't idempotent and never resent automatically.
+func (o *ClientOption) SetReadRetries(n int) *ClientOption {
+	if n >= 0 {
+		o.readRetries = n
+	}
+	return o
+}
+
+// SetAutoReconnectRule sets the rule of reconnecting after the connection is lost, it's DefaultReconnectRetries
+// (ReconnectInfinitely) spaced by DefaultReconnectInterval by default. The negative retries or interval of the rule is
+// replaced by the default.
 func (o *ClientOption) SetAutoReconnectRule(rule *AutoReconnectRule) *ClientOption {
 	if rule == nil {
 		return o
@@ -90,6 +258,124 @@ func (o *ClientOption) SetTLS(tc *tls.Config) *ClientOption {
 	return o
 }
 
+// SetOriginatorAddress sets the originator address (ORG) of the ASDUs sent by the client, which identifies the client
+// when there are multiple controlling stations. It's 0 by default, which means the default controlling station.
+func (o *ClientOption) SetOriginatorAddress(org ORG) *ClientOption {
+	o.org = org
+	return o
+}
+
+// SetCommonAddress sets the common address (COA) of the station which the ASDUs are sent to, it's DefaultCommonAddress
+// by default. COA must be in [1, 65534], 0 isn't used and 65535 is the global address GlobalCOA broadcast only by the
+// specific commands, e.g. BroadcastClockSync, otherwise the address isn't changed.
+func (o *ClientOption) SetCommonAddress(coa COA) *ClientOption {
+	if coa != 0 && !IsGlobalAddress(coa) {
+		o.coa = coa
+	}
+	return o
+}
+
+// SetCOALength sets the length of COA in bytes of the ASDUs sent and received, which is CoaLen (2) by the standard.
+// Some deployments, e.g. the IEC 101 gateways bridged to IEC 104, use 1-byte COA, whose station address must be in
+// [1, 254], Connect fails with the common address set by SetCommonAddress exceeding it. The length other than 1 or 2
+// is ignored.
+func (o *ClientOption) SetCOALength(n int) *ClientOption {
+	if validFieldLength(n) {
+		o.coaLen = n
+	}
+	return o
+}
+
+// SetCOTLength sets the length of COT in bytes of the ASDUs sent and received, which is CotLen (2) by the standard,
+// whose second byte is the originator address (ORG). The profiles without ORG use 1-byte COT, then ORG set by
+// SetOriginatorAddress isn't sent and ORG of the ASDUs received is 0. The lengths of COT and COA are independent,
+// the data unit identifier is 2 bytes (TypeID and VSQ) plus both of them, e.g. 4 bytes for 1-byte COT and COA. The
+// length other than 1 or 2 is ignored.
+func (o *ClientOption) SetCOTLength(n int) *ClientOption {
+	if validFieldLength(n) {
+		o.cotLen = n
+	}
+	return o
+}
+
+// SetLogger sets the logger of the client instead of the logger of the package set by SetLogger, so that the clients
+// connecting to different stations log separately. *logrus.Logger is adapted by NewLogrusLogger, and nil discards the
+// logs of the client.
+func (o *ClientOption) SetLogger(lg Logger) *ClientOption {
+	o.lg = adaptLogger(lg)
+	return o
+}
+
+// SetTimeLocation sets the location to decode CP24Time2a and CP56Time2a in, which is the location the controlled
+// station tags the time in rather than the one of the host. It's time.Local by default, and nil is ignored.
+func (o *ClientOption) SetTimeLocation(loc *time.Location) *ClientOption {
+	if loc != nil {
+		o.loc = loc
+	}
+	return o
+}
+
+// SetCP56YearBase sets the first year of the 100-year window which the 2-digit year of CP56Time2a received is in, it's
+// DefaultCP56YearBase by default, e.g. 2000 decodes years 0-99 as 2000-2099. The base not positive is ignored.
+func (o *ClientOption) SetCP56YearBase(base int) *ClientOption {
+	if base > 0 {
+		o.cp56YearBase = base
+	}
+	return o
+}
+
+// SetCP24ReferenceClock sets the clock to complete the date and hour of CP24Time2a, which only carries minute, second
+// and millisecond. The time when the ASDU is received is used by default.
+func (o *ClientOption) SetCP24ReferenceClock(clock func() time.Time) *ClientOption {
+	o.cp24Clock = clock
+	return o
+}
+
+// SetCP24ReferenceCP56 sets whether the date and hour of CP24Time2a are completed by the last CP56Time2a received
+// from the controlled station, advanced by the time elapsed since then, which follows the clock of the station rather
+// than the host. The host clock is used until any CP56Time2a is received, and the clock set by SetCP24ReferenceClock
+// takes precedence.
+func (o *ClientOption) SetCP24ReferenceCP56(enabled bool) *ClientOption {
+	o.cp24ReferenceCP56 = enabled
+	return o
+}
+
+// SetSignalFunc sets the function called with every signal decoded from the data in monitor direction (TypeID 1-44),
+// which is simpler than implementing ClientHandler to consume the telemetry. It's called in the goroutine handling
+// data before the ClientHandler, which still handles the data.
+func (o *ClientOption) SetSignalFunc(f func(signal *InformationElement)) *ClientOption {
+	o.signalFunc = f
+	return o
+}
+
+// SetOriginatorMatching sets whether the confirmations of commands and the responses of read commands are matched by
+// the originator address (ORG). ORG is the second byte of the 2-byte cause of transmission, when it's enabled, the
+// confirmations whose ORG isn't the client's are dropped since they are directed to another controlling station.
+// It's disabled by default, which fits the single controlling station system where ORG is 0 everywhere.
+func (o *ClientOption) SetOriginatorMatching(enabled bool) *ClientOption {
+	o.originatorMatching = enabled
+	return o
+}
+
+// SetDryRun sets whether the send methods only build the frames without writing them to the socket, the last frame
+// built is returned by Client.LastBuiltFrame. It asserts on the exact bytes without a peer, e.g. in integration tests,
+// so the client needn't connect, and the commands don't wait for the confirmations.
+func (o *ClientOption) SetDryRun(enabled bool) *ClientOption {
+	o.dryRun = enabled
+	return o
+}
+
+// SetInterrogationConfirmations sets whether the confirmations (CotActCon) and terminations (CotActTerm) of general
+// and counter interrogations are dispatched to GeneralInterrogationHandler and CounterInterrogationHandler like the
+// answers. The signal of general interrogation carries the QOI in Value and the group in Group, and the one of
+// counter interrogation carries the QCC decoded. It's disabled by default, by which they are only logged, so the
+// handlers only get the answers of the interrogations.
+func (o *ClientOption) SetInterrogationConfirmations(enabled bool) *ClientOption {
+	o.interrogationConfirmations = enabled
+	return o
+}
+
+// OnConnectHandler is called after the connection is established and the data transfer is started by STARTDT.
 type OnConnectHandler func(c *Client)
 
 func (o *ClientOption) SetOnConnectHandler(handler OnConnectHandler) *ClientOption {
@@ -107,3 +393,21 @@ func (o *ClientOption) SetOnDisconnectHandler(handler OnDisconnectHandler) *Clie
 	}
 	return o
 }
+
+// OnErrorHandler is called when the connection is lost by the error, e.g. the failure of reading from the socket or
+// the timeout of confirmation, before the client reconnects.
+type OnErrorHandler func(c *Client, err error)
+
+func (o *ClientOption) SetOnErrorHandler(handler OnErrorHandler) *ClientOption {
+	if handler != nil {
+		o.onErrorHandler = handler
+	}
+	return o
+}
+
+// SetOnWindowFull sets the callback called when SendIFrame blocks because k I-format frames are not acknowledged,
+// which indicates the server is slow to acknowledge.
+func (o *ClientOption) SetOnWindowFull(callback func()) *ClientOption {
+	o.onWindowFull = callback
+	return o
+}
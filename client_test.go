@@ -0,0 +1,2947 @@
+package iec104
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startTestSubstation starts a fake controlled station on a random local port, the serve function is called with
+// every accepted connection.
+func startTestSubstation(t *testing.T, serve func(conn net.Conn)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// confirmingSubstation confirms STARTDT, STOPDT and TESTFR, and ignores other frames.
+func confirmingSubstation(conn net.Conn) {
+	serveTestSubstation(conn, nil)
+}
+
+// serveTestSubstation confirms STARTDT, STOPDT and TESTFR, and ignores other frames. The started function is called
+// after STARTDT is confirmed.
+func serveTestSubstation(conn net.Conn, started func(conn net.Conn)) {
+	for {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		body := make([]byte, header[1])
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		switch body[0] {
+		case UFrameFunctionStartDTA[0]:
+			_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+			if started != nil {
+				started(conn)
+			}
+		case UFrameFunctionStopDTA[0]:
+			_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+		case UFrameFunctionTestFA[0]:
+			_, _ = conn.Write(buildFrame(UFrameFunctionTestFC))
+		}
+	}
+}
+
+// answeringSubstation confirms STARTDT, STOPDT and TESTFR, and answers each ASDU received by the ASDUs returned by
+// the answer function.
+func answeringSubstation(answer func(asdu []byte) [][]byte) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		ssn := uint16(0)
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			switch {
+			case body[0] == UFrameFunctionStartDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+			case body[0] == UFrameFunctionStopDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+			case body[0] == UFrameFunctionTestFA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionTestFC))
+			case FrameType(body[0]&0x1) == FrameTypeI:
+				for _, asdu := range answer(body[ApduHeaderLen:]) {
+					_, _ = conn.Write(iFrame(ssn, asdu))
+					ssn++
+				}
+			}
+		}
+	}
+}
+
+// withCOT copies the ASDU and replaces its cause of transmission (P/N bit included).
+func withCOT(asdu []byte, cot byte) []byte {
+	x := append([]byte(nil), asdu...)
+	x[2] = x[2]&0x80 | cot
+	return x
+}
+
+// iFrame builds an I-format frame with the send sequence number and ASDU.
+func iFrame(ssn uint16, asdu []byte) []byte {
+	return buildFrame(append((&IFrame{SendSN: ssn}).Data(), asdu...))
+}
+
+func TestClient_StartDTTimeout(t *testing.T) {
+	closed := make(chan struct{})
+	address := startTestSubstation(t, func(conn net.Conn) {
+		// never confirm STARTDT
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetStartDTTimeout(100 * time.Millisecond)
+	client := NewClient(option)
+
+	start := time.Now()
+	err = client.Connect()
+	if !IsErrT1Timeout(err) {
+		t.Fatalf("Connect() error = %v, want t1 timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Connect() takes %s, want about 100ms", elapsed)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("socket isn't closed after t1 timeout")
+	}
+}
+
+func TestClient_ConnectContext(t *testing.T) {
+	t.Run("cancelled while waiting for STARTDT con", func(t *testing.T) {
+		closed := make(chan struct{})
+		address := startTestSubstation(t, func(conn net.Conn) {
+			// never confirm STARTDT
+			_, _ = io.Copy(io.Discard, conn)
+			close(closed)
+		})
+		option, err := NewClientOption(address, &BaseHandler{})
+		if err != nil {
+			t.Fatalf("NewClientOption() error = %v", err)
+		}
+		client := NewClient(option)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := client.ConnectContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("ConnectContext() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Error("socket isn't closed after the context is done")
+		}
+	})
+
+	t.Run("cancelled after connected", func(t *testing.T) {
+		address := startTestSubstation(t, confirmingSubstation)
+		disconnected := make(chan struct{}, 1)
+		option, err := NewClientOption(address, &BaseHandler{})
+		if err != nil {
+			t.Fatalf("NewClientOption() error = %v", err)
+		}
+		option.SetOnDisconnectHandler(func(c *Client) { disconnected <- struct{}{} }).
+			SetAutoReconnectRule(NewAutoReconnectRule(0, 10*time.Millisecond))
+		client := NewClient(option)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := client.ConnectContext(ctx); err != nil {
+			t.Fatalf("ConnectContext() error = %v", err)
+		}
+		cancel()
+		select {
+		case <-disconnected:
+		case <-time.After(time.Second):
+			t.Fatal("client isn't closed after the context is cancelled")
+		}
+		if client.IsConnected() {
+			t.Error("IsConnected() = true after the context is cancelled")
+		}
+		// the client doesn't reconnect
+		time.Sleep(50 * time.Millisecond)
+		if client.IsConnected() {
+			t.Error("client reconnects after the context is cancelled")
+		}
+	})
+}
+
+func TestClient_ReadTimeout(t *testing.T) {
+	address := startTestSubstation(t, func(conn net.Conn) {
+		// confirm STARTDT, then stay silent as the half-open link
+		header := make([]byte, 6)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+		_, _ = io.Copy(io.Discard, conn)
+	})
+
+	errs := make(chan error, 1)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetReadTimeout(100 * time.Millisecond).
+		SetOnErrorHandler(func(c *Client, err error) { errs <- err }).
+		SetAutoReconnectRule(NewAutoReconnectRule(1, time.Hour))
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	select {
+	case err := <-errs:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("OnErrorHandler() gets %v, want the timeout of reading", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the connection isn't lost after the read timeout")
+	}
+}
+
+func TestClient_StartDTConfirmed(t *testing.T) {
+	address := startTestSubstation(t, confirmingSubstation)
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetStartDTTimeout(time.Second)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	client.Close()
+}
+
+func TestClient_TestFrameInterval(t *testing.T) {
+	testFAs := make(chan struct{}, 10)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		buf := make([]byte, 6)
+		for {
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return
+			}
+			switch buf[2] {
+			case UFrameFunctionStartDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+			case UFrameFunctionStopDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+			case UFrameFunctionTestFA[0]:
+				testFAs <- struct{}{}
+				_, _ = conn.Write(buildFrame(UFrameFunctionTestFC))
+			}
+		}
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetTestFrameInterval(50 * time.Millisecond)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-testFAs:
+		case <-time.After(time.Second):
+			t.Fatalf("TESTFR act #%d isn't sent after t3", i+1)
+		}
+	}
+}
+
+func TestClient_TestFrameUnconfirmed(t *testing.T) {
+	closed := make(chan struct{})
+	address := startTestSubstation(t, func(conn net.Conn) {
+		defer close(closed)
+		buf := make([]byte, 6)
+		for {
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return
+			}
+			// confirm STARTDT only
+			if buf[2] == UFrameFunctionStartDTA[0] {
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+			}
+		}
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetStartDTTimeout(50 * time.Millisecond).SetTestFrameInterval(50 * time.Millisecond)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("connection isn't closed when TESTFR con isn't received in t1")
+	}
+}
+
+func TestClient_incSsn(t *testing.T) {
+	tests := []struct {
+		name string
+		ssn  uint16
+		want uint16
+	}{
+		{"from 0", 0, 1},
+		{"before the boundary", 1<<15 - 2, 1<<15 - 1},
+		{"wrap at the boundary", 1<<15 - 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{ssn: tt.ssn, rsn: 100}
+			c.incSsn()
+			if c.ssn != tt.want {
+				t.Errorf("incSsn() ssn = %d, want %d", c.ssn, tt.want)
+			}
+			if c.rsn != 100 {
+				t.Errorf("incSsn() changes rsn to %d", c.rsn)
+			}
+		})
+	}
+}
+
+type readHandler struct {
+	BaseHandler
+	reads chan *APDU
+}
+
+func (h *readHandler) ReadCommandHandler(apdu *APDU) error {
+	h.reads <- apdu
+	return nil
+}
+
+func TestClient_ResolveReads(t *testing.T) {
+	respond := make(chan struct{})
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			go func() {
+				<-respond
+				// MDpTb1, CotReq, IOA 5 is ON at 2022-08-01 10:30:00
+				_, _ = conn.Write(iFrame(0, []byte{
+					0x1f, 0x01, 0x05, 0x00, 0x01, 0x00,
+					0x05, 0x00, 0x00, 0x02, 0x00, 0x00, 0x1e, 0x0a, 0x01, 0x08, 0x16,
+				}))
+			}()
+		})
+	})
+
+	handler := &readHandler{reads: make(chan *APDU, 1)}
+	option, err := NewClientOption(address, handler)
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	other := client.addRead(IOA(6))
+	read := client.addRead(IOA(5))
+	close(respond)
+
+	select {
+	case signal := <-read:
+		if signal.Address != 5 || signal.Value != 2 {
+			t.Errorf("read IOA %d = %f, want IOA 5 = 2", signal.Address, signal.Value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the response with COT CotReq isn't matched to the pending read")
+	}
+	select {
+	case signal := <-other:
+		t.Errorf("the response of IOA %d is matched to the pending read of IOA 6", signal.Address)
+	default:
+	}
+	select {
+	case apdu := <-handler.reads:
+		if apdu.cot != CotReq {
+			t.Errorf("ReadCommandHandler is invoked with COT %d, want %d", apdu.cot, CotReq)
+		}
+	case <-time.After(time.Second):
+		t.Error("the response with COT CotReq isn't dispatched to ReadCommandHandler")
+	}
+}
+
+func TestClient_SendReadCommandRetry(t *testing.T) {
+	reads := make(chan struct{}, 4)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		rsn, ssn := uint16(0), uint16(0)
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			switch {
+			case body[0] == UFrameFunctionStartDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+			case body[0] == UFrameFunctionStopDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+			case FrameType(body[0]&0x1) == FrameTypeI:
+				rsn++
+				reads <- struct{}{}
+				if rsn == 1 {
+					// drop the first read, but acknowledge it
+					_, _ = conn.Write(buildFrame((&SFrame{RecvSN: rsn}).Data()))
+					continue
+				}
+				// MSpNa1, CotReq, IOA 5 is ON
+				asdu := []byte{0x01, 0x01, 0x05, 0x00, 0x01, 0x00, 0x05, 0x00, 0x00, 0x01}
+				_, _ = conn.Write(buildFrame(append((&IFrame{SendSN: ssn, RecvSN: rsn}).Data(), asdu...)))
+				ssn++
+			}
+		}
+	})
+
+	tests := []struct {
+		name    string
+		retries int
+		wantErr bool
+	}{
+		{"no retry", 0, true},
+		{"retry", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			option.SetStartDTTimeout(100 * time.Millisecond).SetReadRetries(tt.retries)
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			err = client.SendReadCommand(5)
+			if tt.wantErr != IsErrT1Timeout(err) || !tt.wantErr && err != nil {
+				t.Fatalf("SendReadCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := len(reads); got != tt.retries+1 {
+				t.Errorf("read commands sent %d times, want %d", got, tt.retries+1)
+			}
+			for len(reads) > 0 {
+				<-reads
+			}
+		})
+	}
+}
+
+// byteConn is a net.Conn delivering the data one byte at a time, which simulates the segmentation of TCP.
+type byteConn struct {
+	net.Conn
+	data []byte
+}
+
+func (c *byteConn) Read(b []byte) (int, error) {
+	if len(c.data) == 0 {
+		return 0, io.EOF
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	b[0], c.data = c.data[0], c.data[1:]
+	return 1, nil
+}
+
+func (c *byteConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func TestClient_readFromSocketSegmented(t *testing.T) {
+	option, err := NewClientOption("127.0.0.1:2404", &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	// the data isn't handled without connecting, so it's buffered
+	client := NewClient(option.SetDataBuffer(1, DataFullPolicyBlock))
+	// MMeNc1, CotSpont, IOA 1 is 230.5
+	asdu := []byte{0x0d, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x80, 0x66, 0x43, 0x00}
+	client.conn = &byteConn{data: append(iFrame(0, asdu), buildFrame(UFrameFunctionTestFC)...)}
+
+	apdu, err := client.readFromSocket(context.Background())
+	if err != nil {
+		t.Fatalf("readFromSocket() error = %v", err)
+	}
+	if !bytes.Equal(apdu.RawASDU(), asdu) {
+		t.Errorf("RawASDU() = [% X], want [% X]", apdu.RawASDU(), asdu)
+	}
+	apdu, err = client.readFromSocket(context.Background())
+	if err != nil {
+		t.Fatalf("readFromSocket() error = %v", err)
+	}
+	if apdu.Frame().Type() != FrameTypeU {
+		t.Errorf("Frame().Type() = %s, want %s", apdu.Frame().Type(), FrameTypeU)
+	}
+	if _, err := client.readFromSocket(context.Background()); err != io.EOF {
+		t.Errorf("readFromSocket() error = %v, want %v", err, io.EOF)
+	}
+}
+
+// dataHandler passes the spontaneous data to the channel.
+type dataHandler struct {
+	BaseHandler
+	data chan *APDU
+}
+
+func (h *dataHandler) APDUHandler(apdu *APDU) error {
+	h.data <- apdu
+	return nil
+}
+
+func TestClient_CP24ReferenceCP56(t *testing.T) {
+	stationTime := time.Date(2022, time.August, 1, 23, 59, 50, 0, time.Local)
+	// MSpTb1, CotSpont, IOA 1 is ON at the station time
+	cp56 := append([]byte{0x1e, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}, SerializeCP56Time2a(stationTime)...)
+	// MSpTa1, CotSpont, IOA 2 is ON at 0min 5000ms
+	cp24 := []byte{0x02, 0x01, 0x03, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x01, 0x88, 0x13, 0x00}
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			_, _ = conn.Write(iFrame(0, cp56))
+			_, _ = conn.Write(iFrame(1, cp24))
+		})
+	})
+
+	handler := &dataHandler{data: make(chan *APDU, 2)}
+	option, err := NewClientOption(address, handler)
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetCP24ReferenceCP56(true)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	want := []time.Time{stationTime, time.Date(2022, time.August, 2, 0, 0, 5, 0, time.Local)}
+	for i, w := range want {
+		select {
+		case apdu := <-handler.data:
+			if got := apdu.Signals[0].Ts; !got.Equal(w) {
+				t.Errorf("Signals[%d].Ts = %v, want %v", i, got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("the %d-th APDU isn't handled", i+1)
+		}
+	}
+}
+
+func TestClientOption_SetTimeLocation(t *testing.T) {
+	// MSpTb1, CotSpont, IOA 1 is ON at 2022-08-01 10:20:30.400
+	cp56 := []byte{0x1e, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01, 0xc0, 0x76, 0x14, 0x0a, 0x01, 0x08, 0x16}
+	// MSpTa1, CotSpont, IOA 2 is ON at 20min 30400ms
+	cp24 := []byte{0x02, 0x01, 0x03, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x01, 0xc0, 0x76, 0x14}
+	ref := time.Date(2022, time.August, 1, 2, 20, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		loc  *time.Location
+	}{
+		{"UTC", time.UTC},
+		{"UTC+8", time.FixedZone("UTC+8", 8*60*60)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, _ := NewClientOption(":2404", nil)
+			c := NewClient(option.SetTimeLocation(tt.loc))
+			want := map[string]time.Time{
+				"CP56Time2a": time.Date(2022, time.August, 1, 10, 20, 30, 400*int(time.Millisecond), tt.loc),
+				"CP24Time2a": time.Date(2022, time.August, 1, ref.In(tt.loc).Hour(), 20, 30, 400*int(time.Millisecond), tt.loc),
+			}
+			for name, asdu := range map[string][]byte{"CP56Time2a": cp56, "CP24Time2a": cp24} {
+				apdu := &APDU{opt: &parseOption{loc: c.loc, cp24Clock: func() time.Time { return ref }}}
+				if err := apdu.Parse(append([]byte{0x00, 0x00, 0x00, 0x00}, asdu...)); err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if got := apdu.Signals[0].Ts; !got.Equal(want[name]) || got.Location() != tt.loc {
+					t.Errorf("%s Ts = %v, want %v", name, got, want[name])
+				}
+			}
+		})
+	}
+}
+
+func TestClientOption_SetCP56YearBase(t *testing.T) {
+	// MSpTb1, CotSpont, IOA 1 is ON at 2070-08-01 10:20:30 or 1970-08-01 10:20:30
+	asdu := []byte{0x1e, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01, 0x30, 0x75, 0x14, 0x0a, 0x01, 0x08, 0x46}
+	tests := []struct {
+		name string
+		base int
+		want int
+	}{
+		{"default", 0, 1970},
+		{"window from 2000", 2000, 2070},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, _ := NewClientOption(":2404", nil)
+			c := NewClient(option.SetCP56YearBase(tt.base))
+			apdu := &APDU{opt: &parseOption{cp56YearBase: c.cp56YearBase}}
+			if err := apdu.Parse(append([]byte{0x00, 0x00, 0x00, 0x00}, asdu...)); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := apdu.Signals[0].Ts.Year(); got != tt.want {
+				t.Errorf("year = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientOption_SetDataBuffer(t *testing.T) {
+	option, _ := NewClientOption(":2404", nil)
+	if got := cap(NewClient(option).dataChan); got != 0 {
+		t.Errorf("data buffer by default = %d, want unbuffered", got)
+	}
+	if got := cap(NewClient(option.SetDataBuffer(16, DataFullPolicyDrop)).dataChan); got != 16 {
+		t.Errorf("data buffer = %d, want 16", got)
+	}
+}
+
+func TestClient_SetScaling(t *testing.T) {
+	// MMeNb1, CotSpont, IOA 1 and 2 are 1000
+	scaled := []byte{0x0b, 0x02, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0xe8, 0x03, 0x00, 0x02, 0x00, 0x00, 0xe8, 0x03, 0x00}
+	// MMeNa1, CotSpont, IOA 3 and 4 are 0.5 (16384)
+	normalized := []byte{0x09, 0x02, 0x03, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00, 0x04, 0x00, 0x00, 0x00, 0x40, 0x00}
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			_, _ = conn.Write(iFrame(0, scaled))
+			_, _ = conn.Write(iFrame(1, normalized))
+		})
+	})
+
+	handler := &dataHandler{data: make(chan *APDU, 2)}
+	option, err := NewClientOption(address, handler)
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	client.SetScaling(1, 0.1, -20)
+	client.SetScaling(3, 0.01, 0)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	want := map[IOA]struct {
+		raw   int64
+		value float64
+	}{
+		1: {1000, 80},      // scaled
+		2: {1000, 1000},    // identity
+		3: {16384, 163.84}, // scaled
+		4: {16384, 0.5},    // identity
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case apdu := <-handler.data:
+			for _, signal := range apdu.Signals {
+				w := want[signal.Address]
+				if signal.RawValue != w.raw || math.Abs(signal.Value-w.value) > 1e-9 {
+					t.Errorf("IOA %d = {%d, %f}, want {%d, %f}", signal.Address, signal.RawValue, signal.Value, w.raw, w.value)
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("the %d-th APDU isn't handled", i+1)
+		}
+	}
+}
+
+func TestClient_SetSignalFunc(t *testing.T) {
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		if asdu[0] != byte(CIcNa1) {
+			return nil
+		}
+		return [][]byte{
+			withCOT(asdu, byte(CotActCon)),
+			// MSpNa1, SQ=1, CotInrogen, IOA 1-3 are ON, OFF, ON
+			{0x01, 0x83, byte(CotInrogen), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x01},
+			// MMeNc1, CotInrogen, IOA 16385 is 230.5
+			{0x0d, 0x01, byte(CotInrogen), 0x00, 0x01, 0x00, 0x01, 0x40, 0x00, 0x00, 0x80, 0x66, 0x43, 0x00},
+			withCOT(asdu, byte(CotActTerm)),
+		}
+	}))
+
+	signals := make(chan *InformationElement, 8)
+	handler := &dataHandler{data: make(chan *APDU, 2)}
+	option, err := NewClientOption(address, handler)
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetSignalFunc(func(signal *InformationElement) { signals <- signal })
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+	client.SendGeneralInterrogation()
+
+	want := []struct {
+		address IOA
+		value   float64
+	}{{1, 1}, {2, 0}, {3, 1}, {16385, 230.5}}
+	for i, w := range want {
+		select {
+		case signal := <-signals:
+			if signal.Address != w.address || signal.Value != w.value {
+				t.Errorf("signal %d = {%d, %f}, want {%d, %f}", i, signal.Address, signal.Value, w.address, w.value)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("signal %d isn't passed to the signal func", i)
+		}
+	}
+	// the handler still handles the data
+	for i := 0; i < 2; i++ {
+		select {
+		case <-handler.data:
+		case <-time.After(time.Second):
+			t.Fatalf("the %d-th APDU isn't handled", i+1)
+		}
+	}
+	select {
+	case signal := <-signals:
+		t.Errorf("unexpected signal of TypeID %X passed to the signal func", uint8(signal.TypeID))
+	default:
+	}
+}
+
+// answeringHandler answers the general interrogation received by the points.
+type answeringHandler struct {
+	BaseHandler
+	points []Point
+}
+
+func (h *answeringHandler) AnswerInterrogation(group uint8) ([]Point, error) {
+	return h.points, nil
+}
+
+func TestClient_AnswerInterrogation(t *testing.T) {
+	received := make(chan []byte, 8)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			switch {
+			case body[0] == UFrameFunctionStartDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+				// the peer interrogates the client with QOI 20
+				_, _ = conn.Write(iFrame(0, []byte{byte(CIcNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}))
+			case body[0] == UFrameFunctionStopDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+			case FrameType(body[0]&0x1) == FrameTypeI:
+				received <- body[ApduHeaderLen:]
+			}
+		}
+	})
+
+	handler := &answeringHandler{points: []Point{
+		{Address: 2, TypeID: MSpNa1, Value: 0},
+		{Address: 1, TypeID: MSpNa1, Value: 1},
+	}}
+	option, err := NewClientOption(address, handler)
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	want := [][]byte{
+		{byte(CIcNa1), 0x01, byte(CotActCon), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+		{byte(MSpNa1), 0x02, byte(CotInrogen), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00},
+		{byte(CIcNa1), 0x01, byte(CotActTerm), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+	}
+	for i, w := range want {
+		select {
+		case asdu := <-received:
+			if !bytes.Equal(asdu, w) {
+				t.Errorf("answer %d = [% X], want [% X]", i, asdu, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("answer %d isn't sent", i)
+		}
+	}
+}
+
+func TestClient_Signals(t *testing.T) {
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		if asdu[0] != byte(CIcNa1) {
+			return nil
+		}
+		return [][]byte{
+			withCOT(asdu, byte(CotActCon)),
+			// MSpNa1, SQ=1, CotInrogen, IOA 1-3 are ON, OFF, ON
+			{0x01, 0x83, byte(CotInrogen), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x01},
+			withCOT(asdu, byte(CotActTerm)),
+		}
+	}))
+
+	option, err := NewClientOption(address, nil)
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	signals := client.Signals()
+	if client.Signals() != signals {
+		t.Error("Signals() returns another channel")
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+	client.SendGeneralInterrogation()
+
+	for _, address := range []IOA{1, 2, 3} {
+		select {
+		case signal := <-signals:
+			if signal.Address != address {
+				t.Errorf("signal of IOA %d, want %d", signal.Address, address)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("signal of IOA %d isn't streamed", address)
+		}
+	}
+}
+
+func TestClient_CommandRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(c *Client) error
+	}{
+		{"single command", func(c *Client) error { return c.SendSingleCommand(0x6001, true) }},
+		{"double command", func(c *Client) error { return c.SendDoubleCommand(0x6002, true) }},
+		{"set-point command", func(c *Client) error { return c.SendSetpointScaled(0x6201, 100, 0) }},
+		{"reset process command", func(c *Client) error { return c.SendResetProcess(QRPGeneralReset) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the command is rejected by the negative confirmation
+			address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+				return [][]byte{withCOT(asdu, byte(CotActCon)|0x40)}
+			}))
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			if err := tt.send(client); !IsErrCommandRejected(err) {
+				t.Errorf("error = %v, want %v", err, ErrCommandRejected)
+			}
+		})
+	}
+}
+
+func TestClient_SendCommandDirect(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(c *Client) error
+		want []byte
+	}{
+		{
+			"single command",
+			func(c *Client) error { return c.SendSingleCommandDirect(0x6001, true) },
+			[]byte{byte(CScNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x01, 0x60, 0x00, 0x01},
+		},
+		{
+			"double command",
+			func(c *Client) error { return c.SendDoubleCommandDirect(0x6002, false) },
+			[]byte{byte(CDcNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x02, 0x60, 0x00, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []byte, 2)
+			address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+				received <- asdu
+				return [][]byte{withCOT(asdu, byte(CotActCon))}
+			}))
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			if err := tt.send(client); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			// only the execution is sent, whose SE bit is cleared
+			if got := <-received; !bytes.Equal(got, tt.want) {
+				t.Errorf("send [% X], want [% X]", got, tt.want)
+			}
+			select {
+			case got := <-received:
+				t.Errorf("send [% X], want the execution only", got)
+			default:
+			}
+		})
+	}
+}
+
+func TestClient_CommandTimeout(t *testing.T) {
+	// the first command isn't confirmed until the second one is received
+	var unconfirmed []byte
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		if unconfirmed == nil {
+			unconfirmed = asdu
+			return nil
+		}
+		return [][]byte{withCOT(unconfirmed, byte(CotActCon)), withCOT(asdu, byte(CotActCon))}
+	}))
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetCommandTimeout(100 * time.Millisecond)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	if err := client.SendSetpointScaled(0x6201, 100, 0); !IsErrCommandTimeout(err) {
+		t.Fatalf("SendSetpointScaled() error = %v, want %v", err, ErrCommandTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SendSetpointScaled() returns after %s, want about 100ms", elapsed)
+	}
+
+	// the late confirmation doesn't block the client
+	if err := client.SendSetpointScaled(0x6201, 200, 0); err != nil {
+		t.Fatalf("SendSetpointScaled() error = %v", err)
+	}
+	if !client.IsConnected() {
+		t.Errorf("IsConnected() = false after the late confirmation")
+	}
+}
+
+func TestClient_SendClockSync(t *testing.T) {
+	received := make(chan []byte, 1)
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		received <- asdu
+		return [][]byte{withCOT(asdu, byte(CotActCon))}
+	}))
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	ts := time.Date(2022, time.August, 1, 10, 30, 15, 500*int(time.Millisecond), time.Local)
+	if err := client.SendClockSync(ts); err != nil {
+		t.Fatalf("SendClockSync() error = %v", err)
+	}
+
+	asdu := <-received
+	want := append([]byte{byte(CCsNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}, SerializeCP56Time2a(ts)...)
+	if !bytes.Equal(asdu, want) {
+		t.Errorf("send [% X], want [% X]", asdu, want)
+	}
+}
+
+func TestClient_BroadcastClockSync(t *testing.T) {
+	received := make(chan []byte, 1)
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		received <- asdu
+		return nil
+	}))
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	ts := time.Date(2022, time.August, 1, 10, 30, 15, 500*int(time.Millisecond), time.Local)
+	if err := client.BroadcastClockSync(ts); err != nil {
+		t.Fatalf("BroadcastClockSync() error = %v", err)
+	}
+
+	select {
+	case asdu := <-received:
+		want := append([]byte{byte(CCsNa1), 0x01, byte(CotAct), 0x00, 0xff, 0xff, 0x00, 0x00, 0x00}, SerializeCP56Time2a(ts)...)
+		if !bytes.Equal(asdu, want) {
+			t.Errorf("send [% X], want [% X]", asdu, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the clock synchronization isn't broadcast")
+	}
+}
+
+func TestClient_TimeTagInLocation(t *testing.T) {
+	received := make(chan []byte, 1)
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		received <- asdu
+		return nil
+	}))
+
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option.SetTimeLocation(loc))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	ts := time.Date(2022, time.August, 1, 2, 30, 15, 0, time.UTC)
+	if err := client.BroadcastClockSync(ts); err != nil {
+		t.Fatalf("BroadcastClockSync() error = %v", err)
+	}
+
+	select {
+	case asdu := <-received:
+		// 10:30:15 of the station in UTC+8
+		want := []byte{0x98, 0x3a, 0x1e, 0x0a, 0x21, 0x08, 0x16}
+		if got := asdu[len(asdu)-7:]; !bytes.Equal(got, want) {
+			t.Errorf("send CP56Time2a [% X], want [% X]", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the clock synchronization isn't broadcast")
+	}
+}
+
+func TestClient_DataTransfer(t *testing.T) {
+	// silentSubstation confirms the first STARTDT only.
+	silentSubstation := func(conn net.Conn) {
+		header := make([]byte, 6)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+		_, _ = io.Copy(io.Discard, conn)
+	}
+
+	tests := []struct {
+		name    string
+		serve   func(conn net.Conn)
+		wantErr bool
+	}{
+		{"confirmed", confirmingSubstation, false},
+		{"unconfirmed", silentSubstation, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := startTestSubstation(t, tt.serve)
+
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			option.SetStartDTTimeout(100 * time.Millisecond)
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+			if !client.IsDataTransferStarted() {
+				t.Fatal("IsDataTransferStarted() = false after Connect()")
+			}
+
+			err = client.StopDataTransfer(context.Background())
+			if tt.wantErr != IsErrT1Timeout(err) || !tt.wantErr && err != nil {
+				t.Fatalf("StopDataTransfer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := client.IsDataTransferStarted(); got != tt.wantErr {
+				t.Errorf("IsDataTransferStarted() = %v after StopDataTransfer(), want %v", got, tt.wantErr)
+			}
+
+			err = client.StartDataTransfer(context.Background())
+			if tt.wantErr != IsErrT1Timeout(err) || !tt.wantErr && err != nil {
+				t.Fatalf("StartDataTransfer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !client.IsDataTransferStarted() {
+				t.Error("IsDataTransferStarted() = false after StartDataTransfer()")
+			}
+		})
+	}
+}
+
+func TestClient_SendReadCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		address IOA
+		wantErr bool
+	}{
+		{"answered", 5, false},
+		{"unanswered", 6, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []byte, 1)
+			address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+				received <- asdu
+				// MSpNa1, CotReq, IOA 5 is ON
+				return [][]byte{{0x01, 0x01, 0x05, 0x00, 0x01, 0x00, 0x05, 0x00, 0x00, 0x01}}
+			}))
+
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			option.SetStartDTTimeout(100 * time.Millisecond)
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			err = client.SendReadCommand(tt.address)
+			if tt.wantErr != IsErrT1Timeout(err) || !tt.wantErr && err != nil {
+				t.Fatalf("SendReadCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			asdu := <-received
+			want := []byte{byte(CRdNa1), 0x01, byte(CotReq), 0x00, 0x01, 0x00, byte(tt.address), 0x00, 0x00}
+			if !bytes.Equal(asdu, want) {
+				t.Errorf("send [% X], want [% X]", asdu, want)
+			}
+		})
+	}
+}
+
+func TestClient_OnWindowFull(t *testing.T) {
+	acknowledge := make(chan struct{})
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			go func() {
+				<-acknowledge
+				// acknowledge the first I-format frame
+				_, _ = conn.Write(buildFrame((&SFrame{RecvSN: 1}).Data()))
+			}()
+		})
+	})
+
+	full := make(chan struct{}, 1)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetOnWindowFull(func() { full <- struct{}{} })
+	option.SetWindowSizes(2, 1)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	client.SendGeneralInterrogation()
+	client.SendGeneralInterrogation()
+	select {
+	case <-full:
+		t.Fatal("OnWindowFull is called before k I-format frames are sent")
+	default:
+	}
+
+	sent := make(chan struct{})
+	go func() {
+		client.SendGeneralInterrogation()
+		close(sent)
+	}()
+	select {
+	case <-full:
+	case <-time.After(time.Second):
+		t.Fatal("OnWindowFull isn't called when k I-format frames are not acknowledged")
+	}
+	select {
+	case <-sent:
+		t.Fatal("SendIFrame doesn't block when the send window is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(acknowledge)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("SendIFrame is still blocked after the acknowledgement")
+	}
+	if got := client.WindowFullCount(); got != 1 {
+		t.Errorf("WindowFullCount() = %d, want 1", got)
+	}
+}
+
+func TestClient_SendWindowClosed(t *testing.T) {
+	address := startTestSubstation(t, confirmingSubstation)
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option.SetWindowSizes(1, 1))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := client.SendGeneralInterrogation(); err != nil {
+		t.Fatalf("SendGeneralInterrogation() error = %v", err)
+	}
+
+	sent := make(chan error, 1)
+	go func() { sent <- client.SendGeneralInterrogation() }()
+	select {
+	case err := <-sent:
+		t.Fatalf("SendGeneralInterrogation() = %v, want blocking while the send window is full", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_ = client.Close()
+	select {
+	case err := <-sent:
+		if !IsErrConnectionClosed(err) {
+			t.Errorf("SendGeneralInterrogation() error = %v, want %v", err, ErrConnectionClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendGeneralInterrogation() is still blocked after Close")
+	}
+	// the sender blocked doesn't hold up the later ones
+	done := make(chan struct{})
+	go func() {
+		_ = client.SendGeneralInterrogation()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SendGeneralInterrogation() blocks after Close")
+	}
+}
+
+func TestClient_SendWindowReconnected(t *testing.T) {
+	address := startTestSubstation(t, confirmingSubstation)
+
+	connected := make(chan struct{}, 2)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetWindowSizes(1, 1).
+		SetStartDTTimeout(100 * time.Millisecond).
+		SetAutoReconnectRule(NewAutoReconnectRule(0, 10*time.Millisecond)).
+		SetOnConnectHandler(func(c *Client) { connected <- struct{}{} })
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+	<-connected
+	if err := client.SendGeneralInterrogation(); err != nil {
+		t.Fatalf("SendGeneralInterrogation() error = %v", err)
+	}
+
+	// the interrogation isn't acknowledged within t1, so the connection is lost and reconnected
+	sent := make(chan error, 1)
+	go func() { sent <- client.SendGeneralInterrogation() }()
+	select {
+	case err := <-sent:
+		if !IsErrConnectionClosed(err) {
+			t.Errorf("SendGeneralInterrogation() error = %v, want %v", err, ErrConnectionClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendGeneralInterrogation() is still blocked after the connection is lost")
+	}
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("the client isn't reconnected")
+	}
+	if err := client.SendGeneralInterrogation(); err != nil {
+		t.Errorf("SendGeneralInterrogation() error = %v after reconnecting", err)
+	}
+}
+
+func TestClient_SendSetpoint(t *testing.T) {
+	received := make(chan []byte, 1)
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		received <- asdu
+		return [][]byte{withCOT(asdu, byte(CotActCon))}
+	}))
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		name string
+		send func() error
+		want []byte
+	}{
+		{
+			"normalized",
+			func() error { return client.SendSetpointNormalized(0x6001, 0.5, 0x00) },
+			[]byte{byte(CSeNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x01, 0x60, 0x00, 0x00, 0x40, 0x00},
+		},
+		{
+			"scaled",
+			func() error { return client.SendSetpointScaled(0x6002, -2, 0x80) },
+			[]byte{byte(CSeNb1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x02, 0x60, 0x00, 0xfe, 0xff, 0x80},
+		},
+		{
+			"short float",
+			func() error { return client.SendSetpointShortFloat(0x6003, 230.5, 0x00) },
+			[]byte{byte(CSeNc1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x03, 0x60, 0x00, 0x00, 0x80, 0x66, 0x43, 0x00},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.send(); err != nil {
+				t.Fatalf("send error = %v", err)
+			}
+			if asdu := <-received; !bytes.Equal(asdu, tt.want) {
+				t.Errorf("send [% X], want [% X]", asdu, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_SendCommandsConcurrently(t *testing.T) {
+	const goroutines, sends = 8, 10
+	type command struct {
+		ssn     uint16
+		typeID  TypeID
+		address IOA
+		selects bool
+	}
+	commands := make(chan command, 2*goroutines*sends)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		ssn, rsn := uint16(0), uint16(0)
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			switch {
+			case body[0] == UFrameFunctionStartDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+			case body[0] == UFrameFunctionStopDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+			case body[0]&0b1 == 0:
+				asdu := body[ApduHeaderLen:]
+				commands <- command{
+					ssn:     parseLittleEndianUint16(body[0:2]) >> 1,
+					typeID:  TypeID(asdu[0]),
+					address: IOA(asdu[6]) | IOA(asdu[7])<<8 | IOA(asdu[8])<<16,
+					selects: asdu[9]&0x80 != 0,
+				}
+				// confirm the command, and acknowledge it by N(R)
+				rsn = seqNext(rsn)
+				_, _ = conn.Write(buildFrame(append((&IFrame{SendSN: ssn, RecvSN: rsn}).Data(),
+					withCOT(asdu, byte(CotActCon))...)))
+				ssn = seqNext(ssn)
+			}
+		}
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetCommandTimeout(time.Second)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*sends)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(address IOA) {
+			defer wg.Done()
+			for j := 0; j < sends; j++ {
+				if j%2 == 0 {
+					errs <- client.SendSingleCommand(address, true)
+				} else {
+					errs <- client.SendDoubleCommand(address, false)
+				}
+			}
+		}(IOA(0x6001 + i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("send command error = %v", err)
+		}
+	}
+
+	// the frames are numbered in order, and the execution of each command follows its selection
+	for want := uint16(0); want < 2*goroutines*sends; want += 2 {
+		selection, execution := <-commands, <-commands
+		if selection.ssn != want || execution.ssn != want+1 {
+			t.Fatalf("receive N(S)=%d and %d, want %d and %d", selection.ssn, execution.ssn, want, want+1)
+		}
+		if !selection.selects || execution.selects || selection.typeID != execution.typeID ||
+			selection.address != execution.address {
+			t.Fatalf("receive %+v after %+v, want the execution of the selected command", execution, selection)
+		}
+	}
+}
+
+func TestClient_SendBitstringCommand(t *testing.T) {
+	received := make(chan []byte, 1)
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		received <- asdu
+		con := withCOT(asdu, byte(CotActCon))
+		if con[6] == 0x02 {
+			con[9] = 0x00 // the second address applies other bits
+		}
+		return [][]byte{con}
+	}))
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SendBitstringCommand(0x6001, 0x12345678); err != nil {
+		t.Fatalf("SendBitstringCommand() error = %v", err)
+	}
+	want := []byte{byte(CBoNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x01, 0x60, 0x00, 0x78, 0x56, 0x34, 0x12}
+	if asdu := <-received; !bytes.Equal(asdu, want) {
+		t.Errorf("send [% X], want [% X]", asdu, want)
+	}
+
+	if err := client.SendBitstringCommand(0x6002, 0x12345678); err == nil {
+		t.Error("SendBitstringCommand() confirmed with other bits error = nil")
+	}
+	<-received
+}
+
+func TestClient_SendStepCommand(t *testing.T) {
+	received := make(chan []byte, 2)
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		received <- asdu
+		return [][]byte{withCOT(asdu, byte(CotActCon))}
+	}))
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		name          string
+		step          StepDirection
+		selectExecute bool
+		want          []byte // RCO sent in order
+		wantErr       bool
+	}{
+		{"select and execute lower", StepLower, true, []byte{0x81, 0x01}, false},
+		{"select and execute higher", StepHigher, true, []byte{0x82, 0x02}, false},
+		{"execute higher", StepHigher, false, []byte{0x02}, false},
+		{"invalid direction", StepDirection(0b11), false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.SendStepCommand(0x6201, tt.step, tt.selectExecute)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendStepCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, rco := range tt.want {
+				want := []byte{byte(CRcNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x01, 0x62, 0x00, rco}
+				if asdu := <-received; !bytes.Equal(asdu, want) {
+					t.Errorf("send [% X], want [% X]", asdu, want)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_AcknowledgeAfterW(t *testing.T) {
+	acks := make(chan uint16, 4)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			switch {
+			case body[0] == UFrameFunctionStartDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+				// CIcNa1, CotActCon, which isn't acknowledged immediately
+				for ssn := uint16(0); ssn < 5; ssn++ {
+					_, _ = conn.Write(iFrame(ssn, []byte{0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}))
+				}
+			case body[0] == UFrameFunctionStopDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+			case FrameType(body[0]&0x3) == FrameTypeS:
+				frame, _ := new(APCI).Parse(body)
+				acks <- frame.(*SFrame).RecvSN
+			}
+		}
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetWindowSizes(4, 2)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	for _, want := range []uint16{2, 4} {
+		select {
+		case got := <-acks:
+			if got != want {
+				t.Errorf("acknowledge N(R) = %d, want %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no acknowledgement of N(R) = %d after w I-format frames", want)
+		}
+	}
+	select {
+	case got := <-acks:
+		t.Errorf("acknowledge N(R) = %d before w I-format frames are received", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_SendWindowK(t *testing.T) {
+	const k = 3
+	received := make(chan uint16, 2*k)
+	acknowledge := make(chan uint16)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			go func() {
+				for rsn := range acknowledge {
+					_, _ = conn.Write(buildFrame((&SFrame{RecvSN: rsn}).Data()))
+				}
+			}()
+		})
+	})
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetWindowSizes(k, 1)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+	defer close(acknowledge)
+
+	go func() {
+		for i := 0; i < 2*k; i++ {
+			client.SendGeneralInterrogation()
+			received <- uint16(i + 1)
+		}
+	}()
+
+	for want := uint16(1); want <= k; want++ {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("I-format frame %d is sent, want %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("I-format frame %d within the window k isn't sent", want)
+		}
+	}
+	select {
+	case got := <-received:
+		t.Fatalf("I-format frame %d is sent with k I-format frames not acknowledged", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	acknowledge <- k
+	for want := uint16(k + 1); want <= 2*k; want++ {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("I-format frame %d is sent, want %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("I-format frame %d isn't sent after the acknowledgement", want)
+		}
+	}
+}
+
+func TestClient_AcknowledgeConfirmation(t *testing.T) {
+	recvSNs := make(chan uint16, 1)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		ssn := uint16(0)
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			switch {
+			case body[0] == UFrameFunctionStartDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+			case body[0] == UFrameFunctionStopDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+			case FrameType(body[0]&0x1) == FrameTypeI:
+				frame, _ := new(APCI).Parse(body[:ApduHeaderLen])
+				recvSNs <- frame.(*IFrame).RecvSN
+				con := &IFrame{SendSN: ssn, RecvSN: seqNext(frame.(*IFrame).SendSN)}
+				_, _ = conn.Write(buildFrame(append(con.Data(), withCOT(body[ApduHeaderLen:], byte(CotActCon))...)))
+				ssn++
+			}
+		}
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	// the frame sent right after the confirmation acknowledges it
+	for want := uint16(0); want < 20; want++ {
+		if err := client.SendSetpointScaled(0x6001, 1, 0x00); err != nil {
+			t.Fatalf("SendSetpointScaled() error = %v", err)
+		}
+		if got := <-recvSNs; got != want {
+			t.Errorf("N(R) of command %d = %d, want %d", want, got, want)
+		}
+	}
+}
+
+func TestClient_ReleaseAcknowledgedFrames(t *testing.T) {
+	acknowledge := make(chan []byte)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			go func() {
+				for frame := range acknowledge {
+					_, _ = conn.Write(frame)
+				}
+			}()
+		})
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+	defer close(acknowledge)
+
+	for i := 0; i < 3; i++ {
+		client.SendGeneralInterrogation()
+	}
+	if got := client.outstandingFrames(); got != 3 {
+		t.Fatalf("outstandingFrames() = %d, want 3", got)
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  int
+	}{
+		{"s frame", buildFrame((&SFrame{RecvSN: 2}).Data()), 1},
+		{"out of the window", buildFrame((&SFrame{RecvSN: 5}).Data()), 1},
+		// CIcNa1, CotActCon with N(R)=3
+		{"i frame", buildFrame(append((&IFrame{RecvSN: 3}).Data(), 0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14)), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acknowledge <- tt.frame
+			deadline := time.Now().Add(time.Second)
+			for client.outstandingFrames() != tt.want && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if got := client.outstandingFrames(); got != tt.want {
+				t.Errorf("outstandingFrames() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_AcknowledgementTimeout(t *testing.T) {
+	closed := make(chan struct{})
+	address := startTestSubstation(t, func(conn net.Conn) {
+		// never acknowledge I-format frames
+		confirmingSubstation(conn)
+		close(closed)
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetStartDTTimeout(100 * time.Millisecond)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	client.SendGeneralInterrogation()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("the connection isn't closed when the I-format frame isn't acknowledged within t1")
+	}
+}
+
+func TestClient_ConnectionClosedDuringCommand(t *testing.T) {
+	address := startTestSubstation(t, func(conn net.Conn) {
+		// close the connection when the command is received
+		answeringSubstation(func(asdu []byte) [][]byte {
+			_ = conn.Close()
+			return nil
+		})(conn)
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.SendSingleCommand(0x6001, true)
+	}()
+	select {
+	case err := <-done:
+		if !IsErrConnectionClosed(err) {
+			t.Errorf("SendSingleCommand() error = %v, want %v", err, ErrConnectionClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendSingleCommand() is still waiting after the connection is closed")
+	}
+}
+
+func TestClient_OriginatorMatching(t *testing.T) {
+	tests := []struct {
+		name     string
+		matching bool
+		wantErr  bool
+	}{
+		// the negative confirmation to another controlling station is taken
+		{"disabled", false, true},
+		{"enabled", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+				other := withCOT(asdu, byte(CotActCon)|0x40)
+				other[3] = 0x05 // ORG
+				return [][]byte{other, withCOT(asdu, byte(CotActCon))}
+			}))
+
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			option.SetOriginatorMatching(tt.matching)
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			if err := client.SendTestCommand(); (err != nil) != tt.wantErr {
+				t.Errorf("SendTestCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_CommandCorrelation(t *testing.T) {
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		con := withCOT(asdu, byte(CotActCon))
+		// the confirmation of another address, whose DCS isn't permitted
+		other := append([]byte(nil), con...)
+		other[6], other[9] = other[6]+1, other[9]|0b11
+		answers := [][]byte{other, con}
+		if asdu[9]&0x80 == 0 {
+			// the termination of the execution isn't waited by any command
+			answers = append(answers, withCOT(asdu, byte(CotActTerm)))
+		}
+		return answers
+	}))
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetCommandTimeout(time.Second)
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := client.SendDoubleCommand(0x6001, true); err != nil {
+			t.Fatalf("SendDoubleCommand() %d error = %v", i, err)
+		}
+	}
+}
+
+type slowHandler struct {
+	BaseHandler
+	handled chan struct{}
+	release chan struct{}
+}
+
+func (h *slowHandler) APDUHandler(apdu *APDU) error {
+	<-h.release
+	h.handled <- struct{}{}
+	return nil
+}
+
+func TestClient_DataFullPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      DataFullPolicy
+		wantHandled int
+		wantDropped uint64
+		wantClosed  bool
+	}{
+		{"block", DataFullPolicyBlock, 4, 0, false},
+		{"drop", DataFullPolicyDrop, 2, 2, false},
+		{"close", DataFullPolicyClose, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			closed := make(chan struct{})
+			address := startTestSubstation(t, func(conn net.Conn) {
+				serveTestSubstation(conn, func(conn net.Conn) {
+					// MSpNa1, CotSpont, IOA 1 is ON
+					for ssn := uint16(0); ssn < 4; ssn++ {
+						_, _ = conn.Write(iFrame(ssn, []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}))
+					}
+				})
+				close(closed)
+			})
+
+			handler := &slowHandler{handled: make(chan struct{}, 4), release: make(chan struct{})}
+			option, err := NewClientOption(address, handler)
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			option.SetDataBuffer(1, tt.policy)
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			// the handler blocks on the first APDU, the buffer is full with the second one
+			select {
+			case <-closed:
+				if !tt.wantClosed {
+					t.Fatal("the connection is closed when the data buffer is full")
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tt.wantClosed {
+					t.Fatal("the connection isn't closed when the data buffer is full")
+				}
+			}
+			if got := client.DroppedDataCount(); got != tt.wantDropped {
+				t.Errorf("DroppedDataCount() = %d, want %d", got, tt.wantDropped)
+			}
+
+			close(handler.release)
+			if tt.wantClosed {
+				// the buffered APDU may be discarded with the connection
+				return
+			}
+			for i := 0; i < tt.wantHandled; i++ {
+				select {
+				case <-handler.handled:
+				case <-time.After(time.Second):
+					t.Fatalf("handled %d APDUs, want %d", i, tt.wantHandled)
+				}
+			}
+			select {
+			case <-handler.handled:
+				t.Errorf("handled more than %d APDUs", tt.wantHandled)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestClient_SendResetProcess(t *testing.T) {
+	tests := []struct {
+		name    string
+		qrp     byte
+		cot     byte
+		wantErr bool
+	}{
+		{"general reset", QRPGeneralReset, byte(CotActCon), false},
+		{"reset event buffer", QRPResetEventBuffer, byte(CotActCon), false},
+		{"negative confirmation", QRPGeneralReset, byte(CotActCon) | 0x40, true},
+		{"not used qualifier", 0, byte(CotActCon), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []byte, 1)
+			address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+				received <- asdu
+				return [][]byte{withCOT(asdu, tt.cot)}
+			}))
+
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			if err := client.SendResetProcess(tt.qrp); (err != nil) != tt.wantErr {
+				t.Fatalf("SendResetProcess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.qrp == 0 {
+				return
+			}
+			want := []byte{byte(CRpNc1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, tt.qrp}
+			if asdu := <-received; !bytes.Equal(asdu, want) {
+				t.Errorf("send [% X], want [% X]", asdu, want)
+			}
+		})
+	}
+}
+
+func TestClient_SendDelayAcquisition(t *testing.T) {
+	received := make(chan []byte, 1)
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		received <- asdu
+		return nil
+	}))
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	for _, delay := range []time.Duration{-time.Millisecond, 65536 * time.Millisecond} {
+		if err := client.SendDelayAcquisition(delay); err == nil {
+			t.Errorf("SendDelayAcquisition(%s) error = nil, want error", delay)
+		}
+	}
+	if err := client.SendDelayAcquisition(1234 * time.Millisecond); err != nil {
+		t.Fatalf("SendDelayAcquisition() error = %v", err)
+	}
+	want := []byte{byte(CCdNa1), 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0xd2, 0x04}
+	select {
+	case asdu := <-received:
+		if !bytes.Equal(asdu, want) {
+			t.Errorf("send [% X], want [% X]", asdu, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("delay acquisition command isn't sent")
+	}
+}
+
+type cyclicHandler struct {
+	BaseHandler
+	cyclic, spontaneous int
+}
+
+func (h *cyclicHandler) CyclicDataHandler(apdu *APDU) error {
+	h.cyclic++
+	return nil
+}
+
+func (h *cyclicHandler) APDUHandler(apdu *APDU) error {
+	h.spontaneous++
+	return nil
+}
+
+func TestHandleClientData_Cyclic(t *testing.T) {
+	tests := []struct {
+		name       string
+		asdu       []byte
+		wantCyclic bool
+	}{
+		{"single point cyclic", []byte{0x01, 0x01, byte(CotPerCyc), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}, true},
+		{"single point background", []byte{0x01, 0x01, byte(CotBack), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}, true},
+		{"single point spontaneous", []byte{0x01, 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}, false},
+		{"double point cyclic", []byte{0x03, 0x01, byte(CotPerCyc), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x02}, true},
+		{"normalized value cyclic", []byte{0x09, 0x01, byte(CotPerCyc), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x40, 0x00}, true},
+		{"normalized value spontaneous", []byte{0x09, 0x01, byte(CotSpont), 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x40, 0x00}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu := new(APDU)
+			if err := apdu.Parse(append((&IFrame{}).Data(), tt.asdu...)); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			h := &cyclicHandler{}
+			if err := handleClientData(h, apdu); err != nil {
+				t.Fatalf("handleClientData() error = %v", err)
+			}
+			if got := h.cyclic == 1 && h.spontaneous == 0; got != tt.wantCyclic {
+				t.Errorf("dispatched to CyclicDataHandler %d times and APDUHandler %d times, want cyclic %v",
+					h.cyclic, h.spontaneous, tt.wantCyclic)
+			}
+		})
+	}
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	accepted := make(chan int32, 4)
+	var n int32
+	address := startTestSubstation(t, func(conn net.Conn) {
+		i := atomic.AddInt32(&n, 1)
+		accepted <- i
+		if i == 1 {
+			// lose the first connection after the data transfer is started
+			serveTestSubstation(conn, func(conn net.Conn) { _ = conn.Close() })
+			return
+		}
+		answeringSubstation(func(asdu []byte) [][]byte {
+			return [][]byte{withCOT(asdu, byte(CotActCon))}
+		})(conn)
+	})
+
+	connected, disconnected := make(chan struct{}, 4), make(chan struct{}, 4)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetAutoReconnectRule(NewAutoReconnectRule(3, 10*time.Millisecond)).
+		SetOnConnectHandler(func(c *Client) { connected <- struct{}{} }).
+		SetOnDisconnectHandler(func(c *Client) { disconnected <- struct{}{} })
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	for _, event := range []struct {
+		name string
+		ch   chan struct{}
+	}{
+		{"connect", connected}, {"disconnect", disconnected}, {"reconnect", connected},
+	} {
+		select {
+		case <-event.ch:
+		case <-time.After(time.Second):
+			t.Fatalf("no %s", event.name)
+		}
+	}
+	if got := <-accepted + <-accepted; got != 3 {
+		t.Fatalf("accepted connections #%d, want #1 and #2", got)
+	}
+	if !client.IsConnected() {
+		t.Error("IsConnected() = false after reconnecting")
+	}
+	// the sequence numbers are restored to zero for the new connection
+	if err := client.SendClockSync(time.Now()); err != nil {
+		t.Fatalf("SendClockSync() error = %v", err)
+	}
+	if ssn, _ := client.seq(); ssn != 1 {
+		t.Errorf("N(S) = %d after reconnecting and sending one I-format frame, want 1", ssn)
+	}
+}
+
+// printLogger sends the messages printed by Printf.
+type printLogger struct {
+	nopLogger
+	printed chan string
+}
+
+func (l *printLogger) Printf(format string, args ...interface{}) {
+	l.printed <- fmt.Sprintf(format, args...)
+}
+
+func TestClient_DefaultConnectionHandlers(t *testing.T) {
+	var n int32
+	address := startTestSubstation(t, func(conn net.Conn) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			// lose the first connection after the data transfer is started
+			serveTestSubstation(conn, func(conn net.Conn) { _ = conn.Close() })
+			return
+		}
+		confirmingSubstation(conn)
+	})
+
+	lg := &printLogger{printed: make(chan string, 8)}
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetLogger(lg).SetAutoReconnectRule(NewAutoReconnectRule(1, 10*time.Millisecond))
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	// the handlers print the remote address while the connection is replaced by reconnecting
+	for _, want := range []string{"connected with ", "disconnected with ", "connected with "} {
+		select {
+		case got := <-lg.printed:
+			if got != want+address {
+				t.Errorf("print %q, want %q", got, want+address)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%q isn't printed", want+address)
+		}
+	}
+	_ = client.Close()
+	if got := <-lg.printed; got != "disconnected with "+address {
+		t.Errorf("print %q after Close, want %q", got, "disconnected with "+address)
+	}
+}
+
+func TestClient_IsConnected(t *testing.T) {
+	conns := make(chan net.Conn, 4)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		conns <- conn
+		confirmingSubstation(conn)
+	})
+
+	disconnected := make(chan struct{}, 1)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetAutoReconnectRule(NewAutoReconnectRule(1, time.Hour)).
+		SetOnDisconnectHandler(func(c *Client) { disconnected <- struct{}{} })
+	client := NewClient(option)
+	if client.IsConnected() {
+		t.Error("IsConnected() = true before Connect")
+	}
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	<-conns
+	if !client.IsConnected() {
+		t.Error("IsConnected() = false after Connect")
+	}
+	client.Close()
+	<-disconnected
+	if client.IsConnected() {
+		t.Error("IsConnected() = true after Close")
+	}
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v after Close", err)
+	}
+	defer client.Close()
+	conn := <-conns
+	if !client.IsConnected() {
+		t.Error("IsConnected() = false after connecting again")
+	}
+	_ = conn.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("the lost connection isn't noticed")
+	}
+	if client.IsConnected() {
+		t.Error("IsConnected() = true after the connection is lost")
+	}
+}
+
+func TestClient_IsConnectedAfterFailure(t *testing.T) {
+	// the substation never confirms STARTDT
+	address := startTestSubstation(t, func(conn net.Conn) { _, _ = io.Copy(io.Discard, conn) })
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetStartDTTimeout(50 * time.Millisecond)
+	client := NewClient(option)
+	defer client.Close()
+	if err := client.Connect(); err == nil {
+		t.Fatal("Connect() error = nil without STARTDT con")
+	}
+	if client.IsConnected() {
+		t.Error("IsConnected() = true after Connect fails")
+	}
+}
+
+func TestClient_CloseTwice(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(conn net.Conn)
+	}{
+		{"connected", confirmingSubstation},
+		{"connection lost", func(conn net.Conn) {
+			serveTestSubstation(conn, func(conn net.Conn) { _ = conn.Close() })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := startTestSubstation(t, tt.serve)
+
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			option.SetAutoReconnectRule(NewAutoReconnectRule(1, time.Hour))
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+
+			closed := make(chan struct{})
+			go func() {
+				client.Close()
+				client.Close()
+				close(closed)
+			}()
+			select {
+			case <-closed:
+			case <-time.After(2 * time.Second):
+				t.Fatal("Close() blocks")
+			}
+			client.SendGeneralInterrogation() // dropped without blocking
+		})
+	}
+}
+
+// closeErrConn closes the underlying connection, but returns err.
+type closeErrConn struct {
+	net.Conn
+	err error
+}
+
+func (c *closeErrConn) Close() error {
+	_ = c.Conn.Close()
+	return c.err
+}
+
+func TestClient_CloseError(t *testing.T) {
+	// unstoppableSubstation confirms STARTDT, but never confirms STOPDT.
+	unstoppableSubstation := func(conn net.Conn) {
+		header := make([]byte, 6)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+		_, _ = io.Copy(io.Discard, conn)
+	}
+
+	tests := []struct {
+		name    string
+		serve   func(conn net.Conn)
+		wantErr func(err error) bool
+	}{
+		{"clean shutdown", confirmingSubstation, nil},
+		{"unconfirmed STOPDT", unstoppableSubstation, IsErrT1Timeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := startTestSubstation(t, tt.serve)
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			option.SetStartDTTimeout(100 * time.Millisecond)
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+
+			if err := client.Close(); tt.wantErr == nil && err != nil || tt.wantErr != nil && !tt.wantErr(err) {
+				t.Errorf("Close() error = %v", err)
+			}
+			if err := client.Close(); err != nil {
+				t.Errorf("Close() error = %v after closed", err)
+			}
+		})
+	}
+
+	t.Run("failed socket close", func(t *testing.T) {
+		address := startTestSubstation(t, confirmingSubstation)
+		option, err := NewClientOption(address, &BaseHandler{})
+		if err != nil {
+			t.Fatalf("NewClientOption() error = %v", err)
+		}
+		errClose := errors.New("close failed")
+		client := NewClient(option)
+		client.dialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			return &closeErrConn{Conn: conn, err: errClose}, nil
+		}
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Connect() error = %v", err)
+		}
+		if err := client.Close(); !errors.Is(err, errClose) {
+			t.Errorf("Close() error = %v, want %v", err, errClose)
+		}
+	})
+
+	t.Run("failed connect", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+		address := listener.Addr().String()
+		_ = listener.Close()
+
+		option, err := NewClientOption(address, &BaseHandler{})
+		if err != nil {
+			t.Fatalf("NewClientOption() error = %v", err)
+		}
+		client := NewClient(option)
+		if err := client.Connect(); err == nil {
+			t.Fatal("Connect() error = nil, want the refused connection")
+		}
+		if err := client.Close(); err != nil {
+			t.Errorf("Close() error = %v after failed connect", err)
+		}
+	})
+}
+
+func TestClient_MaxIdle(t *testing.T) {
+	stopped := make(chan struct{}, 1)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		for {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			switch body[0] {
+			case UFrameFunctionStartDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStartDTC))
+			case UFrameFunctionStopDTA[0]:
+				_, _ = conn.Write(buildFrame(UFrameFunctionStopDTC))
+				stopped <- struct{}{}
+			}
+		}
+	})
+
+	disconnected := make(chan struct{}, 1)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetMaxIdle(200 * time.Millisecond).
+		SetOnDisconnectHandler(func(c *Client) { disconnected <- struct{}{} })
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+	start := time.Now()
+
+	// the data sent postpones closing
+	time.Sleep(100 * time.Millisecond)
+	client.SendGeneralInterrogation()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the idle connection isn't stopped by STOPDT")
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("the connection is closed after %s, want 300ms at least", elapsed)
+	}
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("the idle connection isn't closed")
+	}
+	if client.IsConnected() {
+		t.Error("IsConnected() = true after the idle connection is closed")
+	}
+}
+
+func TestClient_CloseStopsReconnecting(t *testing.T) {
+	accepted := make(chan struct{}, 4)
+	address := startTestSubstation(t, func(conn net.Conn) {
+		accepted <- struct{}{}
+		serveTestSubstation(conn, func(conn net.Conn) { _ = conn.Close() })
+	})
+
+	disconnected := make(chan struct{}, 1)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetAutoReconnectRule(NewAutoReconnectRule(0, 50*time.Millisecond)).
+		SetOnDisconnectHandler(func(c *Client) { disconnected <- struct{}{} })
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	<-accepted
+
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("the lost connection isn't noticed")
+	}
+	client.Close()
+	if client.IsConnected() {
+		t.Error("IsConnected() = true after Close")
+	}
+	select {
+	case <-accepted:
+		t.Error("reconnect after Close")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestClient_OnErrorHandler(t *testing.T) {
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) { _ = conn.Close() })
+	})
+
+	errs := make(chan error, 4)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetAutoReconnectRule(NewAutoReconnectRule(1, time.Minute)).
+		SetOnDisconnectHandler(func(c *Client) {}).
+		SetOnErrorHandler(func(c *Client, err error) { errs <- err })
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("OnErrorHandler is called with %v, want %v", err, io.EOF)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnErrorHandler isn't called when the connection is lost")
+	}
+	select {
+	case err := <-errs:
+		t.Errorf("OnErrorHandler is called again with %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_MalformedFrame(t *testing.T) {
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			// the length of APDU is shorter than the control fields
+			_, _ = conn.Write([]byte{startByte, 0x02, 0x01, 0x00})
+		})
+	})
+
+	errs := make(chan error, 4)
+	disconnected := make(chan struct{}, 1)
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetOnDisconnectHandler(func(c *Client) { disconnected <- struct{}{} }).
+		SetOnErrorHandler(func(c *Client, err error) { errs <- err })
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	// the read error is reported instead of panicking
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("OnErrorHandler is called with nil error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnErrorHandler isn't called for the malformed frame")
+	}
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("OnDisconnectHandler isn't called for the malformed frame")
+	}
+}
+
+func TestClient_SequenceValidation(t *testing.T) {
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			// the IOA of the spontaneous single point information is the send sequence number N(S)
+			for _, ssn := range []uint16{0, 0, 1, 3} {
+				asdu := []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, byte(ssn), 0x00, 0x00, 0x01}
+				_, _ = conn.Write(buildFrame(append((&IFrame{SendSN: ssn}).Data(), asdu...)))
+			}
+		})
+	})
+
+	errs := make(chan error, 4)
+	handler := &dataHandler{data: make(chan *APDU, 4)}
+	option, err := NewClientOption(address, handler)
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	option.SetOnErrorHandler(func(c *Client, err error) { errs <- err })
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	// the frame received again is dropped
+	for _, want := range []IOA{0, 1} {
+		select {
+		case apdu := <-handler.data:
+			if got := apdu.Signals[0].Address; got != want {
+				t.Errorf("handled N(S) = %d, want %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("N(S)=%d isn't handled", want)
+		}
+	}
+
+	// the gap from N(S)=1 to N(S)=3 closes the connection, and the frame after the gap isn't handled
+	select {
+	case err := <-errs:
+		if !IsErrSequence(err) {
+			t.Errorf("OnErrorHandler() error = %v, want %v", err, ErrSequence)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnErrorHandler isn't called for the lost frame")
+	}
+	select {
+	case apdu := <-handler.data:
+		t.Errorf("N(S)=%d is handled after the gap", apdu.Signals[0].Address)
+	default:
+	}
+}
+
+func TestClient_DryRun(t *testing.T) {
+	option, err := NewClientOption("127.0.0.1:2404", &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option.SetDryRun(true))
+	if got := client.LastBuiltFrame(); got != nil {
+		t.Fatalf("LastBuiltFrame() = [% X], want nil", got)
+	}
+
+	client.SendGeneralInterrogation()
+	want := []byte{0x68, 0x0e, 0x00, 0x00, 0x00, 0x00, 0x64, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}
+	if got := client.LastBuiltFrame(); !bytes.Equal(got, want) {
+		t.Errorf("LastBuiltFrame() = [% X], want [% X]", got, want)
+	}
+
+	// the send sequence number is increased, and the command doesn't wait for the confirmation
+	if err := client.SendSingleCommand(0x6001, true); err != nil {
+		t.Fatalf("SendSingleCommand() error = %v", err)
+	}
+	want = []byte{0x68, 0x0e, 0x04, 0x00, 0x00, 0x00, 0x2d, 0x01, 0x06, 0x00, 0x01, 0x00, 0x01, 0x60, 0x00, 0x01}
+	if got := client.LastBuiltFrame(); !bytes.Equal(got, want) {
+		t.Errorf("LastBuiltFrame() = [% X], want [% X]", got, want)
+	}
+
+	// the ASDU whose COA exceeds 1-byte COA isn't sent but fails
+	client = NewClient(option.SetCOALength(1))
+	asdu, err := NewASDU(MSpNa1, CotSpont).SetCommonAddress(0x1234).AddObject(1, 1).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if err := client.SendIFrame(asdu); err == nil {
+		t.Error("SendIFrame() error = nil, want the COA exceeding 1-byte COA")
+	}
+	if got := client.LastBuiltFrame(); got != nil {
+		t.Errorf("LastBuiltFrame() = [% X], want nil", got)
+	}
+
+	// the commands fail as well instead of waiting for the confirmation
+	client = NewClient(option.SetCommonAddress(0x1234))
+	if err := client.SendSetpointScaled(0x6201, 100, 0); err == nil {
+		t.Error("SendSetpointScaled() error = nil, want the COA exceeding 1-byte COA")
+	}
+	if err := client.SendGeneralInterrogation(); err == nil {
+		t.Error("SendGeneralInterrogation() error = nil, want the COA exceeding 1-byte COA")
+	}
+	if got := client.LastBuiltFrame(); got != nil {
+		t.Errorf("LastBuiltFrame() = [% X], want nil", got)
+	}
+}
+
+func TestClient_sendAck(t *testing.T) {
+	option, err := NewClientOption("127.0.0.1:2404", &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option.SetDryRun(true))
+	client.incRsn(0)
+	client.incRsn(1)
+
+	client.sendAck()
+	want := buildFrame((&SFrame{RecvSN: 2}).Data())
+	if got := client.LastBuiltFrame(); !bytes.Equal(got, want) {
+		t.Errorf("LastBuiltFrame() = [% X], want [% X]", got, want)
+	}
+	// the deprecated alias sends the acknowledgement as well
+	client.incRsn(2)
+	client.SendTestFrame()
+	want = buildFrame((&SFrame{RecvSN: 3}).Data())
+	if got := client.LastBuiltFrame(); !bytes.Equal(got, want) {
+		t.Errorf("LastBuiltFrame() = [% X], want [% X]", got, want)
+	}
+}
+
+func TestClientOption_SetAddresses(t *testing.T) {
+	tests := []struct {
+		name string
+		org  ORG
+		coa  COA
+		want []byte // ORG and COA of the ASDU sent
+	}{
+		{"station address", 2, 0x1234, []byte{0x02, 0x34, 0x12}},
+		{"unused address ignored", 0, 0, []byte{0x00, 0x01, 0x00}},
+		{"global address ignored", 0, GlobalCOA, []byte{0x00, 0x01, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, err := NewClientOption("127.0.0.1:2404", &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			client := NewClient(option.SetDryRun(true).SetOriginatorAddress(tt.org).SetCommonAddress(tt.coa))
+			client.SendGeneralInterrogation()
+			if got := client.LastBuiltFrame()[9:12]; !bytes.Equal(got, tt.want) {
+				t.Errorf("ORG and COA = [% X], want [% X]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_SendCommandQualifier(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(c *Client) error
+		want byte // SCO, DCO or RCO of the execution
+	}{
+		{"single command by default", func(c *Client) error { return c.SendSingleCommand(0x6001, true) }, 0x01},
+		{"single command of short pulse", func(c *Client) error {
+			return c.SendSingleCommand(0x6001, true, QUShortPulse)
+		}, 0x05},
+		{"direct single command of persistent output", func(c *Client) error {
+			return c.SendSingleCommandDirect(0x6001, false, QUPersistent)
+		}, 0x0c},
+		{"double command of long pulse", func(c *Client) error {
+			return c.SendDoubleCommand(0x6002, true, QULongPulse)
+		}, 0x0a},
+		{"direct double command of short pulse", func(c *Client) error {
+			return c.SendDoubleCommandDirect(0x6002, false, QUShortPulse)
+		}, 0x05},
+		{"step command of long pulse", func(c *Client) error {
+			return c.SendStepCommand(0x6003, StepHigher, true, QULongPulse)
+		}, 0x0a},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, err := NewClientOption("127.0.0.1:2404", &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			client := NewClient(option.SetDryRun(true))
+			if err := tt.send(client); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			frame := client.LastBuiltFrame()
+			if got := frame[len(frame)-1]; got != tt.want {
+				t.Errorf("command = %02X, want %02X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_SendCommandWithTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		send   func(c *Client) error
+		typeID TypeID
+		sent   int // number of ASDUs sent, 2 for select and execute
+	}{
+		{"single command", func(c *Client) error {
+			return c.SendSingleCommandWithTime(0x6001, true, QUShortPulse)
+		}, CScTa1, 2},
+		{"double command", func(c *Client) error { return c.SendDoubleCommandWithTime(0x6002, false) }, CDcTa1, 2},
+		{"set-point command", func(c *Client) error {
+			return c.SendSetpointShortFloatWithTime(0x6201, 230.5, 0)
+		}, CSeTc1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []byte, 2)
+			address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+				received <- asdu
+				return [][]byte{withCOT(asdu, byte(CotActCon))}
+			}))
+			option, err := NewClientOption(address, &BaseHandler{})
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			client := NewClient(option)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			start := time.Now().Truncate(time.Millisecond)
+			if err := tt.send(client); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			for i := 0; i < tt.sent; i++ {
+				asdu := <-received
+				if TypeID(asdu[0]) != tt.typeID || len(asdu) != 6+IOALength+elementLen[tt.typeID] {
+					t.Fatalf("send [% X], want TypeID[%X] with CP56Time2a", asdu, uint8(tt.typeID))
+				}
+				ts, iv, _, _ := decodeCP56Time2a(asdu[len(asdu)-7:], time.Local, DefaultCP56YearBase)
+				if iv || ts.Before(start) || ts.After(time.Now()) {
+					t.Errorf("time tag = %s (invalid %v), want the current time", ts, iv)
+				}
+			}
+		})
+	}
+}
+
+type interrogationHandler struct {
+	BaseHandler
+	interrogations chan *APDU
+}
+
+func (h *interrogationHandler) GeneralInterrogationHandler(apdu *APDU) error {
+	h.interrogations <- apdu
+	return nil
+}
+
+func TestClient_SendGroupInterrogation(t *testing.T) {
+	received := make(chan []byte, 1)
+	address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+		received <- asdu
+		return [][]byte{withCOT(asdu, byte(CotActCon)), withCOT(asdu, byte(CotActTerm))}
+	}))
+	handler := &interrogationHandler{interrogations: make(chan *APDU, 2)}
+	option, err := NewClientOption(address, handler)
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option.SetInterrogationConfirmations(true))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	for _, group := range []uint8{0, 17} {
+		if err := client.SendGroupInterrogation(group); err == nil {
+			t.Errorf("SendGroupInterrogation(%d) error = nil", group)
+		}
+	}
+	if err := client.SendGroupInterrogation(3); err != nil {
+		t.Fatalf("SendGroupInterrogation() error = %v", err)
+	}
+	want := []byte{byte(CIcNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, QOIGroup3}
+	if asdu := <-received; !bytes.Equal(asdu, want) {
+		t.Errorf("send [% X], want [% X]", asdu, want)
+	}
+
+	// the confirmation and termination carry the QOI of the group
+	for _, cot := range []COT{CotActCon, CotActTerm} {
+		select {
+		case apdu := <-handler.interrogations:
+			signal := apdu.Signals[0]
+			if apdu.cot != cot || byte(signal.Value) != QOIGroup3 || signal.Group != 3 {
+				t.Errorf("GeneralInterrogationHandler() gets COT %d, QOI %v, group %d, want %d, %d, 3",
+					apdu.cot, signal.Value, signal.Group, cot, QOIGroup3)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("GeneralInterrogationHandler() isn't called with COT %d", cot)
+		}
+	}
+}
+
+type counterHandler struct {
+	BaseHandler
+	interrogations chan *APDU
+}
+
+func (h *counterHandler) CounterInterrogationHandler(apdu *APDU) error {
+	h.interrogations <- apdu
+	return nil
+}
+
+func TestClient_SendCounterInterrogation(t *testing.T) {
+	tests := []struct {
+		name    string
+		request CounterRequest
+		freeze  FreezeMode
+		qcc     byte
+	}{
+		{"general request freezing without reset", CounterRequestGeneral, FreezeWithoutReset, 0x45},
+		{"general request reading", CounterRequestGeneral, FreezeRead, 0x05},
+		{"group 2 freezing with reset", CounterRequestGroup2, FreezeWithReset, 0x82},
+		{"group 4 resetting", CounterRequestGroup4, FreezeReset, 0xc4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []byte, 1)
+			address := startTestSubstation(t, answeringSubstation(func(asdu []byte) [][]byte {
+				received <- asdu
+				return [][]byte{withCOT(asdu, byte(CotActCon)), withCOT(asdu, byte(CotActTerm))}
+			}))
+			handler := &counterHandler{interrogations: make(chan *APDU, 2)}
+			option, err := NewClientOption(address, handler)
+			if err != nil {
+				t.Fatalf("NewClientOption() error = %v", err)
+			}
+			client := NewClient(option.SetInterrogationConfirmations(true))
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Close()
+
+			client.SendCounterInterrogation(tt.request, tt.freeze)
+			want := []byte{byte(CCiNa1), 0x01, byte(CotAct), 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, tt.qcc}
+			if asdu := <-received; !bytes.Equal(asdu, want) {
+				t.Errorf("send [% X], want [% X]", asdu, want)
+			}
+
+			// the confirmation and termination carry the QCC
+			for _, cot := range []COT{CotActCon, CotActTerm} {
+				select {
+				case apdu := <-handler.interrogations:
+					signal := apdu.Signals[0]
+					if apdu.cot != cot || signal.CounterRequest != tt.request || signal.FreezeMode != tt.freeze {
+						t.Errorf("CounterInterrogationHandler() gets COT %d, request %d, freeze %d, want %d, %d, %d",
+							apdu.cot, signal.CounterRequest, signal.FreezeMode, cot, tt.request, tt.freeze)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("CounterInterrogationHandler() isn't called with COT %d", cot)
+				}
+			}
+		})
+	}
+}
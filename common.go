@@ -2,15 +2,9 @@ package iec104
 
 import (
 	"encoding/binary"
-	"github.com/sirupsen/logrus"
+	"fmt"
 )
 
-var _lg = logrus.New()
-
-func SetLogger(lg *logrus.Logger) {
-	_lg = lg
-}
-
 func serializeBigEndianUint16(i uint16) []byte {
 	bytes := make([]byte, 2, 2)
 	binary.BigEndian.PutUint16(bytes, i)
@@ -45,6 +39,143 @@ func serializeLittleEndianUint32(i uint32) []byte {
 	return bytes
 }
 
+// The big-endian helpers are only for the nonconformant peers, e.g. the gateways transmitting some fields big-endian,
+// which are decoded by the TypeDecoder registered by RegisterTypeDecoder. IEC 104 is little-endian everywhere.
+
+// ParseBigEndianUint16 parses the first 2 bytes of x as the big-endian uint16.
+func ParseBigEndianUint16(x []byte) uint16 {
+	return binary.BigEndian.Uint16(x)
+}
+
+// ParseBigEndianInt16 parses the first 2 bytes of x as the big-endian int16.
+func ParseBigEndianInt16(x []byte) int16 {
+	return int16(ParseBigEndianUint16(x))
+}
+
+// ParseBigEndianUint32 parses the first 4 bytes of x as the big-endian uint32.
+func ParseBigEndianUint32(x []byte) uint32 {
+	return binary.BigEndian.Uint32(x)
+}
+
+// ParseBigEndianInt32 parses the first 4 bytes of x as the big-endian int32.
+func ParseBigEndianInt32(x []byte) int32 {
+	return int32(ParseBigEndianUint32(x))
+}
+
+// CommandPhase is the phase of a select-before-operate command confirmed by the controlled station.
+type CommandPhase uint8
+
+const (
+	CommandPhaseSelect  CommandPhase = iota + 1 // select confirmed (S/E = 1)
+	CommandPhaseExecute                         // execute confirmed (S/E = 0)
+	CommandPhaseCancel                          // select or execute cancelled (deactivation confirmed)
+	CommandPhaseTerm                            // execution terminated
+)
+
+func (p CommandPhase) String() string {
+	switch p {
+	case CommandPhaseSelect:
+		return "select"
+	case CommandPhaseExecute:
+		return "execute"
+	case CommandPhaseCancel:
+		return "cancel"
+	case CommandPhaseTerm:
+		return "termination"
+	}
+	return fmt.Sprintf("CommandPhase(%d)", uint8(p))
+}
+
+// StepDirection is the RCS (regulating step command state) of regulating step command.
+type StepDirection uint8
+
+const (
+	StepLower  StepDirection = 0b01 // next step lower
+	StepHigher StepDirection = 0b10 // next step higher
+)
+
+// CommandQualifier is the QU (qualifier of command) of single, double and regulating step commands, which defines the
+// duration of the output driving the relay. 4-8 is reserved for standard definitions, 9-15 for the selection of other
+// predefined functions and 16-31 for special use.
+type CommandQualifier uint8
+
+const (
+	QUNone       CommandQualifier = 0 // no additional definition, the duration is defined by the controlled station
+	QUShortPulse CommandQualifier = 1 // short pulse duration
+	QULongPulse  CommandQualifier = 2 // long pulse duration
+	QUPersistent CommandQualifier = 3 // persistent output
+)
+
+// commandQualifier returns the QU bits (bit 3-7) of SCO, DCO and RCO, QUNone is used if qu is empty.
+func commandQualifier(qu []CommandQualifier) byte {
+	if len(qu) == 0 {
+		return 0
+	}
+	return byte(qu[0]&0x1f) << 2
+}
+
+// QOI (qualifier of interrogation) of general interrogation, which is also the COT of the answers. 1-19 is reserved
+// for standard definitions and 37-255 for special use.
+const (
+	QOIStation byte = 20 // station interrogation (global)
+	QOIGroup1  byte = iota + 20
+	QOIGroup2
+	QOIGroup3
+	QOIGroup4
+	QOIGroup5
+	QOIGroup6
+	QOIGroup7
+	QOIGroup8
+	QOIGroup9
+	QOIGroup10
+	QOIGroup11
+	QOIGroup12
+	QOIGroup13
+	QOIGroup14
+	QOIGroup15
+	QOIGroup16
+)
+
+// CounterRequest is the RQT (request) of QCC (qualifier of counter interrogation command), which selects the group
+// of the integrated totals to interrogate.
+type CounterRequest uint8
+
+const (
+	CounterRequestGroup1  CounterRequest = 1 // request counter group 1
+	CounterRequestGroup2  CounterRequest = 2 // request counter group 2
+	CounterRequestGroup3  CounterRequest = 3 // request counter group 3
+	CounterRequestGroup4  CounterRequest = 4 // request counter group 4
+	CounterRequestGeneral CounterRequest = 5 // general request counter
+)
+
+// FreezeMode is the FRZ (freeze) of QCC (qualifier of counter interrogation command), which decides whether the
+// counters are read, frozen or reset.
+type FreezeMode uint8
+
+const (
+	FreezeRead         FreezeMode = 0 // read the counters without freezing or resetting
+	FreezeWithoutReset FreezeMode = 1 // freeze the counters without reset, the integrated totals are the total
+	FreezeWithReset    FreezeMode = 2 // freeze the counters with reset, the integrated totals are the increment
+	FreezeReset        FreezeMode = 3 // reset the counters
+)
+
+// serializeQCC encodes QCC, whose bit 1-6 is RQT and bit 7-8 is FRZ.
+func serializeQCC(request CounterRequest, freeze FreezeMode) byte {
+	return byte(freeze&0b11)<<6 | byte(request&0x3f)
+}
+
+// QRP (qualifier of reset process command) of reset process command. 0 is not used, 3-127 is reserved for standard
+// definitions and 128-255 is reserved for special use.
+const (
+	QRPGeneralReset     byte = 1 // general reset of process
+	QRPResetEventBuffer byte = 2 // reset of pending information with time tag of the event buffer
+)
+
 type cmdRsp struct {
-	err error
+	err     error
+	phase   CommandPhase
+	state   uint8  // SCS of single command, DCS of double command or RCS of regulating step command
+	bits    uint32 // BSI of bitstring command
+	typeID  TypeID // TypeID of the command confirmed
+	address IOA    // IOA of the command confirmed
 }
@@ -0,0 +1,77 @@
+package iec104
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Decoder reads and decodes APDUs from an input stream, e.g. a connection or a captured session.
+type Decoder struct {
+	r      io.Reader
+	cotLen int            // length of COT in bytes, 0 means CotLen
+	coaLen int            // length of COA in bytes, 0 means CoaLen
+	loc    *time.Location // location of the time tags, time.Local if it's nil
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: r,
+	}
+}
+
+// SetCOTLength sets the length of COT in bytes, 1 or 2, of the frames decoded, see ClientOption.SetCOTLength. The
+// length other than 1 or 2 is ignored.
+func (d *Decoder) SetCOTLength(n int) *Decoder {
+	if validFieldLength(n) {
+		d.cotLen = n
+	}
+	return d
+}
+
+// SetCOALength sets the length of COA in bytes, 1 or 2, of the frames decoded, see ClientOption.SetCOALength. The
+// length other than 1 or 2 is ignored.
+func (d *Decoder) SetCOALength(n int) *Decoder {
+	if validFieldLength(n) {
+		d.coaLen = n
+	}
+	return d
+}
+
+// SetTimeLocation sets the location to decode CP24Time2a and CP56Time2a in, see ClientOption.SetTimeLocation. It's
+// time.Local by default, and nil is ignored.
+func (d *Decoder) SetTimeLocation(loc *time.Location) *Decoder {
+	if loc != nil {
+		d.loc = loc
+	}
+	return d
+}
+
+// Decode reads the next frame from the input stream and decodes it. It returns io.EOF if there is no more frame.
+func (d *Decoder) Decode() (*APDU, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != startByte {
+		return nil, fmt.Errorf("invalid data: unexpected start - % X, expected start - % X", header[0], startByte)
+	}
+	if err := checkApduLen(header[1]); err != nil {
+		return nil, err
+	}
+
+	apduData := make([]byte, header[1])
+	if _, err := io.ReadFull(d.r, apduData); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	pkgLogger().Debugf("receive: [% X]", append(header, apduData...))
+
+	apdu := &APDU{opt: &parseOption{cotLen: d.cotLen, coaLen: d.coaLen, loc: d.loc}}
+	if err := apdu.Parse(apduData); err != nil {
+		return nil, err
+	}
+	return apdu, nil
+}
@@ -0,0 +1,76 @@
+package iec104
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	stream := bytes.Join([][]byte{
+		buildFrame(UFrameFunctionStartDTC),
+		buildFrame((&SFrame{RecvSN: 3}).Data()),
+		iFrame(1, []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}),
+	}, nil)
+	decoder := NewDecoder(bytes.NewReader(stream))
+
+	for _, want := range []FrameType{FrameTypeU, FrameTypeS, FrameTypeI} {
+		apdu, err := decoder.Decode()
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if got := apdu.frame.Type(); got != want {
+			t.Errorf("Decode() frame type = %v, want %v", got, want)
+		}
+	}
+	if _, err := decoder.Decode(); err != io.EOF {
+		t.Errorf("Decode() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestDecoder_DecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"unexpected start", []byte{0x69, 0x04, 0x07, 0x00, 0x00, 0x00}},
+		{"truncated header", []byte{0x68}},
+		{"truncated body", []byte{0x68, 0x04, 0x07, 0x00}},
+		{"too short", []byte{0x68, 0x02, 0x07, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDecoder(bytes.NewReader(tt.data)).Decode(); err == nil || err == io.EOF {
+				t.Errorf("Decode() error = %v, want error", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_SetTimeLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	ts := time.Date(2022, time.August, 1, 10, 30, 0, 0, loc)
+	asdu := append([]byte{byte(MSpTb1), 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01},
+		serializeCP56Time2a(ts, loc)...)
+
+	tests := []struct {
+		name string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"station location", loc, ts},
+		{"UTC", time.UTC, time.Date(2022, time.August, 1, 10, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apdu, err := NewDecoder(bytes.NewReader(iFrame(0, asdu))).SetTimeLocation(tt.loc).Decode()
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if ios := apdu.ASDU.ios; len(ios) != 1 || len(ios[0].ies) != 1 || !ios[0].ies[0].Ts.Equal(tt.want) {
+				t.Errorf("Decode() ASDU = %v, want time tag %v", apdu.ASDU, tt.want)
+			}
+		})
+	}
+}
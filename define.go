@@ -8,6 +8,35 @@ type ClientHandler interface {
 	ReadCommandHandler(apdu *APDU) error
 	ResetProcessCommandHandler(apdu *APDU) error
 	DelayAcquisitionCommandHandler(apdu *APDU) error
+	// CyclicDataHandler handles the data with COT CotPerCyc or CotBack, which is the periodic refresh of the
+	// controlled station rather than the spontaneous change handled by APDUHandler.
+	CyclicDataHandler(apdu *APDU) error
 
 	APDUHandler(apdu *APDU) error
 }
+
+// InterrogationAnswerer is optionally implemented by the ServerHandler to supply the points answering the general
+// interrogation, or by the ClientHandler for the dual role, e.g. in peer-to-peer test setups, where the client answers
+// the general interrogation received from the peer like a controlled station. The interrogation is confirmed, the
+// points returned are sent in monitor direction with the COT of the QOI (20 for the station interrogation, 21-36 for
+// the groups), and it's terminated, before GeneralInterrogationHandler is called.
+type InterrogationAnswerer interface {
+	// AnswerInterrogation returns the points of the group, which is 0 for the station interrogation and 1-16 for the
+	// group interrogation. The confirmation is negative if it fails.
+	AnswerInterrogation(group uint8) ([]Point, error)
+}
+
+// ServerHandler handles the data received from the controlling station, it can answer by Conn.SendIFrame.
+type ServerHandler interface {
+	GeneralInterrogationHandler(c *Conn, apdu *APDU) error
+	CounterInterrogationHandler(c *Conn, apdu *APDU) error
+	ClockSynchronizationHandler(c *Conn, apdu *APDU) error
+	TestCommandHandler(c *Conn, apdu *APDU) error
+	ReadCommandHandler(c *Conn, apdu *APDU) error
+	ResetProcessCommandHandler(c *Conn, apdu *APDU) error
+	DelayAcquisitionCommandHandler(c *Conn, apdu *APDU) error
+	// CommandHandler handles process commands, e.g. single command, double command and set-point command.
+	CommandHandler(c *Conn, apdu *APDU) error
+
+	APDUHandler(c *Conn, apdu *APDU) error
+}
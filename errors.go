@@ -1,5 +1,26 @@
 package iec104
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The errors can be matched by errors.Is, or by the IsErrXxx helpers.
+var (
+	ErrSingleCmdTerm       error = errSingleCmdTerm{}
+	ErrDoubleCmdTerm       error = errDoubleCmdTerm{}
+	ErrStepCmdTerm         error = errStepCmdTerm{}
+	ErrConnectionClosed    error = errConnectionClosed{}
+	ErrT1Timeout           error = errT1Timeout{}
+	ErrUnexpectedCmd       error = errUnexpectedCmd{}
+	ErrCommandRejected     error = errCommandRejected{}
+	ErrCommandTimeout      error = errCommandTimeout{}
+	ErrDataTransferStopped error = errDataTransferStopped{}
+	ErrElementLength       error = errElementLength{}
+	ErrSequence            error = errSequence{}
+)
+
 type errSingleCmdTerm struct{}
 
 func (e errSingleCmdTerm) Error() string {
@@ -7,8 +28,7 @@ func (e errSingleCmdTerm) Error() string {
 }
 
 func IsErrSingleCmdTerm(err error) bool {
-	_, ok := err.(errSingleCmdTerm)
-	return ok
+	return errors.Is(err, ErrSingleCmdTerm)
 }
 
 type errDoubleCmdTerm struct{}
@@ -18,6 +38,155 @@ func (e errDoubleCmdTerm) Error() string {
 }
 
 func IsErrDoubleCmdTerm(err error) bool {
-	_, ok := err.(errDoubleCmdTerm)
+	return errors.Is(err, ErrDoubleCmdTerm)
+}
+
+type errStepCmdTerm struct{}
+
+func (e errStepCmdTerm) Error() string {
+	return "termination of regulating step command"
+}
+
+func IsErrStepCmdTerm(err error) bool {
+	return errors.Is(err, ErrStepCmdTerm)
+}
+
+type errConnectionClosed struct{}
+
+func (e errConnectionClosed) Error() string {
+	return "connection closed"
+}
+
+func IsErrConnectionClosed(err error) bool {
+	return errors.Is(err, ErrConnectionClosed)
+}
+
+type errT1Timeout struct {
+	frame string
+}
+
+func (e errT1Timeout) Error() string {
+	return fmt.Sprintf("t1 timeout: no confirmation of %s", e.frame)
+}
+
+func (e errT1Timeout) Is(target error) bool {
+	_, ok := target.(errT1Timeout)
+	return ok
+}
+
+func IsErrT1Timeout(err error) bool {
+	return errors.Is(err, ErrT1Timeout)
+}
+
+type errUnexpectedCmd struct {
+	phase CommandPhase
+	state uint8
+}
+
+func (e errUnexpectedCmd) Error() string {
+	return fmt.Sprintf("unexpected confirmation of command: %s with state %d", e.phase, e.state)
+}
+
+func (e errUnexpectedCmd) Is(target error) bool {
+	_, ok := target.(errUnexpectedCmd)
+	return ok
+}
+
+func IsErrUnexpectedCmd(err error) bool {
+	return errors.Is(err, ErrUnexpectedCmd)
+}
+
+// errCommandRejected is the negative confirmation (PN=1) of the command, e.g. the breaker operation rejected by the
+// interlock of the controlled station.
+type errCommandRejected struct {
+	typeID TypeID
+	cot    COT
+}
+
+func (e errCommandRejected) Error() string {
+	return fmt.Sprintf("command rejected: negative confirmation of TypeID[%X] with COT %d", uint8(e.typeID), e.cot)
+}
+
+func (e errCommandRejected) Is(target error) bool {
+	_, ok := target.(errCommandRejected)
 	return ok
 }
+
+func IsErrCommandRejected(err error) bool {
+	return errors.Is(err, ErrCommandRejected)
+}
+
+// errCommandTimeout is the confirmation of the command not received within the timeout set by SetCommandTimeout.
+type errCommandTimeout struct {
+	timeout time.Duration
+}
+
+func (e errCommandTimeout) Error() string {
+	return fmt.Sprintf("command timeout: no confirmation within %s", e.timeout)
+}
+
+func (e errCommandTimeout) Is(target error) bool {
+	_, ok := target.(errCommandTimeout)
+	return ok
+}
+
+func IsErrCommandTimeout(err error) bool {
+	return errors.Is(err, ErrCommandTimeout)
+}
+
+// errDataTransferStopped is the ASDU not sent since the controlling station hasn't started the data transfer by STARTDT.
+type errDataTransferStopped struct{}
+
+func (e errDataTransferStopped) Error() string {
+	return "data transfer not started"
+}
+
+func (e errDataTransferStopped) Is(target error) bool {
+	_, ok := target.(errDataTransferStopped)
+	return ok
+}
+
+func IsErrDataTransferStopped(err error) bool {
+	return errors.Is(err, ErrDataTransferStopped)
+}
+
+// errElementLength is the information element whose length doesn't match the length of its TypeID.
+type errElementLength struct {
+	typeID TypeID
+	length int
+	want   int
+}
+
+func (e errElementLength) Error() string {
+	return fmt.Sprintf("invalid length of information element of TypeID[%X]: %d bytes, expected %d",
+		uint8(e.typeID), e.length, e.want)
+}
+
+func (e errElementLength) Is(target error) bool {
+	_, ok := target.(errElementLength)
+	return ok
+}
+
+func IsErrElementLength(err error) bool {
+	return errors.Is(err, ErrElementLength)
+}
+
+// errSequence is the I-format frame whose send sequence number N(S) isn't the expected one, i.e. the frames between
+// are lost.
+type errSequence struct {
+	received uint16
+	expected uint16
+}
+
+func (e errSequence) Error() string {
+	return fmt.Sprintf("I-format frames lost: send sequence number %d is received, %d is expected", e.received, e.expected)
+}
+
+func (e errSequence) Is(target error) bool {
+	_, ok := target.(errSequence)
+	return ok
+}
+
+func IsErrSequence(err error) bool {
+	return errors.Is(err, ErrSequence)
+}
@@ -0,0 +1,60 @@
+package iec104
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsErr(t *testing.T) {
+	helpers := map[string]struct {
+		is       func(err error) bool
+		sentinel error
+	}{
+		"SingleCmdTerm":       {IsErrSingleCmdTerm, ErrSingleCmdTerm},
+		"DoubleCmdTerm":       {IsErrDoubleCmdTerm, ErrDoubleCmdTerm},
+		"StepCmdTerm":         {IsErrStepCmdTerm, ErrStepCmdTerm},
+		"T1Timeout":           {IsErrT1Timeout, ErrT1Timeout},
+		"ConnectionClosed":    {IsErrConnectionClosed, ErrConnectionClosed},
+		"UnexpectedCmd":       {IsErrUnexpectedCmd, ErrUnexpectedCmd},
+		"CommandRejected":     {IsErrCommandRejected, ErrCommandRejected},
+		"CommandTimeout":      {IsErrCommandTimeout, ErrCommandTimeout},
+		"DataTransferStopped": {IsErrDataTransferStopped, ErrDataTransferStopped},
+		"ElementLength":       {IsErrElementLength, ErrElementLength},
+		"Sequence":            {IsErrSequence, ErrSequence},
+	}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"single command termination", errSingleCmdTerm{}, "SingleCmdTerm"},
+		{"double command termination", errDoubleCmdTerm{}, "DoubleCmdTerm"},
+		{"regulating step command termination", errStepCmdTerm{}, "StepCmdTerm"},
+		{"t1 timeout of STARTDT", errT1Timeout{frame: "STARTDT"}, "T1Timeout"},
+		{"t1 timeout of TESTFR", errT1Timeout{frame: "TESTFR"}, "T1Timeout"},
+		{"wrapped t1 timeout", fmt.Errorf("connect: %w", errT1Timeout{frame: "STARTDT"}), "T1Timeout"},
+		{"wrapped double command termination", fmt.Errorf("execute: %w", errDoubleCmdTerm{}), "DoubleCmdTerm"},
+		{"unexpected command confirmation", errUnexpectedCmd{phase: CommandPhaseExecute, state: 3}, "UnexpectedCmd"},
+		{"connection closed", fmt.Errorf("execute: %w", errConnectionClosed{}), "ConnectionClosed"},
+		{"command rejected", errCommandRejected{typeID: CScNa1, cot: CotActCon}, "CommandRejected"},
+		{"data transfer stopped", fmt.Errorf("send: %w", errDataTransferStopped{}), "DataTransferStopped"},
+		{"element length", errElementLength{typeID: MSpTb1, length: 3, want: 8}, "ElementLength"},
+		{"sequence", fmt.Errorf("read from socket: %w", errSequence{received: 5, expected: 3}), "Sequence"},
+		{"other error", errors.New("termination of single command"), ""},
+		{"nil", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, helper := range helpers {
+				want := name == tt.want
+				if got := helper.is(tt.err); got != want {
+					t.Errorf("IsErr%s(%v) = %v, want %v", name, tt.err, got, want)
+				}
+				if got := errors.Is(tt.err, helper.sentinel); got != want {
+					t.Errorf("errors.Is(%v, Err%s) = %v, want %v", tt.err, name, got, want)
+				}
+			}
+		})
+	}
+}
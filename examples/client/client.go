@@ -11,7 +11,10 @@ const (
 	serverAddress = "172.16.251.22:6666"
 )
 
-type handler struct{}
+// handler prints the values of interrogations and spontaneous data, the other data is ignored by BaseHandler.
+type handler struct {
+	iec104.BaseHandler
+}
 
 func (h handler) GeneralInterrogationHandler(apdu *iec104.APDU) error {
 	for _, signal := range apdu.Signals {
@@ -29,26 +32,6 @@ func (h handler) CounterInterrogationHandler(apdu *iec104.APDU) error {
 	return nil
 }
 
-func (h handler) ReadCommandHandler(apdu *iec104.APDU) error {
-	return nil
-}
-
-func (h handler) ClockSynchronizationHandler(apdu *iec104.APDU) error {
-	return nil
-}
-
-func (h handler) TestCommandHandler(apdu *iec104.APDU) error {
-	return nil
-}
-
-func (h handler) ResetProcessCommandHandler(apdu *iec104.APDU) error {
-	return nil
-}
-
-func (h handler) DelayAcquisitionCommandHandler(apdu *iec104.APDU) error {
-	return nil
-}
-
 func (h handler) APDUHandler(apdu *iec104.APDU) error {
 	for _, signal := range apdu.Signals {
 		fmt.Printf("%f ", signal.Value)
@@ -70,21 +53,24 @@ func main() {
 	if err := client.Connect(); err != nil {
 		panic(any(err))
 	}
-	defer client.Close()
-
-	go func() {
-		time.Sleep(5 * time.Second)
-		client.SendTestFrame()
+	defer func() {
+		if err := client.Close(); err != nil {
+			logger.Warnf("close the client: %v", err)
+		}
 	}()
 
 	go func() {
 		time.Sleep(1 * time.Second)
-		client.SendGeneralInterrogation()
+		if err := client.SendGeneralInterrogation(); err != nil {
+			logger.Warnf("send general interrogation: %v", err)
+		}
 	}()
 
 	go func() {
 		time.Sleep(2 * time.Second)
-		client.SendCounterInterrogation()
+		if err := client.SendCounterInterrogation(iec104.CounterRequestGeneral, iec104.FreezeWithoutReset); err != nil {
+			logger.Warnf("send counter interrogation: %v", err)
+		}
 	}()
 
 	go func() {
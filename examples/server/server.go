@@ -1,16 +1,60 @@
 package main
 
 import (
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/yobol/go-iec104"
 )
 
+type handler struct{}
+
+func (h handler) GeneralInterrogationHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	fmt.Printf("general interrogation from %s\n", c.RemoteAddr())
+	return nil
+}
+
+func (h handler) CounterInterrogationHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	fmt.Printf("counter interrogation from %s\n", c.RemoteAddr())
+	return nil
+}
+
+func (h handler) ClockSynchronizationHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	return nil
+}
+
+func (h handler) TestCommandHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	return nil
+}
+
+func (h handler) ReadCommandHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	return nil
+}
+
+func (h handler) ResetProcessCommandHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	return nil
+}
+
+func (h handler) DelayAcquisitionCommandHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	return nil
+}
+
+func (h handler) CommandHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	for _, signal := range apdu.Signals {
+		fmt.Printf("command at %d: %f\n", signal.Address, signal.Value)
+	}
+	return nil
+}
+
+func (h handler) APDUHandler(c *iec104.Conn, apdu *iec104.APDU) error {
+	return nil
+}
+
 func main() {
 	logger := logrus.New()
 	logger.SetLevel(logrus.DebugLevel)
 	iec104.SetLogger(logger)
 
-	server := iec104.NewServer(":2404", nil)
+	server := iec104.NewServer(":2404", nil, &handler{})
 	if err := server.Serve(); err != nil {
 		panic(any(err))
 	}
@@ -0,0 +1,102 @@
+package iec104
+
+/*
+BaseHandler implements ClientHandler with no-ops, it can be embedded by the customized handler which only cares about
+some kinds of data, e.g. the handler printing the responses of general interrogation:
+
+	type handler struct {
+		iec104.BaseHandler
+	}
+
+	func (h handler) GeneralInterrogationHandler(apdu *iec104.APDU) error {
+		for _, signal := range apdu.Signals {
+			fmt.Printf("IOA %d: %f\n", signal.Address, signal.Value)
+		}
+		return nil
+	}
+*/
+type BaseHandler struct{}
+
+func (h BaseHandler) GeneralInterrogationHandler(apdu *APDU) error {
+	return nil
+}
+
+func (h BaseHandler) CounterInterrogationHandler(apdu *APDU) error {
+	return nil
+}
+
+func (h BaseHandler) ClockSynchronizationHandler(apdu *APDU) error {
+	return nil
+}
+
+func (h BaseHandler) TestCommandHandler(apdu *APDU) error {
+	return nil
+}
+
+func (h BaseHandler) ReadCommandHandler(apdu *APDU) error {
+	return nil
+}
+
+func (h BaseHandler) ResetProcessCommandHandler(apdu *APDU) error {
+	return nil
+}
+
+func (h BaseHandler) DelayAcquisitionCommandHandler(apdu *APDU) error {
+	return nil
+}
+
+func (h BaseHandler) CyclicDataHandler(apdu *APDU) error {
+	return nil
+}
+
+func (h BaseHandler) APDUHandler(apdu *APDU) error {
+	return nil
+}
+
+/*
+LoggingHandler logs the IOA, value, quality and timestamp of each signal received at Info level. It's a drop-in
+ClientHandler for getting started and debugging.
+*/
+type LoggingHandler struct {
+	BaseHandler
+	Logger Logger // logger of the signals, the logger of the package if it's nil
+}
+
+func (h LoggingHandler) GeneralInterrogationHandler(apdu *APDU) error {
+	h.logSignals(apdu)
+	return nil
+}
+
+func (h LoggingHandler) CounterInterrogationHandler(apdu *APDU) error {
+	h.logSignals(apdu)
+	return nil
+}
+
+func (h LoggingHandler) ReadCommandHandler(apdu *APDU) error {
+	h.logSignals(apdu)
+	return nil
+}
+
+func (h LoggingHandler) CyclicDataHandler(apdu *APDU) error {
+	h.logSignals(apdu)
+	return nil
+}
+
+func (h LoggingHandler) APDUHandler(apdu *APDU) error {
+	h.logSignals(apdu)
+	return nil
+}
+
+func (h LoggingHandler) logSignals(apdu *APDU) {
+	if apdu.ASDU == nil {
+		return
+	}
+	lg := h.Logger
+	if lg == nil {
+		lg = pkgLogger()
+	}
+	for _, signal := range apdu.Signals {
+		lg.Infof("signal: TypeID[%X], IOA[%d], Value[%f], Quality[%s], Ts[%s]",
+			uint8(signal.TypeID), signal.Address, signal.Value, signal.Quality, signal.Ts)
+	}
+}
@@ -0,0 +1,105 @@
+package iec104
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// exampleLogger prints the signals logged by LoggingHandler, and closes logged after the first one.
+type exampleLogger struct {
+	nopLogger
+	once   sync.Once
+	logged chan struct{}
+}
+
+func (l *exampleLogger) Infof(format string, args ...interface{}) {
+	if msg := fmt.Sprintf(format, args...); strings.HasPrefix(msg, "signal:") {
+		l.once.Do(func() {
+			fmt.Println(msg)
+			close(l.logged)
+		})
+	}
+}
+
+func ExampleLoggingHandler() {
+	// A loopback substation which confirms STARTDT/STOPDT and sends a spontaneous single point information.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(any(err))
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 6)
+		for {
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return
+			}
+			switch buf[2] {
+			case UFrameFunctionStartDTA[0]:
+				conn.Write(append([]byte{startByte, 0x04}, UFrameFunctionStartDTC...))
+				conn.Write([]byte{
+					startByte, 0x0e, 0x00, 0x00, 0x00, 0x00, // APCI
+					0x01, 0x01, 0x03, 0x00, 0x01, 0x00, // MSpNa1, CotSpont
+					0x01, 0x00, 0x00, 0x01, // IOA 1 is ON
+				})
+			case UFrameFunctionStopDTA[0]:
+				conn.Write(append([]byte{startByte, 0x04}, UFrameFunctionStopDTC...))
+			}
+		}
+	}()
+
+	lg := &exampleLogger{logged: make(chan struct{})}
+	option, err := NewClientOption(listener.Addr().String(), &LoggingHandler{Logger: lg})
+	if err != nil {
+		panic(any(err))
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		panic(any(err))
+	}
+	defer client.Close()
+
+	<-lg.logged
+	// Output:
+	// signal: TypeID[1], IOA[1], Value[1.000000], Quality[OK], Ts[0001-01-01 00:00:00 +0000 UTC]
+}
+
+// interrogationPrinter prints the signals answering general interrogation, and ignores the other data by BaseHandler.
+type interrogationPrinter struct {
+	BaseHandler
+}
+
+func (h interrogationPrinter) GeneralInterrogationHandler(apdu *APDU) error {
+	for _, signal := range apdu.Signals {
+		fmt.Printf("IOA %d: %.0f\n", signal.Address, signal.Value)
+	}
+	return nil
+}
+
+func ExampleBaseHandler() {
+	var handler ClientHandler = interrogationPrinter{}
+
+	apdu := new(APDU)
+	if err := apdu.Parse([]byte{
+		0x00, 0x00, 0x00, 0x00, // APCI
+		0x01, 0x02, 0x14, 0x00, 0x01, 0x00, // MSpNa1 of 2 objects, CotInrogen
+		0x01, 0x00, 0x00, 0x01, // IOA 1 is ON
+		0x02, 0x00, 0x00, 0x00, // IOA 2 is OFF
+	}); err != nil {
+		panic(any(err))
+	}
+	_ = handler.GeneralInterrogationHandler(apdu)
+	_ = handler.APDUHandler(apdu) // no-op of BaseHandler
+	// Output:
+	// IOA 1: 1
+	// IOA 2: 0
+}
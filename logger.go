@@ -0,0 +1,90 @@
+package iec104
+
+import (
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the logger used by the package, it's implemented by *logrus.Logger, and the other loggers, e.g. zap, slog
+// or the standard log, are plugged in by a thin adapter.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// FieldLogger is optionally implemented by the Logger to log the structured fields, e.g. the fields of the signals
+// decoded. The fields are appended to the message by the Logger not implementing it.
+type FieldLogger interface {
+	Logger
+	WithFields(fields map[string]interface{}) Logger
+}
+
+// _lg holds the logger of the package in loggerHolder, it's replaced by SetLogger while the clients and servers log.
+var _lg atomic.Value
+
+func init() {
+	_lg.Store(loggerHolder{NewLogrusLogger(logrus.New())})
+}
+
+// loggerHolder holds the loggers of different types in _lg, which only stores the values of the same type.
+type loggerHolder struct {
+	Logger
+}
+
+// SetLogger sets the logger of the package, which is used by the clients without their own loggers set by
+// ClientOption.SetLogger. *logrus.Logger is adapted by NewLogrusLogger to log the structured fields, and nil discards
+// the logs. It's safe to call while the clients and servers are running.
+func SetLogger(lg Logger) {
+	_lg.Store(loggerHolder{adaptLogger(lg)})
+}
+
+// pkgLogger returns the logger of the package.
+func pkgLogger() Logger {
+	return _lg.Load().(loggerHolder).Logger
+}
+
+// adaptLogger adapts *logrus.Logger by NewLogrusLogger, and replaces nil by the logger discarding the logs.
+func adaptLogger(lg Logger) Logger {
+	switch x := lg.(type) {
+	case nil:
+		return nopLogger{}
+	case *logrus.Logger:
+		return NewLogrusLogger(x)
+	}
+	return lg
+}
+
+// NewLogrusLogger adapts the logrus logger, e.g. *logrus.Logger or *logrus.Entry, to FieldLogger.
+func NewLogrusLogger(lg logrus.FieldLogger) FieldLogger {
+	return logrusLogger{lg}
+}
+
+type logrusLogger struct {
+	logrus.FieldLogger
+}
+
+func (l logrusLogger) WithFields(fields map[string]interface{}) Logger {
+	return logrusLogger{l.FieldLogger.WithFields(fields)}
+}
+
+// logWithFields logs the message at debug level with the fields by the FieldLogger, or appends the fields to the
+// message if lg doesn't implement it.
+func logWithFields(lg Logger, fields map[string]interface{}, format string, args ...interface{}) {
+	if fl, ok := lg.(FieldLogger); ok {
+		fl.WithFields(fields).Debugf(format, args...)
+		return
+	}
+	lg.Debugf(format+" %v", append(args, fields)...)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+func (nopLogger) Printf(string, ...interface{}) {}
@@ -0,0 +1,97 @@
+package iec104
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordingLogger records the messages logged, it doesn't implement FieldLogger.
+type recordingLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *recordingLogger) record(level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.record("debug", format, args...)
+}
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.record("info", format, args...)
+}
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.record("warn", format, args...)
+}
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.record("error", format, args...)
+}
+func (l *recordingLogger) Printf(format string, args ...interface{}) {
+	l.record("print", format, args...)
+}
+
+func (l *recordingLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, msg := range l.msgs {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(pkgLogger())
+
+	SetLogger(logrus.New())
+	if _, ok := pkgLogger().(FieldLogger); !ok {
+		t.Errorf("SetLogger(*logrus.Logger) sets %T, want FieldLogger", pkgLogger())
+	}
+	SetLogger(nil)
+	pkgLogger().Warnf("discarded") // mustn't panic
+
+	lg := &recordingLogger{}
+	SetLogger(lg)
+	apdu := new(APDU)
+	// MSpNa1 declaring 2 objects but holding 1
+	if err := apdu.Parse([]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !lg.contains("warn ASDU declares 2 information objects") {
+		t.Errorf("logs = %q, want the warning of the package logger", lg.msgs)
+	}
+	// the fields are appended to the message by the logger not implementing FieldLogger
+	if !lg.contains("debug receive i frame: single point information of spontenuous change [变化遥信 - 单点遥信] map[") {
+		t.Errorf("logs = %q, want the signal with fields", lg.msgs)
+	}
+}
+
+func TestClientOption_SetLogger(t *testing.T) {
+	pkg := &recordingLogger{}
+	defer SetLogger(pkgLogger())
+	SetLogger(pkg)
+
+	lg := &recordingLogger{}
+	option, _ := NewClientOption(":2404", nil)
+	c := NewClient(option.SetLogger(lg))
+	c.logger().Infof("client")
+	apdu := &APDU{opt: &parseOption{lg: c.lg}}
+	if err := apdu.Parse([]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if !lg.contains("info client") || !lg.contains("warn ASDU declares 2 information objects") {
+		t.Errorf("client logs = %q, want the logs of the client", lg.msgs)
+	}
+	if len(pkg.msgs) != 0 {
+		t.Errorf("package logs = %q, want none", pkg.msgs)
+	}
+}
@@ -0,0 +1,94 @@
+// Package metrics exposes the statistics of iec104.Client over HTTP, it's kept out of package iec104 so that the
+// core doesn't depend on net/http.
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yobol/go-iec104"
+)
+
+// Stats is the snapshot of the statistics of a client, which is served as JSON.
+type Stats struct {
+	Connected     bool                       `json:"connected"`
+	ConnectedAt   time.Time                  `json:"connected_at"`
+	UptimeSeconds float64                    `json:"uptime_seconds"` // 0 if the client isn't connected
+	Errors        uint64                     `json:"errors"`
+	Timeouts      uint64                     `json:"timeouts"`
+	WindowFull    uint64                     `json:"window_full"`
+	DroppedData   uint64                     `json:"dropped_data"`
+	Frames        map[string]iec104.TypeStat `json:"frames"` // I-format frames received by TypeID in decimal
+}
+
+// Snapshot takes the snapshot of the statistics of the client.
+func Snapshot(c *iec104.Client) Stats {
+	stats := Stats{
+		Connected:   c.IsConnected(),
+		ConnectedAt: c.ConnectedAt(),
+		Errors:      c.ErrorCount(),
+		Timeouts:    c.TimeoutCount(),
+		WindowFull:  c.WindowFullCount(),
+		DroppedData: c.DroppedDataCount(),
+		Frames:      make(map[string]iec104.TypeStat),
+	}
+	if stats.Connected {
+		stats.UptimeSeconds = time.Since(stats.ConnectedAt).Seconds()
+	}
+	for typeID, stat := range c.TypeStats() {
+		stats.Frames[fmt.Sprintf("%d", typeID)] = stat
+	}
+	return stats
+}
+
+// Handler returns the HTTP handler serving the statistics of the client as JSON, or as Prometheus text format if the
+// query parameter format is prometheus, e.g. /metrics?format=prometheus.
+func Handler(c *iec104.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := Snapshot(c)
+		if r.URL.Query().Get("format") == "prometheus" {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			_, _ = w.Write([]byte(stats.prometheus()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(stats)
+	})
+}
+
+// prometheus formats the statistics in Prometheus text format.
+func (s Stats) prometheus() string {
+	var b strings.Builder
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+	}
+	counter := func(name, help string, value uint64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+	}
+
+	connected := 0.0
+	if s.Connected {
+		connected = 1
+	}
+	gauge("iec104_connected", "Whether the client is connected.", connected)
+	gauge("iec104_uptime_seconds", "Seconds since the connection is established.", s.UptimeSeconds)
+	counter("iec104_errors_total", "Connections lost by errors.", s.Errors)
+	counter("iec104_timeouts_total", "Confirmations not received in time.", s.Timeouts)
+	counter("iec104_window_full_total", "Times of the send window is full.", s.WindowFull)
+	counter("iec104_dropped_data_total", "APDUs dropped since the data buffer is full.", s.DroppedData)
+
+	typeIDs := make([]string, 0, len(s.Frames))
+	for typeID := range s.Frames {
+		typeIDs = append(typeIDs, typeID)
+	}
+	sort.Strings(typeIDs)
+	b.WriteString("# HELP iec104_frames_total I-format frames received by TypeID.\n# TYPE iec104_frames_total counter\n")
+	for _, typeID := range typeIDs {
+		fmt.Fprintf(&b, "iec104_frames_total{type_id=\"%s\"} %d\n", typeID, s.Frames[typeID].Count)
+	}
+	return b.String()
+}
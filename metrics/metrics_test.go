@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yobol/go-iec104"
+)
+
+// startSubstation starts the substation confirming STARTDT and STOPDT, which sends an MMeNc1 and an MSpNa1 after
+// STARTDT.
+func startSubstation(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		startDT := make([]byte, 6)
+		if _, err := io.ReadFull(conn, startDT); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{0x68, 0x04, 0x0b, 0x00, 0x00, 0x00})
+		_, _ = conn.Write([]byte{0x68, 0x12, 0x00, 0x00, 0x00, 0x00, 0x0d, 0x01, 0x03, 0x00, 0x01, 0x00,
+			0x01, 0x40, 0x00, 0x00, 0x80, 0x66, 0x43, 0x00})
+		_, _ = conn.Write([]byte{0x68, 0x0e, 0x02, 0x00, 0x00, 0x00, 0x01, 0x01, 0x03, 0x00, 0x01, 0x00,
+			0x01, 0x00, 0x00, 0x01})
+		// confirm STOPDT sent by Close, and ignore the other frames
+		header := make([]byte, 2)
+		for {
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			if body[0] == 0x13 {
+				_, _ = conn.Write([]byte{0x68, 0x04, 0x23, 0x00, 0x00, 0x00})
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func connect(t *testing.T) *iec104.Client {
+	option, err := iec104.NewClientOption(startSubstation(t), &iec104.BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := iec104.NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(client.TypeStats()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return client
+}
+
+func TestHandler_JSON(t *testing.T) {
+	client := connect(t)
+
+	rec := httptest.NewRecorder()
+	Handler(client).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %s, want application/json", got)
+	}
+	var stats Stats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Unmarshal() error = %v, body %s", err, rec.Body)
+	}
+	if !stats.Connected || stats.UptimeSeconds <= 0 || stats.ConnectedAt.IsZero() {
+		t.Errorf("connection = {%v, %v, %s}, want connected", stats.Connected, stats.UptimeSeconds, stats.ConnectedAt)
+	}
+	if stats.Errors != 0 || stats.Timeouts != 0 || stats.WindowFull != 0 || stats.DroppedData != 0 {
+		t.Errorf("counters = %+v, want 0", stats)
+	}
+	for _, typeID := range []string{"1", "13"} {
+		if stats.Frames[typeID].Count != 1 {
+			t.Errorf("frames of TypeID %s = %d, want 1", typeID, stats.Frames[typeID].Count)
+		}
+	}
+}
+
+func TestHandler_Prometheus(t *testing.T) {
+	client := connect(t)
+
+	rec := httptest.NewRecorder()
+	Handler(client).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics?format=prometheus", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"iec104_connected 1\n",
+		"iec104_uptime_seconds ",
+		"iec104_errors_total 0\n",
+		"iec104_timeouts_total 0\n",
+		"iec104_window_full_total 0\n",
+		"iec104_dropped_data_total 0\n",
+		"iec104_frames_total{type_id=\"1\"} 1\n",
+		"iec104_frames_total{type_id=\"13\"} 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body doesn't contain %q:\n%s", want, body)
+		}
+	}
+}
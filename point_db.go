@@ -0,0 +1,112 @@
+package iec104
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// pointElementLen is the length of the information elements of the types supported by PointDB.
+var pointElementLen = map[TypeID]int{
+	MSpNa1: 1, // SIQ
+	MDpNa1: 1, // DIQ
+	MMeNa1: 3, // NVA + QDS
+	MMeNb1: 3, // SVA + QDS
+	MMeNc1: 5, // IEEE754STD + QDS
+}
+
+// Point is a monitored point of the controlled station maintained by PointDB.
+type Point struct {
+	Address IOA
+	TypeID  TypeID // MSpNa1, MDpNa1, MMeNa1, MMeNb1 or MMeNc1
+	Value   float64
+	Quality QualityDescriptor
+	Group   uint8 // interrogation group (1-16) of the point, 0 if it only responds to the station interrogation
+}
+
+func (p Point) element() *InformationElement {
+	ie := &InformationElement{TypeID: p.TypeID, Address: p.Address, Value: p.Value, Quality: p.Quality}
+	_ = serializeElement(ie) // the TypeID is checked by PointDB.Set
+	return ie
+}
+
+/*
+PointDB holds the points of the controlled station, it's safe for concurrent use.
+
+When it's configured by Server.SetPointDB, the Server answers the general interrogation by the points automatically,
+so users only maintain the values of points.
+*/
+type PointDB struct {
+	mu     sync.RWMutex
+	points map[IOA]Point
+}
+
+func NewPointDB() *PointDB {
+	return &PointDB{points: make(map[IOA]Point)}
+}
+
+// Set adds or updates the point. Only the types without time tag, which are responded to the interrogation, are
+// supported, and the value of MMeNb1 must fit in the 16-bit scaled value.
+func (db *PointDB) Set(p Point) error {
+	if _, ok := pointElementLen[p.TypeID]; !ok {
+		return fmt.Errorf("unsupported type of point: TypeID[%X]", uint8(p.TypeID))
+	}
+	if p.Group > 16 {
+		return fmt.Errorf("invalid interrogation group of point: %d", p.Group)
+	}
+	if v := math.Round(p.Value); p.TypeID == MMeNb1 && (v < math.MinInt16 || v > math.MaxInt16) {
+		return fmt.Errorf("scaled value of point out of range [%d, %d]: %f", math.MinInt16, math.MaxInt16, p.Value)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.points[p.Address] = p
+	return nil
+}
+
+// Get returns the point at the address.
+func (db *PointDB) Get(address IOA) (Point, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	p, ok := db.points[address]
+	return p, ok
+}
+
+// interrogate returns the ASDUs responding to the interrogation of the group (0 for the station interrogation) with
+// the COT. Points of the same TypeID are packed in ASDUs of SQ=0 ordered by IOA, each ASDU fits in an APDU.
+func (db *PointDB) interrogate(group uint8, cot COT, org ORG, coa COA) []*ASDU {
+	db.mu.RLock()
+	points := make([]Point, 0, len(db.points))
+	for _, p := range db.points {
+		if group == 0 || p.Group == group {
+			points = append(points, p)
+		}
+	}
+	db.mu.RUnlock()
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].TypeID != points[j].TypeID {
+			return points[i].TypeID < points[j].TypeID
+		}
+		return points[i].Address < points[j].Address
+	})
+
+	// the ASDUs fit in an APDU with the standard lengths of COT and COA, which are the longest
+	headerLen := (&ASDU{}).headerLen()
+	asdus := make([]*ASDU, 0)
+	var asdu *ASDU
+	for _, p := range points {
+		maxObjs := (MaxApduLen - ApduHeaderLen - headerLen) / (IOALength + pointElementLen[p.TypeID])
+		if maxObjs > int(MaxNOO) {
+			maxObjs = int(MaxNOO)
+		}
+		if asdu == nil || asdu.typeID != p.TypeID || len(asdu.ios) == maxObjs {
+			asdu = &ASDU{typeID: p.TypeID, cot: cot, org: org, coa: coa}
+			asdus = append(asdus, asdu)
+		}
+		asdu.ios = append(asdu.ios, &InformationObject{ioa: p.Address, ies: []*InformationElement{p.element()}})
+		asdu.nObjs = NOO(len(asdu.ios))
+	}
+	return asdus
+}
@@ -0,0 +1,60 @@
+package iec104
+
+import (
+	"testing"
+)
+
+func TestPointDB_Set(t *testing.T) {
+	tests := []struct {
+		name    string
+		point   Point
+		wantErr bool
+	}{
+		{"single point", Point{Address: 1, TypeID: MSpNa1, Value: 1}, false},
+		{"short floating point in group 16", Point{Address: 2, TypeID: MMeNc1, Value: 1.5, Group: 16}, false},
+		{"with time tag", Point{Address: 3, TypeID: MSpTb1, Value: 1}, true},
+		{"invalid group", Point{Address: 4, TypeID: MSpNa1, Group: 17}, true},
+		{"scaled value", Point{Address: 5, TypeID: MMeNb1, Value: -32768}, false},
+		{"scaled value overflow", Point{Address: 6, TypeID: MMeNb1, Value: 32768}, true},
+		{"scaled value underflow", Point{Address: 7, TypeID: MMeNb1, Value: -32769}, true},
+	}
+	db := NewPointDB()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.Set(tt.point); (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := db.Get(tt.point.Address); ok == tt.wantErr {
+				t.Errorf("Get() ok = %v, want %v", ok, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPointDB_interrogate(t *testing.T) {
+	db := NewPointDB()
+	for i := 0; i < 100; i++ {
+		_ = db.Set(Point{Address: IOA(0x4001 + i), TypeID: MMeNc1, Value: float64(i)})
+	}
+	_ = db.Set(Point{Address: 1, TypeID: MSpNa1, Value: 1})
+
+	asdus := db.interrogate(0, CotInrogen, 0, 1)
+	want := []struct {
+		typeID TypeID
+		nObjs  NOO
+	}{
+		{MSpNa1, 1}, {MMeNc1, 30}, {MMeNc1, 30}, {MMeNc1, 30}, {MMeNc1, 10},
+	}
+	if len(asdus) != len(want) {
+		t.Fatalf("interrogate() returns %d ASDUs, want %d", len(asdus), len(want))
+	}
+	for i, asdu := range asdus {
+		if asdu.typeID != want[i].typeID || asdu.nObjs != want[i].nObjs {
+			t.Errorf("ASDU #%d = {TypeID[%X], %d objects}, want {TypeID[%X], %d objects}",
+				i, uint8(asdu.typeID), asdu.nObjs, uint8(want[i].typeID), want[i].nObjs)
+		}
+		if n := len(asdu.Data()); n > MaxApduLen-ApduHeaderLen {
+			t.Errorf("ASDU #%d is %d bytes, which doesn't fit in an APDU", i, n)
+		}
+	}
+}
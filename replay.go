@@ -0,0 +1,32 @@
+package iec104
+
+import (
+	"fmt"
+	"io"
+)
+
+/*
+ReplaySession reads the frames of a captured session from r, and dispatches the I-format frames to the handler as
+the Client does, but without any network. It's used to test the handler against recorded captures deterministically.
+
+It stops at the first error returned by the handler, and returns nil when all frames are replayed.
+*/
+func ReplaySession(r io.Reader, h ClientHandler) error {
+	decoder := NewDecoder(r)
+	for n := 0; ; n++ {
+		apdu, err := decoder.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("decode frame %d: %w", n, err)
+		}
+
+		if apdu.frame.Type() != FrameTypeI || !apdu.ASDU.toBeHandled {
+			continue
+		}
+		if err := handleClientData(h, apdu); err != nil {
+			return fmt.Errorf("handle frame %d: %w", n, err)
+		}
+	}
+}
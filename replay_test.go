@@ -0,0 +1,52 @@
+package iec104
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type recordingHandler struct {
+	BaseHandler
+	typeIDs []TypeID
+	err     error
+}
+
+func (h *recordingHandler) APDUHandler(apdu *APDU) error {
+	h.typeIDs = append(h.typeIDs, apdu.typeID)
+	return h.err
+}
+
+func TestReplaySession(t *testing.T) {
+	session := bytes.Join([][]byte{
+		buildFrame(UFrameFunctionStartDTC),
+		iFrame(0, []byte{0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14}),                         // CIcNa1 ActCon
+		iFrame(1, []byte{0x01, 0x01, 0x14, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}),                         // MSpNa1 Inrogen
+		iFrame(2, []byte{0x0d, 0x01, 0x03, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x00}), // MMeNc1 Spont
+		buildFrame((&SFrame{RecvSN: 1}).Data()),
+	}, nil)
+
+	h := &recordingHandler{}
+	if err := ReplaySession(bytes.NewReader(session), h); err != nil {
+		t.Fatalf("ReplaySession() error = %v", err)
+	}
+	want := []TypeID{MSpNa1, MMeNc1}
+	if len(h.typeIDs) != len(want) {
+		t.Fatalf("APDUHandler is invoked with %v, want %v", h.typeIDs, want)
+	}
+	for i := range want {
+		if h.typeIDs[i] != want[i] {
+			t.Errorf("APDUHandler #%d is invoked with TypeID[%X], want TypeID[%X]", i, uint8(h.typeIDs[i]), uint8(want[i]))
+		}
+	}
+}
+
+func TestReplaySession_HandlerError(t *testing.T) {
+	session := iFrame(0, []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01})
+
+	errHandler := errors.New("handler error")
+	h := &recordingHandler{err: errHandler}
+	if err := ReplaySession(bytes.NewReader(session), h); !errors.Is(err, errHandler) {
+		t.Errorf("ReplaySession() error = %v, want %v", err, errHandler)
+	}
+}
@@ -0,0 +1,71 @@
+package iec104
+
+import (
+	"time"
+)
+
+// sentFrame is an I-format frame sent but not acknowledged.
+type sentFrame struct {
+	ssn   uint16
+	frame []byte
+	timer *time.Timer // fires if the frame isn't acknowledged within t1
+}
+
+// sendBuffer is the ring buffer of I-format frames sent but not acknowledged in the order of send sequence number,
+// its capacity is k. It isn't safe for concurrent use.
+type sendBuffer struct {
+	frames []sentFrame
+	head   int // index of the oldest frame
+	size   int
+}
+
+func newSendBuffer(k uint16) *sendBuffer {
+	return &sendBuffer{frames: make([]sentFrame, k)}
+}
+
+func (b *sendBuffer) len() int {
+	if b == nil {
+		return 0
+	}
+	return b.size
+}
+
+// push buffers the frame, it returns false if there are already k frames.
+func (b *sendBuffer) push(f sentFrame) bool {
+	if b.size == len(b.frames) {
+		return false
+	}
+	b.frames[(b.head+b.size)%len(b.frames)] = f
+	b.size++
+	return true
+}
+
+// release removes the n oldest frames which have been acknowledged, and stops their timers.
+func (b *sendBuffer) release(n int) {
+	for ; n > 0 && b.size > 0; n-- {
+		f := &b.frames[b.head]
+		if f.timer != nil {
+			f.timer.Stop()
+		}
+		*f = sentFrame{}
+		b.head = (b.head + 1) % len(b.frames)
+		b.size--
+	}
+}
+
+// contains reports whether the frame of the send sequence number is buffered.
+func (b *sendBuffer) contains(ssn uint16) bool {
+	for i := 0; i < b.size; i++ {
+		if b.frames[(b.head+i)%len(b.frames)].ssn == ssn {
+			return true
+		}
+	}
+	return false
+}
+
+// reset removes all frames, and stops their timers.
+func (b *sendBuffer) reset() {
+	if b != nil {
+		b.release(b.size)
+	}
+}
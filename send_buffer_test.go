@@ -0,0 +1,50 @@
+package iec104
+
+import (
+	"testing"
+)
+
+func TestSendBuffer(t *testing.T) {
+	b := newSendBuffer(3)
+	for ssn := uint16(0); ssn < 3; ssn++ {
+		if !b.push(sentFrame{ssn: ssn}) {
+			t.Fatalf("push(%d) = false, want true", ssn)
+		}
+	}
+	if b.push(sentFrame{ssn: 3}) {
+		t.Error("push() = true when k frames are buffered, want false")
+	}
+
+	// wrap around the ring
+	b.release(2)
+	for _, ssn := range []uint16{3, 4} {
+		if !b.push(sentFrame{ssn: ssn}) {
+			t.Fatalf("push(%d) = false, want true", ssn)
+		}
+	}
+
+	tests := []struct {
+		ssn  uint16
+		want bool
+	}{
+		{0, false},
+		{1, false},
+		{2, true},
+		{3, true},
+		{4, true},
+		{5, false},
+	}
+	for _, tt := range tests {
+		if got := b.contains(tt.ssn); got != tt.want {
+			t.Errorf("contains(%d) = %v, want %v", tt.ssn, got, tt.want)
+		}
+	}
+	if got := b.len(); got != 3 {
+		t.Errorf("len() = %d, want 3", got)
+	}
+
+	b.release(5)
+	if got := b.len(); got != 0 {
+		t.Errorf("len() = %d after releasing all frames, want 0", got)
+	}
+}
@@ -2,14 +2,18 @@ package iec104
 
 import (
 	"crypto/tls"
-	"github.com/sirupsen/logrus"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"sync"
 )
 
-func NewServer(address string, tc *tls.Config) *Server {
+func NewServer(address string, tc *tls.Config, handler ServerHandler) *Server {
 	return &Server{
 		address: address,
 		tc:      tc,
+		handler: handler,
 	}
 }
 
@@ -19,26 +23,74 @@ type Server struct {
 	tc       *tls.Config
 	listener net.Listener
 
-	lg *logrus.Logger
+	handler ServerHandler
+	points  *PointDB // answers the general interrogation automatically if it's set
+	cotLen  int      // length of COT in bytes, 0 means CotLen
+	coaLen  int      // length of COA in bytes, 0 means CoaLen
+	coa     COA      // common address (or station address) of the server answering the global address
+	k       uint16   // maximum number of I-format frames sent but not acknowledged, 0 means DefaultK
+
+	mu    sync.Mutex
+	conns map[*Conn]struct{} // connections being served
 }
 
-func (s *Server) Serve() error {
-	if err := s.listen(); err != nil {
-		return err
+// SetPointDB sets the points of the controlled station. The general interrogation is answered by the points
+// automatically: ActCon, the points with COT CotInrogen (or CotInro1-16 for the group interrogation), then ActTerm.
+// The GeneralInterrogationHandler is still called after the answer, it shouldn't answer again. The points are ignored
+// if the ServerHandler implements InterrogationAnswerer, which supplies the points instead.
+func (s *Server) SetPointDB(db *PointDB) *Server {
+	s.points = db
+	return s
+}
+
+// SetCommonAddress sets the common address (COA) of the station, by which the ASDUs broadcast to the global address
+// GlobalCOA, e.g. the general interrogation and the clock synchronization, are answered instead of GlobalCOA. COA
+// must be in [1, 65534], otherwise the address isn't changed, and the ASDUs are answered by the address they are sent
+// to if it isn't set.
+func (s *Server) SetCommonAddress(coa COA) *Server {
+	if coa != 0 && !IsGlobalAddress(coa) {
+		s.coa = coa
 	}
+	return s
+}
 
-	defer s.listener.Close()
-	for {
-		conn, err := s.listener.Accept()
-		if err != nil {
-			s.lg.Errorf("accept conn with %s", conn.RemoteAddr())
-			continue
-		}
+// SetCOTLength sets the length of COT in bytes, 1 or 2, of the ASDUs received and sent by the server, see
+// ClientOption.SetCOTLength. The length other than 1 or 2 is ignored.
+func (s *Server) SetCOTLength(n int) *Server {
+	if validFieldLength(n) {
+		s.cotLen = n
+	}
+	return s
+}
 
-		go s.serve(&Conn{
-			conn,
-		})
+// SetCOALength sets the length of COA in bytes, 1 or 2, of the ASDUs received and sent by the server, see
+// ClientOption.SetCOALength. The length other than 1 or 2 is ignored.
+func (s *Server) SetCOALength(n int) *Server {
+	if validFieldLength(n) {
+		s.coaLen = n
+	}
+	return s
+}
+
+// SetWindowSize sets k, the maximum number of I-format frames sent but not acknowledged by the controlling station,
+// sending more blocks until they are acknowledged. It must be in [1, 32767], otherwise it isn't changed, and it's
+// DefaultK by default.
+func (s *Server) SetWindowSize(k uint16) *Server {
+	if k > 0 && k < 1<<15 {
+		s.k = k
+	}
+	return s
+}
+
+func (s *Server) Serve() error {
+	// the common address of the station must fit in the length of COA
+	if err := (&ASDU{coa: s.coa, coaLen: s.coaLen}).checkCOA(); err != nil {
+		return err
+	}
+	if err := s.listen(); err != nil {
+		return err
 	}
+	return s.accept()
 }
 func (s *Server) listen() error {
 	if s.tc != nil {
@@ -46,24 +98,537 @@ func (s *Server) listen() error {
 		if err != nil {
 			return err
 		}
-		s.lg.Debugf("IEC104 server serve at %s with security: %+v", s.address, s.tc)
+		pkgLogger().Debugf("IEC104 server serve at %s with security: %+v", s.address, s.tc)
 		s.listener = listener
 	} else {
 		listener, err := net.Listen("tcp", s.address)
 		if err != nil {
 			return err
 		}
-		s.lg.Debugf("IEC104 server serve at %s no security", s.address)
+		pkgLogger().Debugf("IEC104 server serve at %s no security", s.address)
 		s.listener = listener
 	}
 	return nil
 }
+func (s *Server) accept() error {
+	defer s.listener.Close()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			pkgLogger().Errorf("accept conn: %v", err)
+			continue
+		}
+
+		c := &Conn{
+			Conn:   conn,
+			cotLen: s.cotLen,
+			coaLen: s.coaLen,
+			coa:    s.coa,
+			k:      s.k,
+		}
+		if c.k == 0 {
+			c.k = DefaultK
+		}
+		c.window = sync.NewCond(&c.mu)
+		s.track(c, true)
+		go s.serve(c)
+	}
+}
+
+// track adds the connection being served, or removes it if it isn't served any more.
+func (s *Server) track(conn *Conn, served bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !served {
+		delete(s.conns, conn)
+		return
+	}
+	if s.conns == nil {
+		s.conns = make(map[*Conn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+}
+
+// SendSpontaneous sends the value of the point changed spontaneously (COT CotSpont) to every controlling station
+// which has started the data transfer, the others are skipped. The first error of sending is returned after the
+// ASDU is sent to all the stations, see Conn.SendSpontaneous.
+func (s *Server) SendSpontaneous(typeID TypeID, ioa IOA, value float64, quality QualityDescriptor) error {
+	s.mu.Lock()
+	conns := make([]*Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, conn := range conns {
+		err := conn.SendSpontaneous(typeID, ioa, value, quality)
+		if err == nil || IsErrDataTransferStopped(err) {
+			continue
+		}
+		pkgLogger().Warnf("send spontaneous data to %s: %v", conn.RemoteAddr(), err)
+		if firstErr == nil {
+			firstErr = fmt.Errorf("send spontaneous data to %s: %w", conn.RemoteAddr(), err)
+		}
+	}
+	return firstErr
+}
+
+// Close stops listening, the connections being served are not affected.
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
 func (s *Server) serve(conn *Conn) {
-	s.lg.Debugf("serve connection from %s", conn.RemoteAddr())
+	pkgLogger().Debugf("serve connection from %s", conn.RemoteAddr())
+	defer func() {
+		s.track(conn, false)
+		_ = conn.Close()
+		pkgLogger().Debugf("stop serving connection from %s", conn.RemoteAddr())
+	}()
+
+	// After the establishment of a TCP connection, send and receive sequence number should be set to zero.
+	conn.ssn, conn.rsn = 0, 0
+
+	// The frames are received by another goroutine, so that the acknowledgements of the controlling station are
+	// received while the handler is waiting for them to send more than k I-format frames. The I-format frames are
+	// queued without blocking the receiving, the controlling station doesn't send more than its k frames not
+	// acknowledged anyway.
+	iFrames := newFrameQueue()
+	go conn.receive(iFrames)
+
+	for {
+		apdu, ok := iFrames.pop()
+		if !ok {
+			return
+		}
+		conn.incRsn()
+		if err := s.handleData(conn, apdu); err != nil {
+			pkgLogger().Warnf("handle iFrame, got: %v", err)
+		}
+		// Acknowledge the received I-format frames if the handler hasn't answered with I-format frames.
+		if err := conn.sendAck(); err != nil {
+			pkgLogger().Errorf("acknowledge %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+func (s *Server) handleData(conn *Conn, apdu *APDU) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("server handler: %+v", r)
+		}
+	}()
+
+	pkgLogger().Debugf("handle iFrame: TypeID: %X, COT: %X", uint8(apdu.ASDU.typeID), uint8(apdu.ASDU.cot))
 
-	// TODO
+	if apdu.typeID == CTsNb1 && apdu.cot == CotAct {
+		if err := conn.confirmTestCommand(apdu); err != nil {
+			return err
+		}
+	}
+	if apdu.typeID == CIcNa1 && apdu.cot == CotAct {
+		if answerer, ok := s.handler.(InterrogationAnswerer); ok {
+			if err := answerInterrogationBy(answerer, apdu, conn.SendIFrame); err != nil {
+				return err
+			}
+		} else if s.points != nil {
+			if err := answerInterrogation(apdu, s.points, conn.SendIFrame); err != nil {
+				return err
+			}
+		}
+	}
+	if s.handler == nil {
+		return nil
+	}
+	switch apdu.typeID {
+	case CIcNa1:
+		return s.handler.GeneralInterrogationHandler(conn, apdu)
+	case CCiNa1:
+		return s.handler.CounterInterrogationHandler(conn, apdu)
+	case CRdNa1:
+		return s.handler.ReadCommandHandler(conn, apdu)
+	case CCsNa1:
+		return s.handler.ClockSynchronizationHandler(conn, apdu)
+	case CTsNb1, CTsTa1:
+		return s.handler.TestCommandHandler(conn, apdu)
+	case CRpNc1:
+		return s.handler.ResetProcessCommandHandler(conn, apdu)
+	case CCdNa1:
+		return s.handler.DelayAcquisitionCommandHandler(conn, apdu)
+	case CScNa1, CDcNa1, CRcNa1, CSeNa1, CSeNb1, CSeNc1, CBoNa1, CScTa1, CDcTa1, CSeTa1, CSeTb1, CSeTc1:
+		return s.handler.CommandHandler(conn, apdu)
+	default:
+		return s.handler.APDUHandler(conn, apdu)
+	}
 }
 
+// Conn is a connection between the Server and a controlling station.
 type Conn struct {
 	net.Conn
+
+	mu       sync.Mutex
+	window   *sync.Cond // broadcast when the frames sent are acknowledged or the connection isn't served any more
+	ssn, rsn uint16     // send sequence number, receive sequence number
+	ackedRsn uint16     // receive sequence number which has been acknowledged to the controlling station
+	ackedSsn uint16     // send sequence number which has been acknowledged by the controlling station
+	k        uint16     // maximum number of I-format frames sent but not acknowledged
+	started  bool       // whether data transfer is started by STARTDT
+	stopped  bool       // whether the connection isn't served any more
+	cotLen   int        // length of COT in bytes, 0 means CotLen
+	coaLen   int        // length of COA in bytes, 0 means CoaLen
+	coa      COA        // common address of the station answering the global address, 0 if it isn't set
+}
+
+// IsStarted reports whether the controlling station has activated the data transfer by STARTDT.
+func (c *Conn) IsStarted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.started
+}
+
+func (c *Conn) readFromSocket() (*APDU, error) {
+	return NewDecoder(c.Conn).SetCOTLength(c.cotLen).SetCOALength(c.coaLen).Decode()
+}
+
+// receive reads the frames from the controlling station until the connection fails. The U-format frames are answered,
+// the S-format frames and the N(R) of the I-format frames acknowledge the frames sent, and the I-format frames are
+// queued to iFrames, which is closed when it returns.
+func (c *Conn) receive(iFrames *frameQueue) {
+	defer iFrames.close()
+	defer c.stop()
+
+	for {
+		apdu, err := c.readFromSocket()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				pkgLogger().Errorf("read from %s: %v", c.RemoteAddr(), err)
+			}
+			return
+		}
+
+		switch apdu.frame.Type() {
+		case FrameTypeU:
+			if err := c.handleUFrame(apdu.frame.(*UFrame)); err != nil {
+				pkgLogger().Errorf("handle u frame from %s: %v", c.RemoteAddr(), err)
+				return
+			}
+		case FrameTypeS:
+			pkgLogger().Debugf("receive s frame: RecvSN[%d]", apdu.frame.(*SFrame).RecvSN)
+			c.ack(apdu.frame.(*SFrame).RecvSN)
+		case FrameTypeI:
+			c.ack(apdu.frame.(*IFrame).RecvSN)
+			iFrames.push(apdu)
+		}
+	}
+}
+
+// ack acknowledges the I-format frames sent before the receive sequence number of the controlling station.
+func (c *Conn) ack(rsn uint16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !seqInWindow(rsn, c.ackedSsn, c.ssn) {
+		pkgLogger().Warnf("receive sequence number %d is out of the send window [%d, %d]", rsn, c.ackedSsn, c.ssn)
+		return
+	}
+	c.ackedSsn = rsn
+	c.window.Broadcast()
+}
+
+// stop wakes up the senders waiting for the acknowledgements, since the connection isn't served any more.
+func (c *Conn) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	c.window.Broadcast()
+}
+
+func (c *Conn) handleUFrame(uFrame *UFrame) error {
+	switch uFrame.Cmd[0] {
+	case UFrameFunctionStartDTA[0]:
+		pkgLogger().Debugf("receive u frame: StartDTA")
+		c.mu.Lock()
+		c.started = true
+		c.mu.Unlock()
+		return c.sendUFrame(UFrameFunctionStartDTC)
+	case UFrameFunctionStopDTA[0]:
+		pkgLogger().Debugf("receive u frame: StopDTA")
+		c.mu.Lock()
+		c.started = false
+		c.mu.Unlock()
+		return c.sendUFrame(UFrameFunctionStopDTC)
+	case UFrameFunctionTestFA[0]:
+		pkgLogger().Debugf("receive u frame: TestFA")
+		return c.sendUFrame(UFrameFunctionTestFC)
+	case UFrameFunctionTestFC[0]:
+		pkgLogger().Debugf("receive u frame: TestFC")
+	default:
+		pkgLogger().Warnf("receive u frame: unexpected function [% X]", uFrame.Cmd)
+	}
+	return nil
+}
+
+// SendIFrame sends an I-format frame with the ASDU to the controlling station. The global address isn't used in
+// monitor direction, so the ASDU answering the broadcast is sent with the common address of the station set by
+// Server.SetCommonAddress. It blocks while k I-format frames sent are not acknowledged, see Server.SetWindowSize.
+func (c *Conn) SendIFrame(asdu *ASDU) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sendIFrame(asdu)
+}
+
+// SendSpontaneous sends the value of the point changed spontaneously, i.e. the ASDU of the TypeID in monitor direction
+// with COT CotSpont carrying the value and the quality of the IOA, which is time tagged by the current time if the
+// TypeID has a time tag. It's sent with the common address set by Server.SetCommonAddress, or DefaultCommonAddress.
+// ErrDataTransferStopped is returned if the controlling station hasn't started the data transfer by STARTDT. It blocks
+// while k I-format frames sent are not acknowledged like SendIFrame.
+func (c *Conn) SendSpontaneous(typeID TypeID, ioa IOA, value float64, quality QualityDescriptor) error {
+	coa := c.coa
+	if coa == 0 {
+		coa = DefaultCommonAddress
+	}
+	asdu, err := NewASDU(typeID, CotSpont).SetCommonAddress(coa).AddObject(ioa, value, quality).Build()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.started {
+		return ErrDataTransferStopped
+	}
+	return c.sendIFrame(asdu)
+}
+
+// sendIFrame sends the I-format frame numbered by the sequence numbers of the connection, c.mu must be held. It waits
+// until less than k frames sent are not acknowledged, and fails with net.ErrClosed if the connection isn't served any
+// more.
+func (c *Conn) sendIFrame(asdu *ASDU) error {
+	for !c.stopped && seqDistance(c.ackedSsn, c.ssn) >= c.k {
+		c.window.Wait()
+	}
+	if c.stopped {
+		return net.ErrClosed
+	}
+
+	apci := &IFrame{
+		SendSN: c.ssn,
+		RecvSN: c.rsn,
+	}
+	asdu.cotLen, asdu.coaLen = c.cotLen, c.coaLen
+	if IsGlobalAddress(asdu.coa) && c.coa != 0 {
+		asdu.coa = c.coa
+	}
+	if err := asdu.checkCOA(); err != nil {
+		return err
+	}
+	frame := buildFrame(append(apci.Data(), asdu.Data()...))
+	pkgLogger().Debugf("send i frame: [% X]", frame)
+	if _, err := c.Write(frame); err != nil {
+		return err
+	}
+	c.incSsn()
+	c.ackedRsn = c.rsn
+	return nil
+}
+
+// confirmTestCommand echoes the fixed test bit pattern of the test command with CotActCon, the confirmation is
+// negative if the pattern isn't 0x55AA.
+func (c *Conn) confirmTestCommand(apdu *APDU) error {
+	fbp := FixedTestBitPattern
+	if len(apdu.Signals) > 0 {
+		fbp = uint16(apdu.Signals[0].Value)
+	}
+	ios := []*InformationObject{
+		{
+			ioa: 0x000000,
+			ies: []*InformationElement{
+				{
+					Format: []InformationElementType{FBP},
+					Raw:    serializeLittleEndianUint16(fbp),
+				},
+			},
+		},
+	}
+	return c.SendIFrame(&ASDU{
+		typeID: CTsNb1,
+		sq:     false,
+		nObjs:  NOO(len(ios)),
+		t:      false,
+		pn:     len(apdu.Signals) == 0 || fbp != FixedTestBitPattern,
+		cot:    CotActCon,
+		org:    apdu.org,
+		coa:    apdu.coa,
+		ios:    ios,
+	})
+}
+
+// answerInterrogation answers the general interrogation by the points of db, which are sent by send. The confirmation
+// is negative if the QOI isn't the station interrogation (20) or the group interrogation (21-36) or db is nil, and no
+// point is sent then.
+func answerInterrogation(apdu *APDU, db *PointDB, send func(asdu *ASDU) error) error {
+	qoi := interrogationQOI(apdu)
+	valid := db != nil && qoi >= QOIStation && qoi <= QOIGroup16
+	reply := func(cot COT, pn bool) error {
+		return send(&ASDU{
+			typeID: CIcNa1,
+			sq:     false,
+			nObjs:  1,
+			t:      false,
+			pn:     PN(pn),
+			cot:    cot,
+			org:    apdu.org,
+			coa:    apdu.coa,
+			ios: []*InformationObject{
+				{
+					ioa: 0x000000,
+					ies: []*InformationElement{{Format: []InformationElementType{QOI}, Raw: []byte{qoi}}},
+				},
+			},
+		})
+	}
+
+	if err := reply(CotActCon, !valid); err != nil || !valid {
+		return err
+	}
+	for _, asdu := range db.interrogate(qoi-byte(CotInrogen), COT(qoi), apdu.org, apdu.coa) {
+		if err := send(asdu); err != nil {
+			return err
+		}
+	}
+	return reply(CotActTerm, false)
+}
+
+// answerInterrogationBy answers the general interrogation by the points returned by the answerer, which are sent by
+// send. The confirmation is negative if the QOI is invalid or the answerer fails.
+func answerInterrogationBy(answerer InterrogationAnswerer, apdu *APDU, send func(asdu *ASDU) error) error {
+	group := interrogationQOI(apdu) - QOIStation
+	if group > 16 {
+		return answerInterrogation(apdu, nil, send)
+	}
+
+	points, err := answerer.AnswerInterrogation(group)
+	db := NewPointDB()
+	for _, p := range points {
+		p.Group = group
+		if err = db.Set(p); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		_ = answerInterrogation(apdu, nil, send)
+		return fmt.Errorf("answer general interrogation: %w", err)
+	}
+	return answerInterrogation(apdu, db, send)
+}
+
+// interrogationQOI returns the QOI of the general interrogation, which is the COT of the answers, e.g. 20 for the
+// station interrogation.
+func interrogationQOI(apdu *APDU) byte {
+	if len(apdu.Signals) == 0 {
+		return 0
+	}
+	return byte(apdu.Signals[0].Value)
+}
+
+// sendAck sends an S-format frame if there are I-format frames which haven't been acknowledged.
+func (c *Conn) sendAck() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ackedRsn == c.rsn {
+		return nil
+	}
+	frame := buildFrame((&SFrame{RecvSN: c.rsn}).Data())
+	pkgLogger().Debugf("send s frame: [% X]", frame)
+	if _, err := c.Write(frame); err != nil {
+		return err
+	}
+	c.ackedRsn = c.rsn
+	return nil
+}
+
+func (c *Conn) sendUFrame(x UFrameFunction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frame := buildFrame(x)
+	pkgLogger().Debugf("send u frame: [% X]", frame)
+	_, err := c.Write(frame)
+	return err
+}
+
+func (c *Conn) incRsn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rsn = seqNext(c.rsn)
+}
+
+func (c *Conn) incSsn() {
+	c.ssn = seqNext(c.ssn)
+}
+
+// frameQueue queues the I-format frames received until they are handled, it's safe for concurrent use.
+type frameQueue struct {
+	mu     sync.Mutex
+	apdus  []*APDU
+	closed bool
+	ready  chan struct{} // notified when an APDU is pushed or the queue is closed
+}
+
+func newFrameQueue() *frameQueue {
+	return &frameQueue{ready: make(chan struct{}, 1)}
+}
+
+// push queues the APDU without blocking.
+func (q *frameQueue) push(apdu *APDU) {
+	q.mu.Lock()
+	q.apdus = append(q.apdus, apdu)
+	q.mu.Unlock()
+	q.notify()
+}
+
+// close closes the queue, the APDUs queued are dropped since the connection is closed.
+func (q *frameQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *frameQueue) notify() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for the next APDU queued, ok is false if the queue is closed.
+func (q *frameQueue) pop() (apdu *APDU, ok bool) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, false
+		}
+		if len(q.apdus) > 0 {
+			apdu = q.apdus[0]
+			q.apdus[0] = nil
+			q.apdus = q.apdus[1:]
+			q.mu.Unlock()
+			return apdu, true
+		}
+		q.mu.Unlock()
+		<-q.ready
+	}
 }
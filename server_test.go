@@ -0,0 +1,590 @@
+package iec104
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type testServerHandler struct {
+	apdus chan *APDU
+}
+
+func (h *testServerHandler) GeneralInterrogationHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+func (h *testServerHandler) CounterInterrogationHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+func (h *testServerHandler) ClockSynchronizationHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+func (h *testServerHandler) TestCommandHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+func (h *testServerHandler) ReadCommandHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+func (h *testServerHandler) ResetProcessCommandHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+func (h *testServerHandler) DelayAcquisitionCommandHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+func (h *testServerHandler) CommandHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+func (h *testServerHandler) APDUHandler(c *Conn, apdu *APDU) error {
+	h.apdus <- apdu
+	return nil
+}
+
+// startTestServer starts a Server listening on a random local port and dials it.
+func startTestServer(t *testing.T, handler ServerHandler) (*Server, net.Conn) {
+	t.Helper()
+	return startConfiguredTestServer(t, NewServer("127.0.0.1:0", nil, handler))
+}
+
+// startConfiguredTestServer starts the configured Server listening on a random local port and dials it.
+func startConfiguredTestServer(t *testing.T, s *Server) (*Server, net.Conn) {
+	t.Helper()
+
+	if err := s.listen(); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go s.accept()
+	t.Cleanup(func() { _ = s.Close() })
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return s, conn
+}
+
+func expectFrame(t *testing.T, conn net.Conn, want []byte) {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("receive [% X], want [% X]", got, want)
+	}
+}
+
+func TestServer_UFrame(t *testing.T) {
+	_, conn := startTestServer(t, nil)
+
+	tests := []struct {
+		name string
+		send UFrameFunction
+		want UFrameFunction
+	}{
+		{"StartDT", UFrameFunctionStartDTA, UFrameFunctionStartDTC},
+		{"TestFR", UFrameFunctionTestFA, UFrameFunctionTestFC},
+		{"StopDT", UFrameFunctionStopDTA, UFrameFunctionStopDTC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := conn.Write(buildFrame(tt.send)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			expectFrame(t, conn, buildFrame(tt.want))
+		})
+	}
+}
+
+func TestServer_IFrame(t *testing.T) {
+	handler := &testServerHandler{apdus: make(chan *APDU, 1)}
+	_, conn := startTestServer(t, handler)
+
+	if _, err := conn.Write(buildFrame(UFrameFunctionStartDTA)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectFrame(t, conn, buildFrame(UFrameFunctionStartDTC))
+
+	// general interrogation: N(S)=0, N(R)=0, CIcNa1, CotAct, COA=1, IOA=0, QOI=20
+	frame := buildFrame([]byte{
+		0x00, 0x00, 0x00, 0x00,
+		0x64, 0x01, 0x06, 0x00, 0x01, 0x00,
+		0x00, 0x00, 0x00, 0x14,
+	})
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	select {
+	case apdu := <-handler.apdus:
+		if apdu.typeID != CIcNa1 || apdu.cot != CotAct {
+			t.Errorf("handle TypeID[%X] COT[%X], want TypeID[%X] COT[%X]", uint8(apdu.typeID), uint8(apdu.cot), uint8(CIcNa1), uint8(CotAct))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("general interrogation isn't handled")
+	}
+
+	// the server acknowledges with N(R)=1
+	expectFrame(t, conn, buildFrame((&SFrame{RecvSN: 1}).Data()))
+}
+
+func TestServer_TestCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		fbp  []byte
+		want []byte // ASDU of the confirmation
+	}{
+		{"fixed test bit pattern", []byte{0xaa, 0x55}, []byte{0x68, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0xaa, 0x55}},
+		{"wrong pattern", []byte{0x34, 0x12}, []byte{0x68, 0x01, 0x47, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x34, 0x12}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &testServerHandler{apdus: make(chan *APDU, 1)}
+			_, conn := startTestServer(t, handler)
+
+			if _, err := conn.Write(buildFrame(UFrameFunctionStartDTA)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			expectFrame(t, conn, buildFrame(UFrameFunctionStartDTC))
+
+			// test command: N(S)=0, N(R)=0, CTsNb1, CotAct, COA=1, IOA=0
+			asdu := append([]byte{0x68, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}, tt.fbp...)
+			if _, err := conn.Write(iFrame(0, asdu)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			expectFrame(t, conn, buildFrame(append((&IFrame{SendSN: 0, RecvSN: 1}).Data(), tt.want...)))
+			select {
+			case <-handler.apdus:
+			case <-time.After(time.Second):
+				t.Error("test command isn't handled by TestCommandHandler")
+			}
+		})
+	}
+}
+
+func TestServer_TestCommandFromClient(t *testing.T) {
+	s, _ := startTestServer(t, nil)
+
+	option, err := NewClientOption(s.listener.Addr().String(), &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SendTestCommand(); err != nil {
+		t.Errorf("SendTestCommand() error = %v", err)
+	}
+}
+
+func TestServer_COALength(t *testing.T) {
+	handler := &testServerHandler{apdus: make(chan *APDU, 1)}
+	s, conn := startConfiguredTestServer(t, NewServer("127.0.0.1:0", nil, handler).SetCOALength(1))
+	_ = conn.Close()
+
+	option, err := NewClientOption(s.listener.Addr().String(), &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option.SetCOALength(1).SetCommonAddress(0x12))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	// the confirmation is parsed with 1-byte COA
+	if err := client.SendTestCommand(); err != nil {
+		t.Errorf("SendTestCommand() error = %v", err)
+	}
+	select {
+	case apdu := <-handler.apdus:
+		if apdu.coa != 0x12 || len(apdu.Signals) != 1 || uint16(apdu.Signals[0].Value) != FixedTestBitPattern {
+			t.Errorf("TestCommandHandler() gets COA %d, signals %+v", apdu.coa, apdu.Signals)
+		}
+	case <-time.After(time.Second):
+		t.Error("test command isn't handled by TestCommandHandler")
+	}
+
+	// the common address exceeding 1-byte COA is rejected
+	if err := NewServer("127.0.0.1:0", nil, handler).SetCOALength(1).SetCommonAddress(0x1234).Serve(); err == nil {
+		t.Error("Serve() error = nil with COA 0x1234 of 1-byte COA")
+	}
+	option, err = NewClientOption(s.listener.Addr().String(), &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	if err := NewClient(option.SetCOALength(1).SetCommonAddress(0x1234)).Connect(); err == nil {
+		t.Error("Connect() error = nil with COA 0x1234 of 1-byte COA")
+	}
+}
+
+func TestServer_AnswerInterrogation(t *testing.T) {
+	db := NewPointDB()
+	for _, p := range []Point{
+		{Address: 1, TypeID: MSpNa1, Value: 1},
+		{Address: 2, TypeID: MSpNa1, Value: 0, Quality: IV},
+		{Address: 3, TypeID: MSpNa1, Value: 1, Group: 1},
+		{Address: 0x4001, TypeID: MMeNc1, Value: 1},
+		{Address: 0x4002, TypeID: MMeNc1, Value: -2.5, Group: 2},
+	} {
+		if err := db.Set(p); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		qoi  byte
+		want [][]byte // ASDUs answered in order
+	}{
+		{
+			"station interrogation",
+			0x14,
+			[][]byte{
+				{0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+				{0x01, 0x03, 0x14, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x80, 0x03, 0x00, 0x00, 0x01},
+				{0x0d, 0x02, 0x14, 0x00, 0x01, 0x00, 0x01, 0x40, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x00,
+					0x02, 0x40, 0x00, 0x00, 0x00, 0x20, 0xc0, 0x00},
+				{0x64, 0x01, 0x0a, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+			},
+		},
+		{
+			"group interrogation",
+			0x15,
+			[][]byte{
+				{0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x15},
+				{0x01, 0x01, 0x15, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x01},
+				{0x64, 0x01, 0x0a, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x15},
+			},
+		},
+		{
+			"invalid qualifier",
+			0x00,
+			[][]byte{
+				{0x64, 0x01, 0x47, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &testServerHandler{apdus: make(chan *APDU, 1)}
+			_, conn := startConfiguredTestServer(t, NewServer("127.0.0.1:0", nil, handler).SetPointDB(db))
+
+			if _, err := conn.Write(buildFrame(UFrameFunctionStartDTA)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			expectFrame(t, conn, buildFrame(UFrameFunctionStartDTC))
+
+			// general interrogation: N(S)=0, N(R)=0, CIcNa1, CotAct, COA=1, IOA=0
+			if _, err := conn.Write(iFrame(0, []byte{0x64, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, tt.qoi})); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			for ssn, asdu := range tt.want {
+				expectFrame(t, conn, buildFrame(append((&IFrame{SendSN: uint16(ssn), RecvSN: 1}).Data(), asdu...)))
+			}
+
+			select {
+			case apdu := <-handler.apdus:
+				if apdu.typeID != CIcNa1 {
+					t.Errorf("handle TypeID[%X], want TypeID[%X]", uint8(apdu.typeID), uint8(CIcNa1))
+				}
+			case <-time.After(time.Second):
+				t.Fatal("general interrogation isn't handled")
+			}
+			// nothing else is sent since the answer acknowledges the interrogation
+			_ = conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+			if n, err := conn.Read(make([]byte, 1)); err == nil {
+				t.Errorf("receive %d more bytes after the termination", n)
+			}
+		})
+	}
+}
+
+func TestServer_AnswerGlobalAddress(t *testing.T) {
+	db := NewPointDB()
+	if err := db.Set(Point{Address: 1, TypeID: MSpNa1, Value: 1}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	handler := &testServerHandler{apdus: make(chan *APDU, 1)}
+	_, conn := startConfiguredTestServer(t, NewServer("127.0.0.1:0", nil, handler).SetPointDB(db).SetCommonAddress(0x12))
+
+	if _, err := conn.Write(buildFrame(UFrameFunctionStartDTA)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectFrame(t, conn, buildFrame(UFrameFunctionStartDTC))
+
+	// general interrogation broadcast to the global address is answered by the address of the station
+	if _, err := conn.Write(iFrame(0, []byte{0x64, 0x01, 0x06, 0x00, 0xff, 0xff, 0x00, 0x00, 0x00, 0x14})); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	for ssn, asdu := range [][]byte{
+		{0x64, 0x01, 0x07, 0x00, 0x12, 0x00, 0x00, 0x00, 0x00, 0x14},
+		{0x01, 0x01, 0x14, 0x00, 0x12, 0x00, 0x01, 0x00, 0x00, 0x01},
+		{0x64, 0x01, 0x0a, 0x00, 0x12, 0x00, 0x00, 0x00, 0x00, 0x14},
+	} {
+		expectFrame(t, conn, buildFrame(append((&IFrame{SendSN: uint16(ssn), RecvSN: 1}).Data(), asdu...)))
+	}
+	select {
+	case apdu := <-handler.apdus:
+		if !IsGlobalAddress(apdu.coa) {
+			t.Errorf("handle COA %d, want the global address", apdu.coa)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("general interrogation isn't handled")
+	}
+}
+
+func TestServer_SendSpontaneous(t *testing.T) {
+	s, conn := startConfiguredTestServer(t, NewServer("127.0.0.1:0", nil, nil).SetCommonAddress(0x12))
+
+	// the connection is served once TESTFR is answered
+	if _, err := conn.Write(buildFrame(UFrameFunctionTestFA)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectFrame(t, conn, buildFrame(UFrameFunctionTestFC))
+	s.mu.Lock()
+	var served *Conn
+	for c := range s.conns {
+		served = c
+	}
+	s.mu.Unlock()
+	if served == nil {
+		t.Fatal("the connection isn't tracked")
+	}
+
+	// the data transfer isn't started
+	if err := served.SendSpontaneous(MSpNa1, 1, 1, 0); !IsErrDataTransferStopped(err) {
+		t.Errorf("Conn.SendSpontaneous() error = %v, want ErrDataTransferStopped", err)
+	}
+	if err := s.SendSpontaneous(MSpNa1, 1, 1, 0); err != nil {
+		t.Errorf("SendSpontaneous() error = %v, want nil", err)
+	}
+
+	if _, err := conn.Write(buildFrame(UFrameFunctionStartDTA)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectFrame(t, conn, buildFrame(UFrameFunctionStartDTC))
+	if err := s.SendSpontaneous(MSpNa1, 1, 1, IV); err != nil {
+		t.Fatalf("SendSpontaneous() error = %v", err)
+	}
+	if err := s.SendSpontaneous(MMeNc1, 0x4001, -2.5, 0); err != nil {
+		t.Fatalf("SendSpontaneous() error = %v", err)
+	}
+	if err := s.SendSpontaneous(CScNa1, 1, 1, 0); err == nil {
+		t.Error("SendSpontaneous(CScNa1) error = nil, want the unsupported type")
+	}
+	// numbered from 0 since nothing is sent before STARTDT
+	expectFrame(t, conn, iFrame(0, []byte{0x01, 0x01, 0x03, 0x00, 0x12, 0x00, 0x01, 0x00, 0x00, 0x81}))
+	expectFrame(t, conn, iFrame(1, []byte{0x0d, 0x01, 0x03, 0x00, 0x12, 0x00, 0x01, 0x40, 0x00, 0x00, 0x00, 0x20, 0xc0, 0x00}))
+}
+
+// answeringServerHandler answers the general interrogation by the points, or fails by err.
+type answeringServerHandler struct {
+	testServerHandler
+	points []Point
+	err    error
+}
+
+func (h *answeringServerHandler) AnswerInterrogation(group uint8) ([]Point, error) {
+	return h.points, h.err
+}
+
+func TestServer_InterrogationAnswerer(t *testing.T) {
+	db := NewPointDB()
+	if err := db.Set(Point{Address: 9, TypeID: MSpNa1, Value: 1}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		qoi     byte
+		handler *answeringServerHandler
+		want    [][]byte // ASDUs answered in order
+	}{
+		{
+			"points of the handler rather than PointDB",
+			0x14,
+			&answeringServerHandler{points: []Point{
+				{Address: 1, TypeID: MSpNa1, Value: 1},
+				{Address: 0x4001, TypeID: MMeNc1, Value: -2.5},
+			}},
+			[][]byte{
+				{0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+				{0x01, 0x01, 0x14, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01},
+				{0x0d, 0x01, 0x14, 0x00, 0x01, 0x00, 0x01, 0x40, 0x00, 0x00, 0x00, 0x20, 0xc0, 0x00},
+				{0x64, 0x01, 0x0a, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+			},
+		},
+		{
+			"group interrogation",
+			0x15,
+			&answeringServerHandler{points: []Point{{Address: 3, TypeID: MSpNa1, Value: 1}}},
+			[][]byte{
+				{0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x15},
+				{0x01, 0x01, 0x15, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x01},
+				{0x64, 0x01, 0x0a, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x15},
+			},
+		},
+		{
+			"handler failing",
+			0x14,
+			&answeringServerHandler{err: errors.New("snapshot unavailable")},
+			[][]byte{
+				{0x64, 0x01, 0x47, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+			},
+		},
+		{
+			"invalid qualifier",
+			0x00,
+			&answeringServerHandler{points: []Point{{Address: 1, TypeID: MSpNa1, Value: 1}}},
+			[][]byte{
+				{0x64, 0x01, 0x47, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.handler.apdus = make(chan *APDU, 1)
+			_, conn := startConfiguredTestServer(t, NewServer("127.0.0.1:0", nil, tt.handler).SetPointDB(db))
+
+			if _, err := conn.Write(buildFrame(UFrameFunctionStartDTA)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			expectFrame(t, conn, buildFrame(UFrameFunctionStartDTC))
+
+			// general interrogation: N(S)=0, N(R)=0, CIcNa1, CotAct, COA=1, IOA=0
+			if _, err := conn.Write(iFrame(0, []byte{0x64, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, tt.qoi})); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			for ssn, asdu := range tt.want {
+				expectFrame(t, conn, buildFrame(append((&IFrame{SendSN: uint16(ssn), RecvSN: 1}).Data(), asdu...)))
+			}
+			// the framing is completed by the termination, nothing else is sent
+			_ = conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+			if n, err := conn.Read(make([]byte, 1)); err == nil {
+				t.Errorf("receive %d more bytes after the answer", n)
+			}
+		})
+	}
+}
+
+func TestServer_WindowSize(t *testing.T) {
+	s, conn := startConfiguredTestServer(t, NewServer("127.0.0.1:0", nil, nil).SetWindowSize(2))
+
+	if _, err := conn.Write(buildFrame(UFrameFunctionStartDTA)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectFrame(t, conn, buildFrame(UFrameFunctionStartDTC))
+
+	sent := make(chan error, 4)
+	go func() {
+		for i := 0; i < 4; i++ {
+			sent <- s.SendSpontaneous(MSpNa1, 1, 1, 0)
+		}
+	}()
+	asdu := []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}
+	expectFrame(t, conn, iFrame(0, asdu))
+	expectFrame(t, conn, iFrame(1, asdu))
+
+	// the third frame waits for the acknowledgement of the first two
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("the frames in the window aren't sent")
+	}
+	<-sent
+	select {
+	case err := <-sent:
+		t.Fatalf("SendSpontaneous() = %v, want blocking while 2 frames are not acknowledged", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if _, err := conn.Write(buildFrame((&SFrame{RecvSN: 2}).Data())); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectFrame(t, conn, iFrame(2, asdu))
+	expectFrame(t, conn, iFrame(3, asdu))
+	for i := 0; i < 2; i++ {
+		if err := <-sent; err != nil {
+			t.Errorf("SendSpontaneous() error = %v", err)
+		}
+	}
+
+	// the sender waiting for the acknowledgement fails once the connection is closed
+	s.mu.Lock()
+	var served *Conn
+	for c := range s.conns {
+		served = c
+	}
+	s.mu.Unlock()
+	go func() { sent <- served.SendSpontaneous(MSpNa1, 1, 1, 0) }()
+	_ = conn.Close()
+	select {
+	case err := <-sent:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("SendSpontaneous() error = %v, want %v", err, net.ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the sender waiting for the acknowledgement isn't woken up")
+	}
+}
+
+func TestServer_WindowSizeReceiving(t *testing.T) {
+	db := NewPointDB()
+	for _, p := range []Point{
+		{Address: 1, TypeID: MSpNa1, Value: 1},
+		{Address: 0x4001, TypeID: MMeNc1, Value: 1},
+	} {
+		if err := db.Set(p); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	_, conn := startConfiguredTestServer(t, NewServer("127.0.0.1:0", nil, nil).SetPointDB(db).SetWindowSize(1))
+
+	if _, err := conn.Write(buildFrame(UFrameFunctionStartDTA)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectFrame(t, conn, buildFrame(UFrameFunctionStartDTC))
+
+	// general interrogation: N(S)=0, N(R)=0, the answer waits for the acknowledgement of its confirmation
+	if _, err := conn.Write(iFrame(0, []byte{0x64, 0x01, 0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14})); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectFrame(t, conn, buildFrame(append((&IFrame{SendSN: 0, RecvSN: 1}).Data(),
+		0x64, 0x01, 0x07, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14)))
+
+	// the read command N(S)=1, N(R)=0 waits for the interrogation, the acknowledgement after it is still received
+	if _, err := conn.Write(buildFrame(append((&IFrame{SendSN: 1, RecvSN: 0}).Data(),
+		0x66, 0x01, 0x05, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00))); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	answers := [][]byte{
+		{0x01, 0x01, 0x14, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01},
+		{0x0d, 0x01, 0x14, 0x00, 0x01, 0x00, 0x01, 0x40, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x00},
+		{0x64, 0x01, 0x0a, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x14},
+	}
+	for i, asdu := range answers {
+		if _, err := conn.Write(buildFrame((&SFrame{RecvSN: uint16(i + 1)}).Data())); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		expectFrame(t, conn, buildFrame(append((&IFrame{SendSN: uint16(i + 1), RecvSN: 1}).Data(), asdu...)))
+	}
+	// the read command is handled after the interrogation
+	expectFrame(t, conn, buildFrame((&SFrame{RecvSN: 2}).Data()))
+}
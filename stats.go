@@ -0,0 +1,149 @@
+package iec104
+
+import (
+	"sync"
+	"time"
+)
+
+// TypeStat is the statistic of I-format frames with the same TypeID received from the controlled station.
+type TypeStat struct {
+	Count    uint64    `json:"count"`
+	LastSeen time.Time `json:"last_seen"` // the time when the last frame of the TypeID is received
+}
+
+// stats records the statistics of a client, it's safe for concurrent use.
+type stats struct {
+	mu         sync.Mutex
+	types      map[TypeID]TypeStat
+	windowFull uint64 // times of the send window is full
+	dropped    uint64 // number of APDUs dropped since the data buffer is full
+	errors     uint64 // number of connections lost by errors
+	timeouts   uint64 // number of confirmations not received in time
+	connected  time.Time
+}
+
+func (s *stats) recordType(typeID TypeID, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.types == nil {
+		s.types = make(map[TypeID]TypeStat)
+	}
+	stat := s.types[typeID]
+	stat.Count++
+	stat.LastSeen = t
+	s.types[typeID] = stat
+}
+
+func (s *stats) typeStats() map[TypeID]TypeStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	x := make(map[TypeID]TypeStat, len(s.types))
+	for typeID, stat := range s.types {
+		x[typeID] = stat
+	}
+	return x
+}
+
+func (s *stats) recordWindowFull() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.windowFull++
+}
+
+func (s *stats) windowFullCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.windowFull
+}
+
+func (s *stats) recordDataDropped() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropped++
+}
+
+func (s *stats) droppedDataCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+func (s *stats) recordError() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors++
+}
+
+func (s *stats) errorCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.errors
+}
+
+func (s *stats) recordTimeout() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timeouts++
+}
+
+func (s *stats) timeoutCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.timeouts
+}
+
+func (s *stats) recordConnected(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connected = t
+}
+
+func (s *stats) connectedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.connected
+}
+
+// TypeStats returns the count and the last seen time of I-format frames received by TypeID.
+func (c *Client) TypeStats() map[TypeID]TypeStat {
+	return c.stats.typeStats()
+}
+
+// WindowFullCount returns the times of SendIFrame blocked because k I-format frames are not acknowledged.
+func (c *Client) WindowFullCount() uint64 {
+	return c.stats.windowFullCount()
+}
+
+// DroppedDataCount returns the number of APDUs dropped by DataFullPolicyDrop since the data buffer is full.
+func (c *Client) DroppedDataCount() uint64 {
+	return c.stats.droppedDataCount()
+}
+
+// ErrorCount returns the number of connections lost by errors, e.g. the failure of reading from the socket or the
+// timeout of acknowledgement.
+func (c *Client) ErrorCount() uint64 {
+	return c.stats.errorCount()
+}
+
+// TimeoutCount returns the number of confirmations not received in time, including the t1 timeouts of STARTDT,
+// STOPDT, TESTFR, I-format frames and read commands, and the timeouts set by SetCommandTimeout.
+func (c *Client) TimeoutCount() uint64 {
+	return c.stats.timeoutCount()
+}
+
+// ConnectedAt returns the time when the last connection is established, it's zero if the client never connects.
+func (c *Client) ConnectedAt() time.Time {
+	return c.stats.connectedAt()
+}
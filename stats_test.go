@@ -0,0 +1,94 @@
+package iec104
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClient_TypeStats(t *testing.T) {
+	frames := [][]byte{
+		iFrame(0, []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x01}),                         // MSpNa1
+		iFrame(1, []byte{0x0d, 0x01, 0x03, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x80, 0x3f, 0x00}), // MMeNc1
+		iFrame(2, []byte{0x01, 0x01, 0x03, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x00}),                         // MSpNa1
+	}
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {
+			for _, frame := range frames {
+				_, _ = conn.Write(frame)
+			}
+		})
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option)
+	start := time.Now()
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	want := map[TypeID]uint64{MSpNa1: 2, MMeNc1: 1}
+	var got map[TypeID]TypeStat
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		got = client.TypeStats()
+		if got[MSpNa1].Count == want[MSpNa1] && got[MMeNc1].Count == want[MMeNc1] {
+			break
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("TypeStats() = %v, want %d types", got, len(want))
+	}
+	for typeID, count := range want {
+		stat := got[typeID]
+		if stat.Count != count {
+			t.Errorf("TypeStats()[%X].Count = %d, want %d", uint8(typeID), stat.Count, count)
+		}
+		if stat.LastSeen.Before(start) || stat.LastSeen.After(time.Now()) {
+			t.Errorf("TypeStats()[%X].LastSeen = %s, want between %s and now", uint8(typeID), stat.LastSeen, start)
+		}
+	}
+	if got[MSpNa1].LastSeen.Before(got[MMeNc1].LastSeen) {
+		t.Errorf("the last MSpNa1 is received after MMeNc1, but LastSeen %s is before %s",
+			got[MSpNa1].LastSeen, got[MMeNc1].LastSeen)
+	}
+}
+
+func TestClient_ErrorAndTimeoutCount(t *testing.T) {
+	// the I-format frames aren't acknowledged by the substation
+	address := startTestSubstation(t, func(conn net.Conn) {
+		serveTestSubstation(conn, func(conn net.Conn) {})
+	})
+
+	option, err := NewClientOption(address, &BaseHandler{})
+	if err != nil {
+		t.Fatalf("NewClientOption() error = %v", err)
+	}
+	client := NewClient(option.SetStartDTTimeout(100 * time.Millisecond))
+	if !client.ConnectedAt().IsZero() {
+		t.Errorf("ConnectedAt() = %s before connected, want zero", client.ConnectedAt())
+	}
+	start := time.Now()
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+	if connectedAt := client.ConnectedAt(); connectedAt.Before(start) || connectedAt.After(time.Now()) {
+		t.Errorf("ConnectedAt() = %s, want the time of Connect", connectedAt)
+	}
+
+	client.SendGeneralInterrogation()
+	deadline := time.Now().Add(2 * time.Second)
+	for client.ErrorCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := client.ErrorCount(); got != 1 {
+		t.Errorf("ErrorCount() = %d, want 1", got)
+	}
+	if got := client.TimeoutCount(); got != 1 {
+		t.Errorf("TimeoutCount() = %d, want 1", got)
+	}
+}
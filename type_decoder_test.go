@@ -0,0 +1,73 @@
+package iec104_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/yobol/go-iec104"
+)
+
+func TestRegisterTypeDecoder(t *testing.T) {
+	// the gateway transmits the short floating point value big-endian with TypeID 136 of special use
+	const bigEndianFloat iec104.TypeID = 0x88
+	iec104.RegisterTypeDecoder(bigEndianFloat, func(ie *iec104.InformationElement, data []byte) error {
+		if len(data) != 5 {
+			return fmt.Errorf("5 bytes expected, got [% X]", data)
+		}
+		ie.Format = append(ie.Format, iec104.IEEE754STD, iec104.QDS)
+		ie.Value = float64(math.Float32frombits(iec104.ParseBigEndianUint32(data[:4])))
+		ie.Quality = iec104.QualityDescriptor(data[4])
+		return nil
+	})
+	defer iec104.RegisterTypeDecoder(bigEndianFloat, nil)
+
+	data := []byte{0x00, 0x00, 0x00, 0x00, byte(bigEndianFloat), 0x02, byte(iec104.CotSpont), 0x00, 0x01, 0x00,
+		0x01, 0x40, 0x00, 0x43, 0x66, 0x80, 0x00, 0x00, // 230.5
+		0x02, 0x40, 0x00, 0xc2, 0x48, 0x00, 0x00, 0x80} // -50 invalid
+	apdu := new(iec104.APDU)
+	if err := apdu.Parse(data); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []struct {
+		address iec104.IOA
+		value   float64
+		quality iec104.QualityDescriptor
+	}{
+		{0x4001, 230.5, 0},
+		{0x4002, -50, iec104.IV},
+	}
+	if len(apdu.Signals) != len(want) {
+		t.Fatalf("Parse() decodes %d signals, want %d", len(apdu.Signals), len(want))
+	}
+	for i, signal := range apdu.Signals {
+		if signal.Address != want[i].address || signal.Value != want[i].value || signal.Quality != want[i].quality {
+			t.Errorf("Signals[%d] = {%d, %f, %s}, want {%d, %f, %s}", i, signal.Address, signal.Value, signal.Quality,
+				want[i].address, want[i].value, want[i].quality)
+		}
+	}
+
+	if _, err := iec104.DecodeElement(bigEndianFloat, []byte{0x43, 0x66, 0x80, 0x00}); err == nil {
+		t.Error("DecodeElement() error = nil, want the error of the registered decoder")
+	}
+	iec104.RegisterTypeDecoder(bigEndianFloat, nil)
+	if _, err := iec104.DecodeElement(bigEndianFloat, []byte{0x43, 0x66, 0x80, 0x00, 0x00}); err == nil {
+		t.Error("DecodeElement() error = nil after the decoder is unregistered")
+	}
+}
+
+func TestParseBigEndian(t *testing.T) {
+	data := []byte{0xff, 0xfe, 0x00, 0x01}
+	if got := iec104.ParseBigEndianUint16(data); got != 0xfffe {
+		t.Errorf("ParseBigEndianUint16() = %X, want FFFE", got)
+	}
+	if got := iec104.ParseBigEndianInt16(data); got != -2 {
+		t.Errorf("ParseBigEndianInt16() = %d, want -2", got)
+	}
+	if got := iec104.ParseBigEndianUint32(data); got != 0xfffe0001 {
+		t.Errorf("ParseBigEndianUint32() = %X, want FFFE0001", got)
+	}
+	if got := iec104.ParseBigEndianInt32(data); got != -131071 {
+		t.Errorf("ParseBigEndianInt32() = %d, want -131071", got)
+	}
+}